@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -12,52 +13,278 @@ import (
 	"tages/internal/auth"
 	"tages/internal/config"
 	handler "tages/internal/controller/http"
+	"tages/internal/mail"
+	"tages/internal/repository/boltdb"
+	"tages/internal/repository/cache"
 	storage "tages/internal/repository/disk_storage"
 	"tages/internal/repository/pg"
 	"tages/internal/usecase"
+	"tages/migrations"
 
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 
+	migrateMode := flag.String("migrate", pg.MigrateAuto,
+		"применение миграций при запуске: off — не трогать схему, auto — применить недостающие, check — только проверить")
+	flag.Parse()
+
 	// Загружаем конфигурацию
 	cfg, err := config.InitConfig("")
 	if err != nil {
 		log.Fatalf("Failed to initialize config: %v", err)
 	}
 
-	// Подключаемся к базе данных
-	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, cfg.PG.ConnectionString())
-	if err != nil {
-		log.Fatalf("failed to create pool: %v", err)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// Подключаем хранилище метаданных
+	var (
+		pgRepo   *pg.Repository
+		fileRepo usecase.Repository
+		userRepo usecase.UserRepository
+	)
+	switch cfg.Metadata.Backend {
+	case config.MetadataBackendPostgres:
+		pool, err := pgxpool.New(ctx, cfg.PG.ConnectionString())
+		if err != nil {
+			log.Fatalf("failed to create pool: %v", err)
+		}
+		defer pool.Close()
+
+		if err := pool.Ping(ctx); err != nil {
+			log.Fatalf("failed to ping db: %v", err)
+		}
+
+		// Проверяем и применяем миграции схемы
+		if err := pg.Migrate(ctx, pool, migrations.FS, *migrateMode); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+
+		pgRepo = pg.New(pool)
+		fileRepo, userRepo = pgRepo, pgRepo
+	case config.MetadataBackendBolt:
+		boltRepo, err := boltdb.New(cfg.Metadata.BoltPath)
+		if err != nil {
+			log.Fatalf("failed to open embedded metadata store: %v", err)
+		}
+		defer boltRepo.Close()
+
+		log.Printf("INFO: Using embedded metadata store %s; roles, settings, search, favorites, locks, retention, trash, lifecycle, usage, comments, API tokens, audit, change feed and webhooks require PostgreSQL and are disabled", cfg.Metadata.BoltPath)
+		fileRepo, userRepo = boltRepo, boltRepo
+	default:
+		log.Fatalf("unknown metadata backend %q", cfg.Metadata.Backend)
 	}
-	defer pool.Close()
 
-	if err := pool.Ping(ctx); err != nil {
-		log.Fatalf("failed to ping db: %v", err)
+	// Кэш метаданных; инвалидация рассылается другим экземплярам через NOTIFY
+	var metaCache *cache.Cache
+	if cfg.Cache.Enabled {
+		var pubsub usecase.PubSub
+		if pgRepo != nil {
+			pubsub = pgRepo
+		}
+		metaCache = cache.New(cache.Config{
+			Size: cfg.Cache.Size,
+			TTL:  time.Duration(cfg.Cache.TTL) * time.Second,
+		}, pubsub)
+		go metaCache.Run(ctx)
+
+		fileRepo = metaCache.Files(fileRepo)
+		userRepo = metaCache.Users(userRepo)
+	} else {
+		log.Printf("INFO: Cache is disabled: every authenticated request reads the user from the database to check token revocation")
 	}
 
 	// Создаем репозитории и usecase
-	pgRepo := pg.New(pool)
 	fileStorage := storage.New(cfg.App.UploadDir, pgRepo)
 
-	fileUsecase := usecase.New(fileStorage, pgRepo)
+	fileUsecase := usecase.New(fileStorage, fileRepo, usecase.MetadataLimits{
+		MaxTags:        cfg.App.MaxFileTags,
+		MaxAttributes:  cfg.App.MaxFileAttributes,
+		MaxKeyLength:   cfg.App.MaxMetadataKeyLength,
+		MaxValueLength: cfg.App.MaxMetadataValueLength,
+	})
 
 	// Создаем менеджер JWT
-	tokenManager := auth.NewTokenManager(cfg.JWT)
-	userUsecase := usecase.NewUserUsecase(pgRepo, tokenManager)
+	tokenManager, err := auth.NewTokenManager(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to initialize token manager: %v", err)
+	}
+	userUsecase := usecase.NewUserUsecase(userRepo, tokenManager)
+	tokenManager.SetRevocationChecker(userUsecase)
+	userUsecase.SetAdmins(cfg.App.AdminEmails)
+	userUsecase.SetMFAConfig(usecase.MFAConfig{
+		Issuer:        cfg.MFA.Issuer,
+		RecoveryCodes: cfg.MFA.RecoveryCodes,
+	})
+	userUsecase.SetPasswordConfig(usecase.PasswordConfig{
+		ResetTokenLifetime: time.Duration(cfg.Password.ResetTokenLifetime) * time.Minute,
+		ResetURL:           cfg.Password.ResetURL,
+	})
+	userUsecase.SetEmailVerificationConfig(usecase.EmailVerificationConfig{
+		TokenLifetime:    time.Duration(cfg.EmailVerification.TokenLifetime) * time.Hour,
+		URL:              cfg.EmailVerification.URL,
+		ResendInterval:   time.Duration(cfg.EmailVerification.ResendInterval) * time.Second,
+		UnverifiedAccess: cfg.EmailVerification.UnverifiedAccess,
+	})
+	userUsecase.SetLoginThrottleConfig(usecase.LoginThrottleConfig{
+		AccountMaxFailures: cfg.LoginThrottle.AccountMaxFailures,
+		IPMaxFailures:      cfg.LoginThrottle.IPMaxFailures,
+		BaseLockout:        time.Duration(cfg.LoginThrottle.BaseLockout) * time.Second,
+		MaxLockout:         time.Duration(cfg.LoginThrottle.MaxLockout) * time.Second,
+		ResetAfter:         time.Duration(cfg.LoginThrottle.ResetAfter) * time.Minute,
+	})
+
+	// Почта для писем пользователям
+	if cfg.Mail.Host != "" {
+		mailer, err := mail.NewSMTPMailer(mail.Config{
+			Host:     cfg.Mail.Host,
+			Port:     cfg.Mail.Port,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+			TLSMode:  cfg.Mail.TLSMode,
+			Timeout:  time.Duration(cfg.Mail.Timeout) * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+		userUsecase.SetMailer(mailer)
+	} else {
+		log.Printf("WARN: Mail is not configured, password reset and verification emails will not be sent")
+	}
+	go userUsecase.RunSessionCleanup(ctx, time.Duration(cfg.Sessions.CleanupInterval)*time.Minute)
 
 	// Создаем HTTP обработчики
-	fileHandler := handler.NewFileHandler(fileUsecase)
-	authHandler := handler.NewAuthHandler(userUsecase)
+	handlers := handler.Handlers{
+		File: handler.NewFileHandler(fileUsecase),
+		Auth: handler.NewAuthHandler(userUsecase),
+	}
+	if metaCache != nil {
+		handlers.Cache = handler.NewCacheHandler(metaCache)
+	}
+
+	// Возможности, которым нужен PostgreSQL
+	if pgRepo != nil {
+		// Роли пользователей и системные настройки
+		roleUsecase := usecase.NewRoleUsecase(pgRepo)
+		settingsUsecase := usecase.NewSettingsUsecase(pgRepo, roleUsecase)
+		userUsecase.SetRoles(roleUsecase)
+		userUsecase.SetSettings(settingsUsecase)
+		go roleUsecase.Run(ctx, time.Duration(cfg.Roles.ReloadInterval)*time.Second)
+		handlers.Roles = handler.NewRoleHandler(roleUsecase)
+		handlers.Settings = handler.NewSettingsHandler(settingsUsecase)
+		// Фоновое извлечение текста для полнотекстового поиска
+		indexer := usecase.NewContentIndexer(fileStorage, pgRepo, usecase.IndexerConfig{
+			Interval:         time.Duration(cfg.Search.IndexInterval) * time.Second,
+			BatchSize:        cfg.Search.IndexBatchSize,
+			MaxFileSize:      cfg.Search.MaxExtractSize,
+			MaxContentLength: cfg.Search.MaxContentLength,
+		})
+		fileUsecase.SetContentIndexer(indexer)
+		go indexer.Run(ctx)
+		handlers.Search = handler.NewSearchHandler(usecase.NewSearchUsecase(pgRepo))
+
+		// Избранные и недавние файлы
+		favoritesUsecase := usecase.NewFavoritesUsecase(pgRepo)
+		fileUsecase.SetRecentTracker(favoritesUsecase)
+		handlers.Favorites = handler.NewFavoritesHandler(favoritesUsecase)
+
+		// Блокировки файлов
+		lockUsecase := usecase.NewLockUsecase(pgRepo, usecase.LockConfig{
+			DefaultTTL: time.Duration(cfg.Locks.DefaultTTL) * time.Second,
+			MaxTTL:     time.Duration(cfg.Locks.MaxTTL) * time.Second,
+		})
+		fileUsecase.SetFileLocker(lockUsecase)
+		handlers.Lock = handler.NewLockHandler(lockUsecase)
+
+		// Сроки хранения и юридические удержания
+		retentionUsecase := usecase.NewRetentionUsecase(pgRepo, userUsecase, time.Duration(cfg.Retention.MaxDays)*24*time.Hour)
+		fileUsecase.SetProtectionChecker(retentionUsecase)
+		handlers.Retention = handler.NewRetentionHandler(retentionUsecase)
+
+		// Корзина и правила жизненного цикла
+		trashUsecase := usecase.NewTrashUsecase(fileUsecase, pgRepo, time.Duration(cfg.Lifecycle.TrashRetentionDays)*24*time.Hour)
+		lifecycleUsecase := usecase.NewLifecycleUsecase(pgRepo, fileUsecase, trashUsecase, userUsecase, usecase.LifecycleConfig{
+			Interval:         time.Duration(cfg.Lifecycle.Interval) * time.Minute,
+			BatchSize:        cfg.Lifecycle.BatchSize,
+			HistoryRetention: time.Duration(cfg.Lifecycle.HistoryDays) * 24 * time.Hour,
+		})
+		fileUsecase.SetAccessRecorder(lifecycleUsecase)
+		handlers.Trash = handler.NewTrashHandler(trashUsecase)
+		handlers.Lifecycle = handler.NewLifecycleHandler(lifecycleUsecase)
+
+		// Статистика занятого места
+		usageUsecase := usecase.NewUsageUsecase(pgRepo, fileStorage, usecase.UsageConfig{
+			CheckInterval: time.Duration(cfg.Usage.CheckInterval) * time.Minute,
+			Retention:     time.Duration(cfg.Usage.RetentionDays) * 24 * time.Hour,
+			BatchSize:     cfg.Usage.BatchSize,
+		})
+		go usageUsecase.Run(ctx)
+		handlers.Usage = handler.NewUsageHandler(usageUsecase)
+
+		// Обсуждения файлов
+		handlers.Comments = handler.NewCommentsHandler(usecase.NewCommentsUsecase(pgRepo, userUsecase))
+
+		// Персональные токены доступа к API
+		apiTokenUsecase := usecase.NewAPITokenUsecase(pgRepo, userUsecase, usecase.APITokenConfig{
+			DefaultLifetime: time.Duration(cfg.APITokens.DefaultLifetimeDays) * 24 * time.Hour,
+			MaxLifetime:     time.Duration(cfg.APITokens.MaxLifetimeDays) * 24 * time.Hour,
+			MaxPerUser:      cfg.APITokens.MaxPerUser,
+		})
+		handlers.APITokens = handler.NewAPITokenHandler(apiTokenUsecase)
+
+		// Журнал аудита
+		auditUsecase := usecase.NewAuditUsecase(pgRepo, time.Duration(cfg.Audit.RetentionDays)*24*time.Hour)
+		fileUsecase.SetAuditor(auditUsecase)
+		userUsecase.SetAuditor(auditUsecase)
+		lockUsecase.SetAuditor(auditUsecase)
+		retentionUsecase.SetAuditor(auditUsecase)
+		lifecycleUsecase.SetAuditor(auditUsecase)
+		apiTokenUsecase.SetAuditor(auditUsecase)
+		roleUsecase.SetAuditor(auditUsecase)
+		settingsUsecase.SetAuditor(auditUsecase)
+		go auditUsecase.RunRetention(ctx, time.Duration(cfg.Audit.PruneInterval)*time.Minute)
+		handlers.Audit = handler.NewAuditHandler(auditUsecase)
+
+		// Лента изменений файлов
+		eventBroker := usecase.NewFileEventBroker(pgRepo, pgRepo, time.Duration(cfg.Events.RetentionHours)*time.Hour)
+		go eventBroker.Run(ctx, time.Duration(cfg.Events.PruneInterval)*time.Minute)
+		handlers.Events = handler.NewEventsHandler(eventBroker, time.Duration(cfg.Events.HeartbeatInterval)*time.Second)
+
+		// Вебхуки
+		webhookUsecase := usecase.NewWebhookUsecase(pgRepo, usecase.WebhookConfig{
+			MaxAttempts:    cfg.Webhooks.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.Webhooks.InitialBackoff) * time.Second,
+			MaxBackoff:     time.Duration(cfg.Webhooks.MaxBackoff) * time.Second,
+			Timeout:        time.Duration(cfg.Webhooks.Timeout) * time.Second,
+			PollInterval:   time.Duration(cfg.Webhooks.PollInterval) * time.Second,
+			BatchSize:      cfg.Webhooks.BatchSize,
+
+			AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+		})
+		userUsecase.SetWebhookNotifier(webhookUsecase)
+		go webhookUsecase.Run(ctx)
+		handlers.Webhook = handler.NewWebhookHandler(webhookUsecase)
+
+		fileUsecase.SetEventPublisher(usecase.MultiEventPublisher{eventBroker, webhookUsecase})
+
+		// Планировщик удаляет файлы через fileUsecase, поэтому запускается после подключения аудита и событий
+		go lifecycleUsecase.Run(ctx)
+	}
+
+	// Администраторы из конфигурации получают роль admin
+	if err := userUsecase.EnsureAdmins(ctx); err != nil {
+		log.Fatalf("Failed to grant admin roles: %v", err)
+	}
 
 	// Настраиваем роутер
-	router := handler.SetupRouter(fileHandler, authHandler, tokenManager)
+	router, err := handler.SetupRouter(handlers, tokenManager, cfg.HTTP.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
 
 	// Создаем HTTP сервер
 	server := &http.Server{
@@ -82,6 +309,9 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Останавливаем фоновые задачи
+	stop()
+
 	// Создаем контекст с таймаутом для корректного завершения
 	shutdownCtx, cancel := context.WithTimeout(
 		context.Background(),
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -12,7 +15,14 @@ import (
 )
 
 type TokenManager struct {
-	config *config.JWT
+	config     *config.JWT
+	keys       *KeySet // nil — подпись HS256 общими секретами из конфигурации
+	revocation RevocationChecker
+}
+
+// RevocationChecker проверяет, не отозван ли access token с действующей подписью
+type RevocationChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, claims *TokenClaims) (bool, error)
 }
 
 type TokenPair struct {
@@ -20,8 +30,24 @@ type TokenPair struct {
 	RefreshToken string
 }
 
+// Назначение токена. При подписи одним набором ключей не позволяет
+// использовать refresh token вместо access token и наоборот
+const (
+	tokenUseAccess  = "access"
+	tokenUseRefresh = "refresh"
+	tokenUseMFA     = "mfa"
+	tokenUseVerify  = "email_verification"
+)
+
 type TokenClaims struct {
-	UserID uint
+	UserID   uint
+	TokenUse string `json:"token_use,omitempty"`
+	// Роль пользователя на момент выдачи; только в access token
+	Role string `json:"role,omitempty"`
+	// Подтверждаемый адрес; только в токене подтверждения email
+	Email string `json:"email,omitempty"`
+	// Поколение токенов пользователя на момент выдачи; только в access token
+	TokenVersion int `json:"tv,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -29,47 +55,79 @@ type TokenClaims struct {
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token expired")
+	ErrRevokedToken = errors.New("token revoked")
 )
 
-// Создание нового менеджера токенов
-func NewTokenManager(cfg *config.JWT) *TokenManager {
-	return &TokenManager{
+// Создание нового менеджера токенов. Если в конфигурации заданы ключи,
+// токены подписываются ими (RS256, ES256 или EdDSA), иначе — HS256
+func NewTokenManager(cfg *config.JWT) (*TokenManager, error) {
+	m := &TokenManager{
 		config: cfg,
 	}
+
+	if len(cfg.Keys) > 0 {
+		keys, err := LoadKeySet(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt keys: %w", err)
+		}
+		m.keys = keys
+	}
+
+	return m, nil
+}
+
+// SetRevocationChecker подключает проверку отзыва access токенов
+func (m *TokenManager) SetRevocationChecker(checker RevocationChecker) {
+	m.revocation = checker
 }
 
 // Генерация пары токенов (access и refresh)
 func (m *TokenManager) GenerateTokenPair(user *models.User) (*TokenPair, error) {
-	// Генерируем access token
+	// Генерируем access token. ID нужен для отзыва токена до истечения срока
+	accessTokenID, err := newTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate access token id: %w", err)
+	}
+
 	accessTokenExpires := time.Now().Add(time.Minute * time.Duration(m.config.AccessTokenExpiration))
 	accessClaims := TokenClaims{
-		UserID: user.ID,
+		UserID:       user.ID,
+		TokenUse:     tokenUseAccess,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        accessTokenID,
 			ExpiresAt: jwt.NewNumericDate(accessTokenExpires),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(m.config.AccessTokenSecret))
+	accessTokenString, err := m.sign(accessClaims, m.config.AccessTokenSecret)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate access token: %w", err)
 	}
 
-	// Генерируем refresh token
+	// Генерируем refresh token. Случайный ID делает токены уникальными,
+	// даже если пользователь входит с нескольких устройств в одну секунду
+	refreshTokenID, err := newTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate refresh token id: %w", err)
+	}
+
 	refreshTokenExpires := time.Now().Add(time.Hour * time.Duration(m.config.RefreshTokenExpiration))
 	refreshClaims := TokenClaims{
-		UserID: user.ID,
+		UserID:   user.ID,
+		TokenUse: tokenUseRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        refreshTokenID,
 			ExpiresAt: jwt.NewNumericDate(refreshTokenExpires),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(m.config.RefreshTokenSecret))
+	refreshTokenString, err := m.sign(refreshClaims, m.config.RefreshTokenSecret)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate refresh token: %w", err)
 	}
@@ -80,37 +138,145 @@ func (m *TokenManager) GenerateTokenPair(user *models.User) (*TokenPair, error)
 	}, nil
 }
 
-// Валидация access token
-func (m *TokenManager) ParseAccessToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.config.AccessTokenSecret), nil
-	})
+// Генерация токена для второго шага входа: пароль проверен, ожидается код
+// подтверждения. Токен не дает доступа к API и подписывается как access token
+func (m *TokenManager) GenerateMFAToken(user *models.User) (string, time.Time, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("cannot generate mfa token id: %w", err)
+	}
+
+	expires := time.Now().Add(time.Minute * time.Duration(m.config.MFATokenExpiration))
+	claims := TokenClaims{
+		UserID:   user.ID,
+		TokenUse: tokenUseMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	}
 
+	token, err := m.sign(claims, m.config.AccessTokenSecret)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
+		return "", time.Time{}, fmt.Errorf("cannot generate mfa token: %w", err)
+	}
+	return token, time.Unix(claims.ExpiresAt.Unix(), 0), nil
+}
+
+// Валидация токена второго шага входа. Повторное использование
+// проверяет вызывающий по jti
+func (m *TokenManager) ParseMFAToken(tokenString string) (*TokenClaims, error) {
+	claims, err := m.parse(tokenString, tokenUseMFA, m.config.AccessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	// Старые токены без token_use подписаны тем же секретом и не годятся для входа
+	if claims.TokenUse != tokenUseMFA || claims.ID == "" {
 		return nil, ErrInvalidToken
 	}
+	return claims, nil
+}
 
-	claims, ok := token.Claims.(*TokenClaims)
-	if !ok || !token.Valid {
+// Генерация токена для ссылки подтверждения email. Токен действует,
+// пока адрес пользователя совпадает с подписанным
+func (m *TokenManager) GenerateVerificationToken(user *models.User, lifetime time.Duration) (string, error) {
+	claims := TokenClaims{
+		UserID:   user.ID,
+		TokenUse: tokenUseVerify,
+		Email:    user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	}
+
+	token, err := m.sign(claims, m.config.AccessTokenSecret)
+	if err != nil {
+		return "", fmt.Errorf("cannot generate verification token: %w", err)
+	}
+	return token, nil
+}
+
+// Валидация токена подтверждения email
+func (m *TokenManager) ParseVerificationToken(tokenString string) (*TokenClaims, error) {
+	claims, err := m.parse(tokenString, tokenUseVerify, m.config.AccessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != tokenUseVerify || claims.Email == "" {
 		return nil, ErrInvalidToken
 	}
+	return claims, nil
+}
+
+// Валидация access token: подпись, срок действия и отсутствие отзыва
+func (m *TokenManager) ParseAccessToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	claims, err := m.parse(tokenString, tokenUseAccess, m.config.AccessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.revocation != nil {
+		revoked, err := m.revocation.IsAccessTokenRevoked(ctx, claims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
 
 	return claims, nil
 }
 
 // Валидация refresh token
 func (m *TokenManager) ParseRefreshToken(tokenString string) (*TokenClaims, error) {
+	return m.parse(tokenString, tokenUseRefresh, m.config.RefreshTokenSecret)
+}
+
+// JWKS открытые ключи для проверки токенов другими сервисами.
+// При подписи общим секретом список пуст
+func (m *TokenManager) JWKS() []JWK {
+	if m.keys == nil {
+		return []JWK{}
+	}
+	return m.keys.JWKS(time.Now())
+}
+
+// Подпись токена текущим ключом набора или секретом HS256
+func (m *TokenManager) sign(claims TokenClaims, secret string) (string, error) {
+	if m.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	}
+
+	key := m.keys.signing
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+func (m *TokenManager) parse(tokenString, use, secret string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if m.keys == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(secret), nil
+		}
+
+		// Ключ выбирается по kid; алгоритм должен совпадать с типом ключа
+		kid, _ := token.Header["kid"].(string)
+		key := m.keys.verificationKey(kid, time.Now())
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(m.config.RefreshTokenSecret), nil
+		return key.public, nil
 	})
 
 	if err != nil {
@@ -125,5 +291,18 @@ func (m *TokenManager) ParseRefreshToken(tokenString string) (*TokenClaims, erro
 		return nil, ErrInvalidToken
 	}
 
+	// Токены, выпущенные до появления token_use, подписаны раздельными секретами
+	if claims.TokenUse != "" && claims.TokenUse != use {
+		return nil, ErrInvalidToken
+	}
+
 	return claims, nil
 }
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"tages/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Минимальная длина RSA ключа
+const minRSAKeyBits = 2048
+
+// signingKey ключ подписи токенов. Алгоритм определяется типом ключа:
+// RSA — RS256, ECDSA P-256 — ES256, Ed25519 — EdDSA
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.Signer // nil, если известен только открытый ключ
+	public    crypto.PublicKey
+	retiredAt time.Time // нулевое значение — ключ действует
+}
+
+// KeySet набор ключей подписи. Новые токены подписываются одним ключом,
+// выведенные из оборота ключи принимаются при проверке еще grace после вывода
+type KeySet struct {
+	keys    []*signingKey
+	byID    map[string]*signingKey
+	signing *signingKey
+	grace   time.Duration
+}
+
+// JWK открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// LoadKeySet загружает ключи подписи из PEM файлов, перечисленных в конфигурации
+func LoadKeySet(cfg *config.JWT) (*KeySet, error) {
+	set := &KeySet{
+		byID:  make(map[string]*signingKey, len(cfg.Keys)),
+		grace: time.Duration(cfg.RetiredKeyGrace) * time.Hour,
+	}
+
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.ID == "" {
+			return nil, fmt.Errorf("jwt key %s: id is required", keyCfg.File)
+		}
+		if _, ok := set.byID[keyCfg.ID]; ok {
+			return nil, fmt.Errorf("jwt key %s: duplicate id", keyCfg.ID)
+		}
+
+		key, err := loadSigningKey(keyCfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", keyCfg.ID, err)
+		}
+		key.id = keyCfg.ID
+
+		if keyCfg.RetiredAt != "" {
+			if key.retiredAt, err = time.Parse(time.RFC3339, keyCfg.RetiredAt); err != nil {
+				return nil, fmt.Errorf("jwt key %s: invalid retiredAt: %w", keyCfg.ID, err)
+			}
+		}
+
+		set.keys = append(set.keys, key)
+		set.byID[key.id] = key
+	}
+
+	// Ключ подписи: указанный явно или первый действующий
+	if cfg.SigningKeyID != "" {
+		set.signing = set.byID[cfg.SigningKeyID]
+		if set.signing == nil {
+			return nil, fmt.Errorf("jwt signing key %s not found", cfg.SigningKeyID)
+		}
+	} else {
+		for _, key := range set.keys {
+			if key.retiredAt.IsZero() {
+				set.signing = key
+				break
+			}
+		}
+		if set.signing == nil {
+			return nil, errors.New("no active jwt signing key")
+		}
+	}
+	if !set.signing.retiredAt.IsZero() {
+		return nil, fmt.Errorf("jwt signing key %s is retired", set.signing.id)
+	}
+	if set.signing.private == nil {
+		return nil, fmt.Errorf("jwt signing key %s has no private key", set.signing.id)
+	}
+
+	return set, nil
+}
+
+// Ключ для проверки подписи или nil, если ключ неизвестен или срок его приема истек
+func (s *KeySet) verificationKey(kid string, now time.Time) *signingKey {
+	key := s.byID[kid]
+	if key == nil || !key.acceptedAt(now, s.grace) {
+		return nil
+	}
+	return key
+}
+
+// JWKS открытые ключи, по которым сейчас принимаются токены
+func (s *KeySet) JWKS(now time.Time) []JWK {
+	jwks := make([]JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.acceptedAt(now, s.grace) {
+			jwks = append(jwks, key.jwk())
+		}
+	}
+	return jwks
+}
+
+func (k *signingKey) acceptedAt(now time.Time, grace time.Duration) bool {
+	return k.retiredAt.IsZero() || now.Before(k.retiredAt.Add(grace))
+}
+
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{
+		KeyID:     k.id,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// Загрузка ключа из PEM файла: закрытого (PKCS#8, PKCS#1, SEC 1) или открытого (PKIX)
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	key := &signingKey{}
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("rsa key %s is shorter than %d bits", path, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ecdsa key %s must use P-256 curve", path)
+		}
+		key.method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T in %s", key.public, path)
+	}
+
+	return key, nil
+}
@@ -7,10 +7,39 @@ import (
 )
 
 type Config struct {
-	PG   *pg.Config `mapstructure:"db"`
-	HTTP *HTTP      `mapstructure:"http"`
-	App  *App       `mapstructure:"app"`
-	JWT  *JWT       `mapstructure:"jwt"`
+	Metadata          *Metadata          `mapstructure:"metadata"`
+	PG                *pg.Config         `mapstructure:"db"`
+	HTTP              *HTTP              `mapstructure:"http"`
+	App               *App               `mapstructure:"app"`
+	JWT               *JWT               `mapstructure:"jwt"`
+	Sessions          *Sessions          `mapstructure:"sessions"`
+	APITokens         *APITokens         `mapstructure:"apiTokens"`
+	MFA               *MFA               `mapstructure:"mfa"`
+	Password          *Password          `mapstructure:"password"`
+	Mail              *Mail              `mapstructure:"mail"`
+	EmailVerification *EmailVerification `mapstructure:"emailVerification"`
+	LoginThrottle     *LoginThrottle     `mapstructure:"loginThrottle"`
+	Roles             *Roles             `mapstructure:"roles"`
+	Search            *Search            `mapstructure:"search"`
+	Audit             *Audit             `mapstructure:"audit"`
+	Events            *Events            `mapstructure:"events"`
+	Webhooks          *Webhooks          `mapstructure:"webhooks"`
+	Cache             *Cache             `mapstructure:"cache"`
+	Locks             *Locks             `mapstructure:"locks"`
+	Retention         *Retention         `mapstructure:"retention"`
+	Lifecycle         *Lifecycle         `mapstructure:"lifecycle"`
+	Usage             *Usage             `mapstructure:"usage"`
+}
+
+// Хранилища метаданных
+const (
+	MetadataBackendPostgres = "postgres"
+	MetadataBackendBolt     = "bolt"
+)
+
+type Metadata struct {
+	Backend  string `mapstructure:"backend"`  // postgres или bolt
+	BoltPath string `mapstructure:"boltPath"` // файл встроенной базы для bolt
 }
 
 type HTTP struct {
@@ -18,12 +47,20 @@ type HTTP struct {
 	ReadTimeout     int    `mapstructure:"readTimeout"`
 	WriteTimeout    int    `mapstructure:"writeTimeout"`
 	ShutdownTimeout int    `mapstructure:"shutdownTimeout"`
+	// Адреса и подсети прокси, которым доверяется X-Forwarded-For.
+	// Пустой список — адрес клиента берется из соединения
+	TrustedProxies []string `mapstructure:"trustedProxies"`
 }
 
 type App struct {
-	UploadLimiterConcurrency int    `mapstructure:"uploadLimiterConcurrency"`
-	ListLimiterConcurrency   int    `mapstructure:"listLimiterConcurrency"`
-	UploadDir                string `mapstructure:"uploadDir"`
+	UploadLimiterConcurrency int      `mapstructure:"uploadLimiterConcurrency"`
+	ListLimiterConcurrency   int      `mapstructure:"listLimiterConcurrency"`
+	UploadDir                string   `mapstructure:"uploadDir"`
+	MaxFileTags              int      `mapstructure:"maxFileTags"`
+	MaxFileAttributes        int      `mapstructure:"maxFileAttributes"`
+	MaxMetadataKeyLength     int      `mapstructure:"maxMetadataKeyLength"`
+	MaxMetadataValueLength   int      `mapstructure:"maxMetadataValueLength"`
+	AdminEmails              []string `mapstructure:"adminEmails"`
 }
 
 type JWT struct {
@@ -31,6 +68,132 @@ type JWT struct {
 	RefreshTokenExpiration int    `mapstructure:"refreshTokenExpiration"` // в часах
 	AccessTokenSecret      string `mapstructure:"accessTokenSecret"`
 	RefreshTokenSecret     string `mapstructure:"refreshTokenSecret"`
+	MFATokenExpiration     int    `mapstructure:"mfaTokenExpiration"` // в минутах
+
+	// Асимметричная подпись. Если ключи не заданы, используется HS256 с секретами выше
+	SigningKeyID    string   `mapstructure:"signingKeyID"`    // пусто — первый действующий ключ
+	RetiredKeyGrace int      `mapstructure:"retiredKeyGrace"` // в часах
+	Keys            []JWTKey `mapstructure:"keys"`
+}
+
+// JWTKey ключ подписи токенов. Алгоритм определяется типом ключа:
+// RSA — RS256, ECDSA P-256 — ES256, Ed25519 — EdDSA
+type JWTKey struct {
+	ID        string `mapstructure:"id"`        // публикуется в заголовке kid
+	File      string `mapstructure:"file"`      // PEM; для выведенных ключей достаточно открытого
+	RetiredAt string `mapstructure:"retiredAt"` // RFC 3339; пусто — ключ действует
+}
+
+type Sessions struct {
+	CleanupInterval int `mapstructure:"cleanupInterval"` // в минутах
+}
+
+type APITokens struct {
+	DefaultLifetimeDays int `mapstructure:"defaultLifetimeDays"`
+	MaxLifetimeDays     int `mapstructure:"maxLifetimeDays"`
+	MaxPerUser          int `mapstructure:"maxPerUser"`
+}
+
+type MFA struct {
+	Issuer        string `mapstructure:"issuer"`        // название сервиса в приложении-аутентификаторе
+	RecoveryCodes int    `mapstructure:"recoveryCodes"` // количество кодов восстановления
+}
+
+type Password struct {
+	ResetTokenLifetime int    `mapstructure:"resetTokenLifetime"` // в минутах
+	ResetURL           string `mapstructure:"resetURL"`           // страница сброса пароля, токен в параметре token
+}
+
+type EmailVerification struct {
+	TokenLifetime    int    `mapstructure:"tokenLifetime"`    // в часах
+	URL              string `mapstructure:"url"`              // адрес подтверждения, токен в параметре token
+	ResendInterval   int    `mapstructure:"resendInterval"`   // в секундах
+	UnverifiedAccess string `mapstructure:"unverifiedAccess"` // full, read_only или none
+}
+
+// LoginThrottle защита от подбора паролей. После maxFailures неудачных попыток
+// учетная запись или IP блокируются на baseLockout, каждая следующая неудача
+// удваивает блокировку до maxLockout
+type LoginThrottle struct {
+	AccountMaxFailures int `mapstructure:"accountMaxFailures"`
+	IPMaxFailures      int `mapstructure:"ipMaxFailures"`
+	BaseLockout        int `mapstructure:"baseLockout"` // в секундах
+	MaxLockout         int `mapstructure:"maxLockout"`  // в секундах
+	ResetAfter         int `mapstructure:"resetAfter"`  // в минутах без неудачных попыток счетчик обнуляется
+}
+
+// Mail SMTP сервер для писем пользователям. Пустой host — почта не отправляется
+type Mail struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	TLSMode  string `mapstructure:"tlsMode"` // starttls, tls или none
+	Timeout  int    `mapstructure:"timeout"` // в секундах
+}
+
+type Roles struct {
+	ReloadInterval int `mapstructure:"reloadInterval"` // в секундах
+}
+
+type Search struct {
+	IndexInterval    int   `mapstructure:"indexInterval"` // в секундах
+	IndexBatchSize   int   `mapstructure:"indexBatchSize"`
+	MaxExtractSize   int64 `mapstructure:"maxExtractSize"`   // в байтах
+	MaxContentLength int   `mapstructure:"maxContentLength"` // в байтах
+}
+
+type Audit struct {
+	RetentionDays int `mapstructure:"retentionDays"` // 0 — хранить бессрочно
+	PruneInterval int `mapstructure:"pruneInterval"` // в минутах
+}
+
+type Events struct {
+	RetentionHours    int `mapstructure:"retentionHours"`
+	PruneInterval     int `mapstructure:"pruneInterval"`     // в минутах
+	HeartbeatInterval int `mapstructure:"heartbeatInterval"` // в секундах
+}
+
+type Cache struct {
+	Enabled bool `mapstructure:"enabled"`
+	Size    int  `mapstructure:"size"` // записей в каждом кэше
+	TTL     int  `mapstructure:"ttl"`  // в секундах
+}
+
+type Locks struct {
+	DefaultTTL int `mapstructure:"defaultTTL"` // в секундах
+	MaxTTL     int `mapstructure:"maxTTL"`     // в секундах
+}
+
+type Retention struct {
+	MaxDays int `mapstructure:"maxDays"` // наибольший срок хранения от текущего момента
+}
+
+type Lifecycle struct {
+	Interval           int `mapstructure:"interval"` // в минутах
+	BatchSize          int `mapstructure:"batchSize"`
+	TrashRetentionDays int `mapstructure:"trashRetentionDays"` // 0 — хранить до ручной очистки
+	HistoryDays        int `mapstructure:"historyDays"`        // 0 — хранить бессрочно
+}
+
+type Usage struct {
+	CheckInterval int `mapstructure:"checkInterval"` // в минутах
+	RetentionDays int `mapstructure:"retentionDays"` // 0 — хранить бессрочно
+	BatchSize     int `mapstructure:"batchSize"`
+}
+
+type Webhooks struct {
+	MaxAttempts    int `mapstructure:"maxAttempts"`
+	InitialBackoff int `mapstructure:"initialBackoff"` // в секундах
+	MaxBackoff     int `mapstructure:"maxBackoff"`     // в секундах
+	Timeout        int `mapstructure:"timeout"`        // в секундах
+	PollInterval   int `mapstructure:"pollInterval"`   // в секундах
+	BatchSize      int `mapstructure:"batchSize"`
+	// Разрешить доставку на адреса внутренней сети (localhost, частные и
+	// link-local адреса). По умолчанию запрещено, чтобы вебхуками нельзя было
+	// обращаться к внутренним сервисам
+	AllowPrivateNetworks bool `mapstructure:"allowPrivateNetworks"`
 }
 
 func InitConfig(path string) (*Config, error) {
@@ -51,6 +214,17 @@ func InitConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	// По умолчанию метаданные хранятся в PostgreSQL
+	if cfg.Metadata == nil {
+		cfg.Metadata = &Metadata{}
+	}
+	if cfg.Metadata.Backend == "" {
+		cfg.Metadata.Backend = MetadataBackendPostgres
+	}
+	if cfg.Metadata.BoltPath == "" {
+		cfg.Metadata.BoltPath = "./data/metadata.db"
+	}
+
 	// Значения по умолчанию для HTTP
 	if cfg.HTTP == nil {
 		cfg.HTTP = &HTTP{
@@ -61,6 +235,23 @@ func InitConfig(path string) (*Config, error) {
 		}
 	}
 
+	// Значения по умолчанию для ограничений метаданных файлов
+	if cfg.App == nil {
+		cfg.App = &App{UploadDir: "./uploads"}
+	}
+	if cfg.App.MaxFileTags == 0 {
+		cfg.App.MaxFileTags = 32
+	}
+	if cfg.App.MaxFileAttributes == 0 {
+		cfg.App.MaxFileAttributes = 32
+	}
+	if cfg.App.MaxMetadataKeyLength == 0 {
+		cfg.App.MaxMetadataKeyLength = 64
+	}
+	if cfg.App.MaxMetadataValueLength == 0 {
+		cfg.App.MaxMetadataValueLength = 256
+	}
+
 	// Значения по умолчанию для JWT
 	if cfg.JWT == nil {
 		cfg.JWT = &JWT{
@@ -70,6 +261,239 @@ func InitConfig(path string) (*Config, error) {
 			RefreshTokenSecret:     "refresh_secret_key_change_in_production",
 		}
 	}
+	if cfg.JWT.MFATokenExpiration == 0 {
+		cfg.JWT.MFATokenExpiration = 5
+	}
+	// Выведенный ключ принимается, пока могут действовать подписанные им refresh токены
+	if cfg.JWT.RetiredKeyGrace == 0 {
+		cfg.JWT.RetiredKeyGrace = cfg.JWT.RefreshTokenExpiration
+	}
+
+	// Значения по умолчанию для сессий пользователей
+	if cfg.Sessions == nil {
+		cfg.Sessions = &Sessions{}
+	}
+	if cfg.Sessions.CleanupInterval == 0 {
+		cfg.Sessions.CleanupInterval = 60
+	}
+
+	// Значения по умолчанию для ролей
+	if cfg.Roles == nil {
+		cfg.Roles = &Roles{}
+	}
+	if cfg.Roles.ReloadInterval == 0 {
+		cfg.Roles.ReloadInterval = 30
+	}
+
+	// Значения по умолчанию для персональных токенов доступа
+	if cfg.APITokens == nil {
+		cfg.APITokens = &APITokens{}
+	}
+	if cfg.APITokens.DefaultLifetimeDays == 0 {
+		cfg.APITokens.DefaultLifetimeDays = 30
+	}
+	if cfg.APITokens.MaxLifetimeDays == 0 {
+		cfg.APITokens.MaxLifetimeDays = 365
+	}
+	if cfg.APITokens.MaxPerUser == 0 {
+		cfg.APITokens.MaxPerUser = 50
+	}
+	if cfg.APITokens.DefaultLifetimeDays > cfg.APITokens.MaxLifetimeDays {
+		cfg.APITokens.DefaultLifetimeDays = cfg.APITokens.MaxLifetimeDays
+	}
+
+	// Значения по умолчанию для двухфакторной аутентификации
+	if cfg.MFA == nil {
+		cfg.MFA = &MFA{}
+	}
+	if cfg.MFA.Issuer == "" {
+		cfg.MFA.Issuer = "Tages"
+	}
+	if cfg.MFA.RecoveryCodes == 0 {
+		cfg.MFA.RecoveryCodes = 10
+	}
+
+	// Значения по умолчанию для смены пароля
+	if cfg.Password == nil {
+		cfg.Password = &Password{}
+	}
+	if cfg.Password.ResetTokenLifetime == 0 {
+		cfg.Password.ResetTokenLifetime = 60
+	}
+	if cfg.Password.ResetURL == "" {
+		cfg.Password.ResetURL = "http://localhost:3000/reset-password"
+	}
+
+	// Значения по умолчанию для подтверждения email
+	if cfg.EmailVerification == nil {
+		cfg.EmailVerification = &EmailVerification{}
+	}
+	if cfg.EmailVerification.TokenLifetime == 0 {
+		cfg.EmailVerification.TokenLifetime = 48
+	}
+	if cfg.EmailVerification.URL == "" {
+		cfg.EmailVerification.URL = "http://localhost:8080/api/auth/verify-email"
+	}
+	if cfg.EmailVerification.ResendInterval == 0 {
+		cfg.EmailVerification.ResendInterval = 60
+	}
+	if cfg.EmailVerification.UnverifiedAccess == "" {
+		cfg.EmailVerification.UnverifiedAccess = "full"
+	}
+
+	// Значения по умолчанию для защиты от подбора паролей
+	if cfg.LoginThrottle == nil {
+		cfg.LoginThrottle = &LoginThrottle{}
+	}
+	if cfg.LoginThrottle.AccountMaxFailures == 0 {
+		cfg.LoginThrottle.AccountMaxFailures = 5
+	}
+	if cfg.LoginThrottle.IPMaxFailures == 0 {
+		cfg.LoginThrottle.IPMaxFailures = 20
+	}
+	if cfg.LoginThrottle.BaseLockout == 0 {
+		cfg.LoginThrottle.BaseLockout = 60
+	}
+	if cfg.LoginThrottle.MaxLockout == 0 {
+		cfg.LoginThrottle.MaxLockout = 3600
+	}
+	if cfg.LoginThrottle.ResetAfter == 0 {
+		cfg.LoginThrottle.ResetAfter = 30
+	}
+
+	// Значения по умолчанию для почты
+	if cfg.Mail == nil {
+		cfg.Mail = &Mail{}
+	}
+	if cfg.Mail.Port == 0 {
+		cfg.Mail.Port = 587
+	}
+	if cfg.Mail.TLSMode == "" {
+		cfg.Mail.TLSMode = "starttls"
+	}
+	if cfg.Mail.Timeout == 0 {
+		cfg.Mail.Timeout = 30
+	}
+
+	// Значения по умолчанию для поиска
+	if cfg.Search == nil {
+		cfg.Search = &Search{}
+	}
+	if cfg.Search.IndexInterval == 0 {
+		cfg.Search.IndexInterval = 30
+	}
+	if cfg.Search.IndexBatchSize == 0 {
+		cfg.Search.IndexBatchSize = 50
+	}
+	if cfg.Search.MaxExtractSize == 0 {
+		cfg.Search.MaxExtractSize = 20 << 20 // 20 МБ
+	}
+	if cfg.Search.MaxContentLength == 0 {
+		cfg.Search.MaxContentLength = 512 << 10 // 512 КБ
+	}
+
+	// Значения по умолчанию для журнала аудита
+	if cfg.Audit == nil {
+		cfg.Audit = &Audit{}
+	}
+	if cfg.Audit.PruneInterval == 0 {
+		cfg.Audit.PruneInterval = 60
+	}
+
+	// Значения по умолчанию для ленты изменений
+	if cfg.Events == nil {
+		cfg.Events = &Events{}
+	}
+	if cfg.Events.RetentionHours == 0 {
+		cfg.Events.RetentionHours = 24 * 7
+	}
+	if cfg.Events.PruneInterval == 0 {
+		cfg.Events.PruneInterval = 60
+	}
+	if cfg.Events.HeartbeatInterval == 0 {
+		cfg.Events.HeartbeatInterval = 25
+	}
+
+	// Значения по умолчанию для кэша
+	if cfg.Cache == nil {
+		cfg.Cache = &Cache{}
+	}
+	if cfg.Cache.Size == 0 {
+		cfg.Cache.Size = 10000
+	}
+	if cfg.Cache.TTL == 0 {
+		cfg.Cache.TTL = 60
+	}
+
+	// Значения по умолчанию для блокировок файлов
+	if cfg.Locks == nil {
+		cfg.Locks = &Locks{}
+	}
+	if cfg.Locks.DefaultTTL == 0 {
+		cfg.Locks.DefaultTTL = 30 * 60
+	}
+	if cfg.Locks.MaxTTL == 0 {
+		cfg.Locks.MaxTTL = 24 * 60 * 60
+	}
+	if cfg.Locks.DefaultTTL > cfg.Locks.MaxTTL {
+		cfg.Locks.DefaultTTL = cfg.Locks.MaxTTL
+	}
+
+	// Значения по умолчанию для сроков хранения
+	if cfg.Retention == nil {
+		cfg.Retention = &Retention{}
+	}
+	if cfg.Retention.MaxDays == 0 {
+		cfg.Retention.MaxDays = 10 * 365
+	}
+
+	// Значения по умолчанию для правил жизненного цикла
+	if cfg.Lifecycle == nil {
+		cfg.Lifecycle = &Lifecycle{
+			TrashRetentionDays: 30,
+			HistoryDays:        30,
+		}
+	}
+	if cfg.Lifecycle.Interval == 0 {
+		cfg.Lifecycle.Interval = 60
+	}
+	if cfg.Lifecycle.BatchSize == 0 {
+		cfg.Lifecycle.BatchSize = 100
+	}
+
+	// Значения по умолчанию для статистики занятого места
+	if cfg.Usage == nil {
+		cfg.Usage = &Usage{}
+	}
+	if cfg.Usage.CheckInterval == 0 {
+		cfg.Usage.CheckInterval = 60
+	}
+	if cfg.Usage.BatchSize == 0 {
+		cfg.Usage.BatchSize = 100
+	}
+
+	// Значения по умолчанию для вебхуков
+	if cfg.Webhooks == nil {
+		cfg.Webhooks = &Webhooks{}
+	}
+	if cfg.Webhooks.MaxAttempts == 0 {
+		cfg.Webhooks.MaxAttempts = 8
+	}
+	if cfg.Webhooks.InitialBackoff == 0 {
+		cfg.Webhooks.InitialBackoff = 10
+	}
+	if cfg.Webhooks.MaxBackoff == 0 {
+		cfg.Webhooks.MaxBackoff = 3600
+	}
+	if cfg.Webhooks.Timeout == 0 {
+		cfg.Webhooks.Timeout = 10
+	}
+	if cfg.Webhooks.PollInterval == 0 {
+		cfg.Webhooks.PollInterval = 5
+	}
+	if cfg.Webhooks.BatchSize == 0 {
+		cfg.Webhooks.BatchSize = 20
+	}
 
 	return &cfg, nil
 }
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	apiTokenUsecase *usecase.APITokenUsecase
+}
+
+func NewAPITokenHandler(apiTokenUsecase *usecase.APITokenUsecase) *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenUsecase: apiTokenUsecase,
+	}
+}
+
+// CreateAPITokenRequest структура для выпуска персонального токена
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=128"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	AllowedIPs    []string `json:"allowed_ips"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+}
+
+// CreateAPITokenResponse ответ с выпущенным токеном. Токен показывается только здесь
+type CreateAPITokenResponse struct {
+	*models.APIToken
+	Token string `json:"token"`
+}
+
+// CreateHandler выпускает персональный токен пользователя
+func (h *APITokenHandler) CreateHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, raw, err := h.apiTokenUsecase.Create(c.Request.Context(), userID, req.Name, req.Scopes, req.AllowedIPs, lifetime)
+	if err != nil {
+		log.Printf("ERROR: Failed to create api token: %v", err)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidAPIToken):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверные параметры токена: " + err.Error(),
+			})
+		case errors.Is(err, usecase.ErrAPITokenForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "роль пользователя не дает запрошенных прав",
+			})
+		case errors.Is(err, usecase.ErrAPITokenLimit):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "превышено количество токенов",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка создания токена",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPITokenResponse{
+		APIToken: token,
+		Token:    raw,
+	})
+}
+
+// ListHandler возвращает персональные токены пользователя без секретов
+func (h *APITokenHandler) ListHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	tokens, err := h.apiTokenUsecase.List(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list api tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения списка токенов",
+		})
+		return
+	}
+	if tokens == nil {
+		tokens = []*models.APIToken{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// RevokeHandler отзывает персональный токен пользователя
+func (h *APITokenHandler) RevokeHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор токена",
+		})
+		return
+	}
+
+	if err := h.apiTokenUsecase.Revoke(c.Request.Context(), userID, uint(id)); err != nil {
+		log.Printf("ERROR: Failed to revoke api token: %v", err)
+		if errors.Is(err, models.ErrAPITokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "токен не найден",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка отзыва токена",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "токен отозван",
+	})
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditUsecase *usecase.AuditUsecase
+}
+
+func NewAuditHandler(auditUsecase *usecase.AuditUsecase) *AuditHandler {
+	return &AuditHandler{
+		auditUsecase: auditUsecase,
+	}
+}
+
+// ListHandler возвращает события журнала аудита с фильтрацией:
+// ?actor_id=&action=&target=&outcome=&from=&to=&limit=&offset=
+func (h *AuditHandler) ListHandler(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные параметры фильтра: " + err.Error(),
+		})
+		return
+	}
+
+	events, err := h.auditUsecase.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("ERROR: Failed to list audit events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения журнала аудита",
+		})
+		return
+	}
+
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// ExportHandler выгружает события журнала в формате JSON Lines
+func (h *AuditHandler) ExportHandler(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные параметры фильтра: " + err.Error(),
+		})
+		return
+	}
+
+	// Выгрузка большого журнала идет дольше общего таймаута записи сервера
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARN: Failed to reset write deadline for audit export: %v", err)
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", "attachment; filename=audit-events.jsonl")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	count := 0
+	lastFlush := time.Now()
+	err = h.auditUsecase.Export(c.Request.Context(), filter, func(event *models.AuditEvent) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		// Сбрасываем буфер каждые 500 событий или раз в секунду, если фильтр отбирает редкие события
+		if count++; count%500 == 0 || time.Since(lastFlush) >= time.Second {
+			c.Writer.Flush()
+			lastFlush = time.Now()
+		}
+		return nil
+	})
+	c.Writer.Flush()
+	if err != nil {
+		// Заголовки уже отправлены, поэтому только логируем
+		log.Printf("ERROR: Failed to export audit events: %v", err)
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (*models.AuditFilter, error) {
+	filter := &models.AuditFilter{}
+
+	if value := c.Query("actor_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		actorID := uint(id)
+		filter.ActorID = &actorID
+	}
+	if value := c.Query("action"); value != "" {
+		filter.Action = &value
+	}
+	if value := c.Query("target"); value != "" {
+		filter.Target = &value
+	}
+	if value := c.Query("outcome"); value != "" {
+		filter.Outcome = &value
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.To = &to
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Limit = limit
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
@@ -1,8 +1,11 @@
 package http
 
 import (
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"tages/internal/usecase"
@@ -22,14 +25,16 @@ func NewAuthHandler(userUsecase *usecase.UserUsecase) *AuthHandler {
 
 // RegisterRequest структура для регистрации пользователя
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=6"`
+	DeviceName string `json:"device_name" binding:"max=128"`
 }
 
 // LoginRequest структура для авторизации пользователя
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name" binding:"max=128"`
 }
 
 // TokenResponse структура ответа с токеном
@@ -40,8 +45,10 @@ type TokenResponse struct {
 
 // UserInfo структура с информацией о пользователе
 type UserInfo struct {
-	ID    uint   `json:"id"`
-	Email string `json:"email"`
+	ID            uint   `json:"id"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 // RefreshResponse структура ответа при обновлении токена
@@ -60,13 +67,20 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	user, tokens, err := h.userUsecase.Register(c.Request.Context(), req.Email, req.Password)
+	user, tokens, err := h.userUsecase.Register(c.Request.Context(), req.Email, req.Password, req.DeviceName)
 	if err != nil {
 		log.Printf("ERROR: Failed to register user: %v", err)
+		if writeLockoutError(c, err) {
+			return
+		}
 		if err == usecase.ErrUserAlreadyExists {
 			c.JSON(http.StatusConflict, gin.H{
 				"error": "пользователь с таким email уже существует",
 			})
+		} else if err == usecase.ErrRegistrationClosed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "регистрация закрыта",
+			})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "ошибка регистрации: " + err.Error(),
@@ -82,8 +96,10 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, TokenResponse{
 		AccessToken: tokens.AccessToken,
 		User: UserInfo{
-			ID:    user.ID,
-			Email: user.Email,
+			ID:            user.ID,
+			Email:         user.Email,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
 		},
 	})
 }
@@ -98,9 +114,12 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	user, tokens, err := h.userUsecase.Login(c.Request.Context(), req.Email, req.Password)
+	result, err := h.userUsecase.Login(c.Request.Context(), req.Email, req.Password, req.DeviceName)
 	if err != nil {
 		log.Printf("ERROR: Failed to login: %v", err)
+		if writeLockoutError(c, err) {
+			return
+		}
 		if err == usecase.ErrInvalidCredentials {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "неверный email или пароль",
@@ -113,15 +132,27 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
+	// Включена двухфакторная аутентификация: токены выдаются после ввода кода
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, MFARequiredResponse{
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+			ExpiresIn:   int(time.Until(result.MFAExpiresAt).Seconds()),
+		})
+		return
+	}
+
 	// Устанавливаем refresh token в httpOnly cookie
-	setRefreshTokenCookie(c, tokens.RefreshToken)
+	setRefreshTokenCookie(c, result.Tokens.RefreshToken)
 
 	// Возвращаем access token и данные пользователя
 	c.JSON(http.StatusOK, TokenResponse{
-		AccessToken: tokens.AccessToken,
+		AccessToken: result.Tokens.AccessToken,
 		User: UserInfo{
-			ID:    user.ID,
-			Email: user.Email,
+			ID:            result.User.ID,
+			Email:         result.User.Email,
+			Role:          result.User.Role,
+			EmailVerified: result.User.EmailVerified,
 		},
 	})
 }
@@ -141,6 +172,9 @@ func (h *AuthHandler) RefreshTokenHandler(c *gin.Context) {
 	tokens, err := h.userUsecase.RefreshToken(c.Request.Context(), refreshToken)
 	if err != nil {
 		log.Printf("ERROR: Failed to refresh token: %v", err)
+		if writeLockoutError(c, err) {
+			return
+		}
 		if err == usecase.ErrInvalidToken {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "неверный refresh token",
@@ -162,13 +196,16 @@ func (h *AuthHandler) RefreshTokenHandler(c *gin.Context) {
 	})
 }
 
-// LogoutHandler обрабатывает выход из системы
+// LogoutHandler обрабатывает выход из системы: завершает сессию
+// и отзывает access token, если он передан
 func (h *AuthHandler) LogoutHandler(c *gin.Context) {
 	// Получаем refresh token из cookie
-	refreshToken, err := getRefreshTokenFromCookie(c)
-	if err == nil {
+	refreshToken, _ := getRefreshTokenFromCookie(c)
+	accessToken := getAccessToken(c)
+
+	if refreshToken != "" || accessToken != "" {
 		// Удаляем токен из базы данных
-		if err := h.userUsecase.Logout(c.Request.Context(), refreshToken); err != nil {
+		if err := h.userUsecase.Logout(c.Request.Context(), refreshToken, accessToken); err != nil {
 			log.Printf("WARN: Failed to logout: %v", err)
 		}
 	}
@@ -182,6 +219,48 @@ func (h *AuthHandler) LogoutHandler(c *gin.Context) {
 	})
 }
 
+// LogoutAllHandler завершает все сессии пользователя и отзывает все его access токены
+func (h *AuthHandler) LogoutAllHandler(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "пользователь не авторизован",
+		})
+		return
+	}
+
+	if err := h.userUsecase.LogoutAll(c.Request.Context(), userID); err != nil {
+		log.Printf("ERROR: Failed to logout everywhere: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка выхода на всех устройствах",
+		})
+		return
+	}
+
+	deleteRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "выход выполнен на всех устройствах",
+	})
+}
+
+// Ответ на попытку во время блокировки после неудачных попыток. Возвращает
+// false, если err — другая ошибка
+func writeLockoutError(c *gin.Context, err error) bool {
+	var lockout *usecase.LockoutError
+	if !errors.As(err, &lockout) {
+		return false
+	}
+
+	seconds := strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds())))
+	c.Header("Retry-After", seconds)
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": "слишком много неудачных попыток, повторите через " + seconds + " с",
+	})
+	return true
+}
+
 // Установка refresh token в cookie
 func setRefreshTokenCookie(c *gin.Context, token string) {
 	c.SetCookie(
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+
+	"tages/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheStatsSource источник счетчиков кэша
+type CacheStatsSource interface {
+	Stats() []models.CacheStats
+}
+
+type CacheHandler struct {
+	cache CacheStatsSource
+}
+
+func NewCacheHandler(cache CacheStatsSource) *CacheHandler {
+	return &CacheHandler{
+		cache: cache,
+	}
+}
+
+// StatsHandler возвращает число записей, попаданий и промахов по каждому кэшу
+func (h *CacheHandler) StatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"caches": h.cache.Stats(),
+	})
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentsHandler struct {
+	commentsUsecase *usecase.CommentsUsecase
+}
+
+func NewCommentsHandler(commentsUsecase *usecase.CommentsUsecase) *CommentsHandler {
+	return &CommentsHandler{
+		commentsUsecase: commentsUsecase,
+	}
+}
+
+// CommentRequest структура для создания и изменения комментария
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID int64  `json:"parent_id"`
+}
+
+// ListHandler возвращает ветки обсуждения файла
+func (h *CommentsHandler) ListHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	comments, err := h.commentsUsecase.List(c.Request.Context(), filename)
+	if err != nil {
+		writeCommentError(c, err, "ошибка получения комментариев")
+		return
+	}
+
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+	})
+}
+
+// CreateHandler добавляет комментарий или ответ к файлу
+func (h *CommentsHandler) CreateHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	comment, err := h.commentsUsecase.Create(c.Request.Context(), userID, filename, req.ParentID, req.Body)
+	if err != nil {
+		writeCommentError(c, err, "ошибка создания комментария")
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateHandler изменяет текст своего комментария
+func (h *CommentsHandler) UpdateHandler(c *gin.Context) {
+	id, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	comment, err := h.commentsUsecase.Update(c.Request.Context(), userID, id, req.Body)
+	if err != nil {
+		writeCommentError(c, err, "ошибка изменения комментария")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteHandler удаляет комментарий вместе с ответами
+func (h *CommentsHandler) DeleteHandler(c *gin.Context) {
+	id, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.commentsUsecase.Delete(c.Request.Context(), userID, id); err != nil {
+		writeCommentError(c, err, "ошибка удаления комментария")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "комментарий удален",
+	})
+}
+
+// ResolveHandler помечает ветку обсуждения решенной
+func (h *CommentsHandler) ResolveHandler(c *gin.Context) {
+	h.resolve(c, true)
+}
+
+// ReopenHandler снимает отметку о решении
+func (h *CommentsHandler) ReopenHandler(c *gin.Context) {
+	h.resolve(c, false)
+}
+
+// MentionsHandler возвращает комментарии с упоминанием текущего пользователя:
+// ?page=1&page_size=20
+func (h *CommentsHandler) MentionsHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	userID, _ := GetUserID(c)
+	result, err := h.commentsUsecase.Mentions(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		writeCommentError(c, err, "ошибка получения упоминаний")
+		return
+	}
+
+	comments := result.Comments
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"comments":  comments,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
+
+func (h *CommentsHandler) resolve(c *gin.Context, resolved bool) {
+	id, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	comment, err := h.commentsUsecase.Resolve(c.Request.Context(), userID, id, resolved)
+	if err != nil {
+		writeCommentError(c, err, "ошибка изменения статуса комментария")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func parseCommentID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор комментария",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeCommentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "файл не найден",
+		})
+	case errors.Is(err, models.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "комментарий не найден",
+		})
+	case errors.Is(err, usecase.ErrCommentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "недостаточно прав для изменения комментария",
+		})
+	case errors.Is(err, usecase.ErrInvalidComment):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный комментарий: " + err.Error(),
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmailRequest структура для подтверждения email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmailHandler подтверждает email по токену. Токен передается
+// в параметре token ссылки из письма или в теле POST запроса
+func (h *AuthHandler) VerifyEmailHandler(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверный формат данных: " + err.Error(),
+			})
+			return
+		}
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "токен подтверждения не указан",
+		})
+		return
+	}
+
+	if _, err := h.userUsecase.VerifyEmail(c.Request.Context(), token); err != nil {
+		log.Printf("ERROR: Failed to verify email: %v", err)
+		if errors.Is(err, usecase.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "ссылка для подтверждения недействительна или истекла",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка подтверждения email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "email подтвержден",
+	})
+}
+
+// ResendVerificationHandler повторно отправляет письмо для подтверждения email
+func (h *AuthHandler) ResendVerificationHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	retryAfter, err := h.userUsecase.ResendVerificationEmail(c.Request.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrEmailAlreadyVerified):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "email уже подтвержден",
+			})
+		case errors.Is(err, usecase.ErrVerificationThrottled):
+			seconds := int(retryAfter.Seconds())
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "письмо уже отправлено, повторите через " + strconv.Itoa(seconds) + " с",
+			})
+		default:
+			log.Printf("ERROR: Failed to resend verification email: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка отправки письма",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "письмо для подтверждения email отправлено",
+	})
+}
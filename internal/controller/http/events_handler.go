@@ -0,0 +1,179 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	sseRetryInterval  = 3 * time.Second
+	wsWriteTimeout    = 10 * time.Second
+)
+
+type EventsHandler struct {
+	broker    *usecase.FileEventBroker
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+}
+
+func NewEventsHandler(broker *usecase.FileEventBroker, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{
+		broker:    broker,
+		heartbeat: heartbeat,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || slices.Contains(allowedOrigins, origin)
+			},
+		},
+	}
+}
+
+// StreamHandler отдает ленту изменений файлов через Server-Sent Events.
+// Для продолжения после обрыва клиент передает заголовок Last-Event-ID
+// (браузерный EventSource делает это сам) или параметр ?last_event_id=
+func (h *EventsHandler) StreamHandler(c *gin.Context) {
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор последнего события",
+		})
+		return
+	}
+
+	events, err := h.broker.Subscribe(c.Request.Context(), lastEventID)
+	if err != nil {
+		log.Printf("ERROR: Failed to subscribe to file events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка подписки на события",
+		})
+		return
+	}
+
+	// Поток живет дольше WriteTimeout сервера
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARN: Failed to reset write deadline for event stream: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetryInterval.Milliseconds())
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("ERROR: Failed to encode file event %d: %v", event.ID, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// WebSocketHandler отдает ленту изменений файлов через WebSocket.
+// Каждое сообщение — JSON события; для продолжения передается ?last_event_id=
+func (h *EventsHandler) WebSocketHandler(c *gin.Context) {
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор последнего события",
+		})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("ERROR: Failed to upgrade to websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx := c.Request.Context()
+	events, err := h.broker.Subscribe(ctx, lastEventID)
+	if err != nil {
+		log.Printf("ERROR: Failed to subscribe to file events: %v", err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "subscription failed"),
+			time.Now().Add(wsWriteTimeout))
+		return
+	}
+
+	// Читаем входящие сообщения только чтобы заметить закрытие соединения клиентом
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func parseLastEventID(c *gin.Context) (int64, error) {
+	value := c.GetHeader(lastEventIDHeader)
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FavoritesHandler struct {
+	favoritesUsecase *usecase.FavoritesUsecase
+}
+
+func NewFavoritesHandler(favoritesUsecase *usecase.FavoritesUsecase) *FavoritesHandler {
+	return &FavoritesHandler{
+		favoritesUsecase: favoritesUsecase,
+	}
+}
+
+type starredFileInfo struct {
+	fileInfo
+	StarredAt string `json:"starred_at"`
+}
+
+type recentFileInfo struct {
+	fileInfo
+	Action     string `json:"action"`
+	AccessedAt string `json:"accessed_at"`
+}
+
+// StarHandler добавляет файл в избранное текущего пользователя
+func (h *FavoritesHandler) StarHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.favoritesUsecase.Star(c.Request.Context(), userID, filename); err != nil {
+		if errors.Is(err, models.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "файл не найден",
+			})
+			return
+		}
+		log.Printf("ERROR: Failed to star file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка добавления в избранное",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnstarHandler убирает файл из избранного текущего пользователя
+func (h *FavoritesHandler) UnstarHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.favoritesUsecase.Unstar(c.Request.Context(), userID, filename); err != nil {
+		log.Printf("ERROR: Failed to unstar file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка удаления из избранного",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// StarredHandler возвращает избранные файлы: ?page=1&page_size=20
+func (h *FavoritesHandler) StarredHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	userID, _ := GetUserID(c)
+	result, err := h.favoritesUsecase.ListStarred(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		log.Printf("ERROR: Failed to list starred files: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения избранных файлов",
+		})
+		return
+	}
+
+	files := make([]starredFileInfo, 0, len(result.Files))
+	for _, f := range result.Files {
+		files = append(files, starredFileInfo{
+			fileInfo:  newFileInfo(f.File),
+			StarredAt: f.StarredAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":     files,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
+
+// RecentHandler возвращает недавние файлы: ?page=1&page_size=20
+func (h *FavoritesHandler) RecentHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	userID, _ := GetUserID(c)
+	result, err := h.favoritesUsecase.ListRecent(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		log.Printf("ERROR: Failed to list recent files: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения недавних файлов",
+		})
+		return
+	}
+
+	files := make([]recentFileInfo, 0, len(result.Files))
+	for _, f := range result.Files {
+		files = append(files, recentFileInfo{
+			fileInfo:   newFileInfo(f.File),
+			Action:     f.Action,
+			AccessedAt: f.AccessedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":     files,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"errors"
 	"io"
 	"log"
 	"net/http"
 
+	"tages/internal/models"
 	"tages/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +22,42 @@ func NewFileHandler(fileUsecase *usecase.Usecase) *FileHandler {
 	}
 }
 
+// fileInfo описание файла в ответах API
+type fileInfo struct {
+	Name       string            `json:"name"`
+	CreatedAt  string            `json:"created_at"`
+	UpdatedAt  string            `json:"updated_at"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+	ETag       string            `json:"etag"`
+	Size       int64             `json:"size"`
+	MimeType   string            `json:"mime_type,omitempty"`
+	OwnerID    uint              `json:"owner_id,omitempty"`
+	Lock       *models.FileLock  `json:"lock,omitempty"`
+}
+
+func newFileInfo(file *models.FileMeta) fileInfo {
+	info := fileInfo{
+		Name:       file.Name,
+		CreatedAt:  file.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:  file.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Tags:       file.Tags,
+		Attributes: file.Attributes,
+		ETag:       file.ETag,
+		Size:       file.Size,
+		MimeType:   file.MimeType,
+		OwnerID:    file.OwnerID,
+		Lock:       file.Lock,
+	}
+	if info.Tags == nil {
+		info.Tags = []string{}
+	}
+	if info.Attributes == nil {
+		info.Attributes = map[string]string{}
+	}
+	return info
+}
+
 // UploadHandler обрабатывает загрузку файлов
 func (h *FileHandler) UploadHandler(c *gin.Context) {
 	// Получаем файл из формы
@@ -35,6 +73,15 @@ func (h *FileHandler) UploadHandler(c *gin.Context) {
 	// Получаем имя файла
 	filename := header.Filename
 
+	// Получаем теги и атрибуты
+	tags, attributes, err := parseUploadMetadata(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные метаданные: " + err.Error(),
+		})
+		return
+	}
+
 	// Получаем содержимое файла
 	data, err := io.ReadAll(file)
 	if err != nil {
@@ -45,9 +92,34 @@ func (h *FileHandler) UploadHandler(c *gin.Context) {
 	}
 
 	// Сохраняем файл
-	err = h.fileUsecase.Upload(c.Request.Context(), filename, data)
+	meta, err := h.fileUsecase.Upload(c.Request.Context(), filename, data, usecase.UploadOptions{
+		Preconditions: parsePreconditions(c),
+		Tags:          tags,
+		Attributes:    attributes,
+	})
 	if err != nil {
 		log.Printf("ERROR: Failed to upload file: %v", err)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMetadata):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверные метаданные: " + err.Error(),
+			})
+			return
+		case errors.Is(err, usecase.ErrInvalidFileName):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "недопустимое имя файла",
+			})
+			return
+		case errors.Is(err, models.ErrFileLocked):
+			writeFileLocked(c)
+			return
+		case errors.Is(err, models.ErrFileProtected):
+			writeFileProtected(c)
+			return
+		case errors.Is(err, models.ErrPreconditionFailed):
+			writePreconditionFailed(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "ошибка загрузки файла: " + err.Error(),
 		})
@@ -55,10 +127,12 @@ func (h *FileHandler) UploadHandler(c *gin.Context) {
 	}
 
 	// Формируем успешный ответ
+	c.Header("ETag", formatETag(meta.ETag))
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  "файл успешно загружен",
 		"filename": filename,
+		"etag":     meta.ETag,
 	})
 }
 
@@ -74,7 +148,7 @@ func (h *FileHandler) DownloadHandler(c *gin.Context) {
 	}
 
 	// Открываем файл для чтения
-	fileReader, err := h.fileUsecase.Download(filename)
+	fileReader, err := h.fileUsecase.Download(c.Request.Context(), filename)
 	if err != nil {
 		log.Printf("ERROR: Failed to download file: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{
@@ -84,6 +158,11 @@ func (h *FileHandler) DownloadHandler(c *gin.Context) {
 	}
 	defer fileReader.Close()
 
+	// Версия файла для последующих условных запросов
+	if meta, err := h.fileUsecase.GetFile(c.Request.Context(), filename); err == nil {
+		c.Header("ETag", formatETag(meta.ETag))
+	}
+
 	// Устанавливаем заголовки для скачивания
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 	c.Header("Content-Type", "application/octet-stream")
@@ -101,7 +180,7 @@ func (h *FileHandler) DownloadHandler(c *gin.Context) {
 
 // ListHandler отображает список файлов
 func (h *FileHandler) ListHandler(c *gin.Context) {
-	files, err := h.fileUsecase.ListFiles(c.Request.Context())
+	files, err := h.fileUsecase.ListFiles(c.Request.Context(), parseFileFilter(c))
 	if err != nil {
 		log.Printf("ERROR: Failed to list files: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -111,19 +190,9 @@ func (h *FileHandler) ListHandler(c *gin.Context) {
 	}
 
 	// Формируем ответ
-	type fileInfo struct {
-		Name      string `json:"name"`
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
-	}
-
 	response := make([]fileInfo, 0, len(files))
 	for _, file := range files {
-		response = append(response, fileInfo{
-			Name:      file.Name,
-			CreatedAt: file.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt: file.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		})
+		response = append(response, newFileInfo(file))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -142,9 +211,20 @@ func (h *FileHandler) DeleteHandler(c *gin.Context) {
 		return
 	}
 
-	err := h.fileUsecase.DeleteFile(c.Request.Context(), filename)
+	err := h.fileUsecase.DeleteFile(c.Request.Context(), filename, parsePreconditions(c))
 	if err != nil {
 		log.Printf("ERROR: Failed to delete file: %v", err)
+		switch {
+		case errors.Is(err, models.ErrFileLocked):
+			writeFileLocked(c)
+			return
+		case errors.Is(err, models.ErrFileProtected):
+			writeFileProtected(c)
+			return
+		case errors.Is(err, models.ErrPreconditionFailed):
+			writePreconditionFailed(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "ошибка удаления файла",
 		})
@@ -156,3 +236,64 @@ func (h *FileHandler) DeleteHandler(c *gin.Context) {
 		"message": "файл успешно удален",
 	})
 }
+
+// MoveRequest структура для переименования файла
+type MoveRequest struct {
+	NewName string `json:"new_name" binding:"required"`
+}
+
+// MoveHandler переименовывает файл
+func (h *FileHandler) MoveHandler(c *gin.Context) {
+	filename := c.Param("filename")
+
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	err := h.fileUsecase.Move(c.Request.Context(), filename, req.NewName, parsePreconditions(c))
+	if err != nil {
+		log.Printf("ERROR: Failed to move file: %v", err)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidFileName):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "недопустимое имя файла",
+			})
+		case errors.Is(err, models.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "файл не найден",
+			})
+		case errors.Is(err, models.ErrFileExists):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "файл с таким именем уже существует",
+			})
+		case errors.Is(err, models.ErrFileLocked):
+			writeFileLocked(c)
+		case errors.Is(err, models.ErrFileProtected):
+			writeFileProtected(c)
+		case errors.Is(err, models.ErrPreconditionFailed):
+			writePreconditionFailed(c)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка переименования файла",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "файл успешно переименован",
+		"filename": req.NewName,
+	})
+}
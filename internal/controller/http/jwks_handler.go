@@ -0,0 +1,22 @@
+package http
+
+import (
+	"net/http"
+
+	"tages/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Время кэширования набора ключей клиентами, в секундах
+const jwksMaxAge = "300"
+
+// JWKSHandler публикует открытые ключи подписи токенов (RFC 7517)
+func JWKSHandler(tokenManager *auth.TokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age="+jwksMaxAge)
+		c.JSON(http.StatusOK, gin.H{
+			"keys": tokenManager.JWKS(),
+		})
+	}
+}
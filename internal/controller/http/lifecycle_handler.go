@@ -0,0 +1,248 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LifecycleHandler struct {
+	lifecycleUsecase *usecase.LifecycleUsecase
+}
+
+func NewLifecycleHandler(lifecycleUsecase *usecase.LifecycleUsecase) *LifecycleHandler {
+	return &LifecycleHandler{
+		lifecycleUsecase: lifecycleUsecase,
+	}
+}
+
+// LifecycleRuleRequest структура для создания, изменения и предпросмотра правила
+type LifecycleRuleRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Prefix    string `json:"prefix"`
+	Tag       string `json:"tag"`
+	Condition string `json:"condition" binding:"required"`
+	AfterDays int    `json:"after_days" binding:"required"`
+	Action    string `json:"action" binding:"required"`
+	// По умолчанию правило включено
+	Enabled *bool `json:"enabled"`
+}
+
+func (r *LifecycleRuleRequest) rule() *models.LifecycleRule {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &models.LifecycleRule{
+		Name:      r.Name,
+		Prefix:    r.Prefix,
+		Tag:       r.Tag,
+		Condition: r.Condition,
+		AfterDays: r.AfterDays,
+		Action:    r.Action,
+		Enabled:   enabled,
+	}
+}
+
+// ListHandler возвращает правила пользователя (администратору — все правила)
+func (h *LifecycleHandler) ListHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+	rules, err := h.lifecycleUsecase.Rules(c.Request.Context(), userID)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка получения правил")
+		return
+	}
+
+	if rules == nil {
+		rules = []*models.LifecycleRule{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+	})
+}
+
+// CreateHandler создает правило жизненного цикла
+func (h *LifecycleHandler) CreateHandler(c *gin.Context) {
+	var req LifecycleRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	rule, err := h.lifecycleUsecase.CreateRule(c.Request.Context(), userID, req.rule())
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка создания правила")
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetHandler возвращает правило
+func (h *LifecycleHandler) GetHandler(c *gin.Context) {
+	id, ok := parseLifecycleRuleID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	rule, err := h.lifecycleUsecase.Rule(c.Request.Context(), userID, id)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка получения правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateHandler заменяет условия и действие правила
+func (h *LifecycleHandler) UpdateHandler(c *gin.Context) {
+	id, ok := parseLifecycleRuleID(c)
+	if !ok {
+		return
+	}
+
+	var req LifecycleRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	rule, err := h.lifecycleUsecase.UpdateRule(c.Request.Context(), userID, id, req.rule())
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка изменения правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteHandler удаляет правило вместе с историей
+func (h *LifecycleHandler) DeleteHandler(c *gin.Context) {
+	id, ok := parseLifecycleRuleID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.lifecycleUsecase.DeleteRule(c.Request.Context(), userID, id); err != nil {
+		writeLifecycleError(c, err, "ошибка удаления правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "правило удалено",
+	})
+}
+
+// DryRunRuleHandler показывает, какие файлы затронет сохраненное правило: ?limit=100
+func (h *LifecycleHandler) DryRunRuleHandler(c *gin.Context) {
+	id, ok := parseLifecycleRuleID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	userID, _ := GetUserID(c)
+	rule, err := h.lifecycleUsecase.Rule(c.Request.Context(), userID, id)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка предпросмотра правила")
+		return
+	}
+
+	result, err := h.lifecycleUsecase.DryRun(c.Request.Context(), rule, limit)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка предпросмотра правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DryRunHandler показывает, какие файлы затронет правило из тела запроса: ?limit=100
+func (h *LifecycleHandler) DryRunHandler(c *gin.Context) {
+	var req LifecycleRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	// Несохраненное правило отбирает файлы с правами текущего пользователя
+	rule := req.rule()
+	rule.OwnerID, _ = GetUserID(c)
+	result, err := h.lifecycleUsecase.DryRun(c.Request.Context(), rule, limit)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка предпросмотра правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ActionsHandler возвращает историю применения правила: ?page=1&page_size=20
+func (h *LifecycleHandler) ActionsHandler(c *gin.Context) {
+	id, ok := parseLifecycleRuleID(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	userID, _ := GetUserID(c)
+	result, err := h.lifecycleUsecase.Actions(c.Request.Context(), userID, id, page, pageSize)
+	if err != nil {
+		writeLifecycleError(c, err, "ошибка получения истории правила")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func parseLifecycleRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор правила",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeLifecycleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidLifecycleRule):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверное правило: " + err.Error(),
+		})
+	case errors.Is(err, models.ErrLifecycleRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "правило не найдено",
+		})
+	case errors.Is(err, usecase.ErrLifecycleRuleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "недостаточно прав для работы с правилом",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
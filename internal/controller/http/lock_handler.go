@@ -0,0 +1,176 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовок с токеном блокировки для изменения заблокированного файла
+const lockTokenHeader = "Lock-Token"
+
+type LockHandler struct {
+	lockUsecase *usecase.LockUsecase
+}
+
+func NewLockHandler(lockUsecase *usecase.LockUsecase) *LockHandler {
+	return &LockHandler{
+		lockUsecase: lockUsecase,
+	}
+}
+
+// LockRequest структура для блокировки и продления блокировки файла
+type LockRequest struct {
+	// Время жизни блокировки в секундах; 0 — значение по умолчанию
+	TTL int `json:"ttl"`
+}
+
+// LockHandler блокирует файл и возвращает токен блокировки
+func (h *LockHandler) LockHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req LockRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	lock, err := h.lockUsecase.Lock(c.Request.Context(), userID, filename, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		if errors.Is(err, models.ErrFileLocked) && lock != nil {
+			c.JSON(http.StatusLocked, gin.H{
+				"error": "файл заблокирован другим пользователем",
+				"lock":  lock,
+			})
+			return
+		}
+		writeLockError(c, err, "ошибка блокировки файла")
+		return
+	}
+
+	c.JSON(http.StatusCreated, lock)
+}
+
+// RefreshHandler продлевает блокировку по токену из заголовка Lock-Token
+func (h *LockHandler) RefreshHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	token := c.GetHeader(lockTokenHeader)
+	if filename == "" || token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "не указано имя файла или токен блокировки",
+		})
+		return
+	}
+
+	var req LockRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		return
+	}
+
+	lock, err := h.lockUsecase.Refresh(c.Request.Context(), filename, token, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		writeLockError(c, err, "ошибка продления блокировки")
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// UnlockHandler снимает блокировку по токену из заголовка Lock-Token
+func (h *LockHandler) UnlockHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	token := c.GetHeader(lockTokenHeader)
+	if filename == "" || token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "не указано имя файла или токен блокировки",
+		})
+		return
+	}
+
+	if err := h.lockUsecase.Unlock(c.Request.Context(), filename, token); err != nil {
+		writeLockError(c, err, "ошибка снятия блокировки")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "блокировка снята",
+	})
+}
+
+// BreakHandler принудительно снимает блокировку (администратор)
+func (h *LockHandler) BreakHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	lock, err := h.lockUsecase.Break(c.Request.Context(), filename)
+	if err != nil {
+		writeLockError(c, err, "ошибка снятия блокировки")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "блокировка снята принудительно",
+		"lock":    lock,
+	})
+}
+
+func writeFileLocked(c *gin.Context) {
+	c.JSON(http.StatusLocked, gin.H{
+		"error": "файл заблокирован, укажите токен блокировки в заголовке " + lockTokenHeader,
+	})
+}
+
+// Тело запроса необязательно; при ошибке ответ уже отправлен
+func bindOptionalJSON(c *gin.Context, dst any) error {
+	if c.Request.ContentLength == 0 {
+		return nil
+	}
+	if err := c.ShouldBindJSON(dst); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+func writeLockError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "файл не найден",
+		})
+	case errors.Is(err, models.ErrLockNotFound):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "файл не заблокирован или блокировка истекла",
+		})
+	case errors.Is(err, models.ErrFileLocked):
+		c.JSON(http.StatusLocked, gin.H{
+			"error": "неверный токен блокировки",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
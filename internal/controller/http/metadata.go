@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tagsHeader      = "X-File-Tags"
+	attributeHeader = "X-File-Attribute"
+	attributePrefix = "attr."
+)
+
+// UpdateMetadataRequest структура для изменения тегов и атрибутов файла
+type UpdateMetadataRequest struct {
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// UpdateMetadataHandler заменяет теги и атрибуты файла
+func (h *FileHandler) UpdateMetadataHandler(c *gin.Context) {
+	filename := c.Param("filename")
+
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req UpdateMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	meta, err := h.fileUsecase.UpdateMetadata(c.Request.Context(), filename, req.Tags, req.Attributes, parsePreconditions(c))
+	if err != nil {
+		log.Printf("ERROR: Failed to update metadata: %v", err)
+		switch {
+		case errors.Is(err, models.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "файл не найден",
+			})
+		case errors.Is(err, usecase.ErrInvalidMetadata):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверные метаданные: " + err.Error(),
+			})
+		case errors.Is(err, models.ErrPreconditionFailed):
+			writePreconditionFailed(c)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка обновления метаданных",
+			})
+		}
+		return
+	}
+
+	c.Header("ETag", formatETag(meta.ETag))
+	c.JSON(http.StatusOK, newFileInfo(meta))
+}
+
+// Получение тегов и атрибутов из формы загрузки и заголовков.
+// Теги: поле формы "tags" (можно повторять, через запятую) и заголовок X-File-Tags.
+// Атрибуты: поле формы "attributes" (JSON-объект) и заголовки X-File-Attribute: ключ=значение
+func parseUploadMetadata(c *gin.Context) ([]string, map[string]string, error) {
+	var tags []string
+	for _, value := range c.PostFormArray("tags") {
+		tags = append(tags, splitList(value)...)
+	}
+	for _, value := range c.Request.Header.Values(tagsHeader) {
+		tags = append(tags, splitList(value)...)
+	}
+
+	var attributes map[string]string
+	if raw := c.PostForm("attributes"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &attributes); err != nil {
+			return nil, nil, fmt.Errorf("%w: attributes must be a JSON object of strings", usecase.ErrInvalidMetadata)
+		}
+	}
+	for _, value := range c.Request.Header.Values(attributeHeader) {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s header must be key=value", usecase.ErrInvalidMetadata, attributeHeader)
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return tags, attributes, nil
+}
+
+// Получение фильтра списка файлов из query: ?tag=a&tag=b&attr.project=x
+func parseFileFilter(c *gin.Context) *models.FileFilter {
+	filter := &models.FileFilter{}
+	for key, values := range c.Request.URL.Query() {
+		switch {
+		case key == "tag":
+			for _, value := range values {
+				filter.Tags = append(filter.Tags, splitList(value)...)
+			}
+		case strings.HasPrefix(key, attributePrefix) && len(values) > 0:
+			if filter.Attributes == nil {
+				filter.Attributes = make(map[string]string)
+			}
+			filter.Attributes[strings.TrimPrefix(key, attributePrefix)] = values[0]
+		}
+	}
+
+	if filter.IsEmpty() {
+		return nil
+	}
+	return filter
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFARequiredResponse ответ на вход с паролем, если нужен код подтверждения
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"` // в секундах
+}
+
+// LoginMFARequest структура для второго шага входа
+type LoginMFARequest struct {
+	MFAToken   string `json:"mfa_token" binding:"required"`
+	Code       string `json:"code" binding:"required,max=32"`
+	DeviceName string `json:"device_name" binding:"max=128"`
+}
+
+// EnrollTOTPRequest структура для подключения приложения-аутентификатора
+type EnrollTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ConfirmTOTPRequest структура для подтверждения подключения кодом из приложения
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// MFAReauthRequest структура для отключения двухфакторной аутентификации
+// и замены кодов восстановления: пароль и код TOTP или код восстановления
+type MFAReauthRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+// RecoveryCodesResponse коды восстановления. Показываются только здесь
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginMFAHandler второй шаг входа: обмен токена и кода на пару токенов
+func (h *AuthHandler) LoginMFAHandler(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	user, tokens, err := h.userUsecase.LoginMFA(c.Request.Context(), req.MFAToken, req.Code, req.DeviceName)
+	if err != nil {
+		writeMFAError(c, err, "ошибка авторизации")
+		return
+	}
+
+	// Устанавливаем refresh token в httpOnly cookie
+	setRefreshTokenCookie(c, tokens.RefreshToken)
+
+	c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: tokens.AccessToken,
+		User: UserInfo{
+			ID:            user.ID,
+			Email:         user.Email,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+		},
+	})
+}
+
+// MFAStatusHandler возвращает состояние двухфакторной аутентификации
+func (h *AuthHandler) MFAStatusHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	status, err := h.userUsecase.MFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		writeMFAError(c, err, "ошибка получения состояния двухфакторной аутентификации")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTOTPHandler создает секрет TOTP и ссылку otpauth:// для приложения
+func (h *AuthHandler) EnrollTOTPHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req EnrollTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := h.userUsecase.EnrollTOTP(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		writeMFAError(c, err, "ошибка подключения двухфакторной аутентификации")
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTPHandler включает двухфакторную аутентификацию и возвращает коды восстановления
+func (h *AuthHandler) ConfirmTOTPHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.userUsecase.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(c, err, "ошибка включения двухфакторной аутентификации")
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableMFAHandler отключает двухфакторную аутентификацию
+func (h *AuthHandler) DisableMFAHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req MFAReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userUsecase.DisableMFA(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		writeMFAError(c, err, "ошибка отключения двухфакторной аутентификации")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "двухфакторная аутентификация отключена",
+	})
+}
+
+// RegenerateRecoveryCodesHandler заменяет коды восстановления новыми
+func (h *AuthHandler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req MFAReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.userUsecase.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Password, req.Code)
+	if err != nil {
+		writeMFAError(c, err, "ошибка создания кодов восстановления")
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+func writeMFAError(c *gin.Context, err error, message string) {
+	if writeLockoutError(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidMFAToken):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "токен подтверждения входа недействителен или истек",
+		})
+	case errors.Is(err, usecase.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "неверный код подтверждения",
+		})
+	case errors.Is(err, usecase.ErrInvalidPassword):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "неверный пароль",
+		})
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "двухфакторная аутентификация уже включена",
+		})
+	case errors.Is(err, usecase.ErrMFANotEnabled):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "двухфакторная аутентификация не включена",
+		})
+	case errors.Is(err, usecase.ErrMFANotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "сначала получите секрет для приложения-аутентификатора",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
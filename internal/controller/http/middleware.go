@@ -1,78 +1,251 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"tages/internal/auth"
+	"tages/internal/models"
+	"tages/internal/usecase"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware middleware для проверки JWT токена
+// AuthMiddleware middleware для проверки JWT токена и персональных токенов доступа.
+// Определяет роль пользователя и проверяет ее разрешения
 type AuthMiddleware struct {
 	tokenManager *auth.TokenManager
+	users        *usecase.UserUsecase
+	apiTokens    *usecase.APITokenUsecase // nil — персональные токены не поддерживаются
 }
 
 // NewAuthMiddleware создает новое middleware для авторизации
-func NewAuthMiddleware(tokenManager *auth.TokenManager) *AuthMiddleware {
+func NewAuthMiddleware(tokenManager *auth.TokenManager, users *usecase.UserUsecase) *AuthMiddleware {
 	return &AuthMiddleware{
 		tokenManager: tokenManager,
+		users:        users,
 	}
 }
 
-// Middleware проверяет JWT токен в заголовке Authorization
+// SetAPITokens подключает проверку персональных токенов доступа
+func (m *AuthMiddleware) SetAPITokens(apiTokens *usecase.APITokenUsecase) {
+	m.apiTokens = apiTokens
+}
+
+// Middleware проверяет JWT токен в заголовке Authorization.
+// Персональные токены на таких маршрутах не принимаются
 func (m *AuthMiddleware) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var token string
-
-		// 1. Попытка взять из заголовка Authorization
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				token = parts[1]
-			}
-		}
+		m.authenticate(c, "")
+	}
+}
 
-		// 2. Если не найден — пробуем взять из cookie
-		if token == "" {
-			cookie, err := c.Cookie("access_token")
-			if err == nil {
-				token = cookie
-			}
-		}
+// RequirePermission пропускает запросы пользователей, роль которых дает
+// разрешение permission. Принимаются JWT и персональные токены с такой же
+// областью действия
+func (m *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.authenticate(c, permission)
+	}
+}
 
-		// 3. Если токена всё ещё нет — 401
-		if token == "" {
+func (m *AuthMiddleware) authenticate(c *gin.Context, permission string) {
+	// 1-2. Берем токен из заголовка Authorization или из cookie
+	token := getAccessToken(c)
+
+	// 3. Если токена всё ещё нет — 401
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "токен авторизации не найден",
+		})
+		return
+	}
+
+	// 4. Проверяем токен: персональный отдельно от JWT
+	var (
+		userID uint
+		role   string
+		ok     bool
+	)
+	if usecase.IsAPIToken(token) {
+		userID, ok = m.authenticateAPIToken(c, token, permission)
+	} else {
+		userID, role, ok = m.authenticateJWT(c, token)
+	}
+	if !ok {
+		return
+	}
+
+	// 5. Роль берется из токена; для персональных токенов и токенов,
+	// выпущенных до появления ролей, — из профиля пользователя
+	if role == "" {
+		var err error
+		if role, err = m.users.UserRole(c.Request.Context(), userID); err != nil {
+			log.Printf("ERROR: Failed to get role of user %d: %v", userID, err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "токен авторизации не найден",
+				"error": "пользователь не найден",
+			})
+			return
+		}
+	}
+
+	// 6. Проверяем разрешение роли
+	if permission != "" {
+		allowed, err := m.users.RoleHasPermission(c.Request.Context(), role, permission)
+		if err != nil {
+			log.Printf("ERROR: Failed to check permissions: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка проверки прав доступа",
+			})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "недостаточно прав",
 			})
 			return
 		}
 
-		// 4. Парсим токен
-		claims, err := m.tokenManager.ParseAccessToken(token)
+		// Пока email не подтвержден, доступ может быть ограничен
+		allowed, err = m.users.EmailVerificationAllows(c.Request.Context(), userID, permission)
 		if err != nil {
-			if err == auth.ErrExpiredToken {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error": "срок действия токена истек",
-				})
-			} else {
-				log.Printf("ERROR: Invalid token: %v", err)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error": "неверный токен",
-				})
-			}
+			log.Printf("ERROR: Failed to check email verification: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка проверки прав доступа",
+			})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "подтвердите email, чтобы выполнить этот запрос",
+			})
 			return
 		}
+	}
+
+	// 7. Добавляем ID и роль в контекст
+	c.Set("userID", userID)
+	c.Set("role", role)
+	c.Request = c.Request.WithContext(usecase.WithActor(c.Request.Context(), userID))
+	c.Next()
+}
+
+func (m *AuthMiddleware) authenticateJWT(c *gin.Context, token string) (uint, string, bool) {
+	// Парсим токен и проверяем, не отозван ли он
+	claims, err := m.tokenManager.ParseAccessToken(c.Request.Context(), token)
+	if err != nil {
+		if err == auth.ErrExpiredToken {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "срок действия токена истек",
+			})
+		} else if err == auth.ErrRevokedToken {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "токен отозван",
+			})
+		} else {
+			log.Printf("ERROR: Invalid token: %v", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "неверный токен",
+			})
+		}
+		return 0, "", false
+	}
+	return claims.UserID, claims.Role, true
+}
+
+func (m *AuthMiddleware) authenticateAPIToken(c *gin.Context, token, permission string) (uint, bool) {
+	if m.apiTokens == nil || permission == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "персональный токен не принимается для этого запроса",
+		})
+		return 0, false
+	}
+
+	// Адрес из RequestMetaMiddleware: X-Forwarded-For учтен, только если запрос
+	// пришел от доверенного прокси, поэтому список разрешенных адресов не обойти
+	// подставленным заголовком
+	ctx := c.Request.Context()
+	apiToken, err := m.apiTokens.Authenticate(ctx, token, usecase.RequestMetaFromContext(ctx).IP)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrAPITokenUnauthorized):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "неверный или просроченный токен",
+			})
+		case errors.Is(err, usecase.ErrAPITokenIPDenied):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "токен не разрешено использовать с этого адреса",
+			})
+		default:
+			log.Printf("ERROR: Failed to check api token: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "ошибка проверки токена",
+			})
+		}
+		return 0, false
+	}
+
+	if !apiToken.HasScope(permission) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "у токена нет области действия " + permission,
+		})
+		return 0, false
+	}
+	return apiToken.UserID, true
+}
+
+// Получение access token из заголовка Authorization или из cookie
+func getAccessToken(c *gin.Context) string {
+	// 1. Попытка взять из заголовка Authorization
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
+		}
+	}
+
+	// 2. Если не найден — пробуем взять из cookie
+	if cookie, err := c.Cookie("access_token"); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestMetaMiddleware добавляет в контекст запроса IP, User-Agent и ID запроса.
+// ID берется из заголовка X-Request-ID или генерируется и возвращается в ответе
+func RequestMetaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
 
-		// 5. Добавляем ID в контекст
-		c.Set("userID", claims.UserID)
+		ctx := usecase.WithRequestMeta(c.Request.Context(), models.RequestMeta{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestID,
+		})
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
 // GetUserID извлекает ID пользователя из контекста
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("userID")
@@ -83,3 +256,8 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	id, ok := userID.(uint)
 	return id, ok
 }
+
+// GetUserRole извлекает роль пользователя из контекста
+func GetUserRole(c *gin.Context) string {
+	return c.GetString("role")
+}
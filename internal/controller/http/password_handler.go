@@ -0,0 +1,119 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPasswordRequest структура для запроса сброса пароля
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest структура для сброса пароля по токену из письма
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// ChangePasswordRequest структура для смены пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ForgotPasswordHandler отправляет ссылку для сброса пароля. Ответ одинаковый
+// для зарегистрированных и незарегистрированных email
+func (h *AuthHandler) ForgotPasswordHandler(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userUsecase.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		log.Printf("ERROR: Failed to request password reset: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка запроса сброса пароля",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "если email зарегистрирован, на него отправлена ссылка для сброса пароля",
+	})
+}
+
+// ResetPasswordHandler задает новый пароль по токену из письма
+func (h *AuthHandler) ResetPasswordHandler(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userUsecase.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		log.Printf("ERROR: Failed to reset password: %v", err)
+		if errors.Is(err, usecase.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "ссылка для сброса пароля недействительна или истекла",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка сброса пароля",
+		})
+		return
+	}
+
+	deleteRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "пароль изменен, войдите с новым паролем",
+	})
+}
+
+// ChangePasswordHandler меняет пароль текущего пользователя и завершает все его сессии
+func (h *AuthHandler) ChangePasswordHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userUsecase.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		log.Printf("ERROR: Failed to change password: %v", err)
+		if errors.Is(err, usecase.ErrInvalidPassword) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "неверный текущий пароль",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка смены пароля",
+		})
+		return
+	}
+
+	deleteRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "пароль изменен, войдите с новым паролем",
+	})
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Условия изменения файла из заголовков запроса: Lock-Token, If-Match и If-None-Match
+func parsePreconditions(c *gin.Context) usecase.Preconditions {
+	return usecase.Preconditions{
+		LockToken:   c.GetHeader(lockTokenHeader),
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+}
+
+func formatETag(etag string) string {
+	return `"` + etag + `"`
+}
+
+func writePreconditionFailed(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": "файл изменен или уже существует, условие запроса не выполнено",
+	})
+}
@@ -0,0 +1,261 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	retentionUsecase *usecase.RetentionUsecase
+}
+
+func NewRetentionHandler(retentionUsecase *usecase.RetentionUsecase) *RetentionHandler {
+	return &RetentionHandler{
+		retentionUsecase: retentionUsecase,
+	}
+}
+
+// RetentionRequest структура для установки срока хранения файла
+type RetentionRequest struct {
+	RetainUntil time.Time `json:"retain_until" binding:"required"`
+	Mode        string    `json:"mode" binding:"required"`
+}
+
+// FolderRetentionRequest структура для установки срока хранения по префиксу имени
+type FolderRetentionRequest struct {
+	Prefix      string    `json:"prefix" binding:"required"`
+	RetainUntil time.Time `json:"retain_until" binding:"required"`
+	Mode        string    `json:"mode" binding:"required"`
+}
+
+// LegalHoldRequest структура для постановки файла на юридическое удержание
+type LegalHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ProtectionHandler возвращает действующие защиты файла
+func (h *RetentionHandler) ProtectionHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	protection, err := h.retentionUsecase.Protection(c.Request.Context(), filename)
+	if err != nil {
+		writeRetentionError(c, err, "ошибка получения защиты файла")
+		return
+	}
+
+	if protection.FolderRetentions == nil {
+		protection.FolderRetentions = []*models.Retention{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"protected":  protection.Protected(),
+		"protection": protection,
+	})
+}
+
+// SetRetentionHandler устанавливает или продлевает срок хранения файла
+func (h *RetentionHandler) SetRetentionHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req RetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	retention, err := h.retentionUsecase.SetFileRetention(c.Request.Context(), userID, filename, req.RetainUntil, req.Mode)
+	if err != nil {
+		writeRetentionError(c, err, "ошибка установки срока хранения")
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// ClearRetentionHandler снимает срок хранения файла
+func (h *RetentionHandler) ClearRetentionHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.retentionUsecase.ClearFileRetention(c.Request.Context(), userID, filename); err != nil {
+		writeRetentionError(c, err, "ошибка снятия срока хранения")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "срок хранения снят",
+	})
+}
+
+// ListFolderRetentionsHandler возвращает действующие сроки хранения префиксов (администратор)
+func (h *RetentionHandler) ListFolderRetentionsHandler(c *gin.Context) {
+	retentions, err := h.retentionUsecase.FolderRetentions(c.Request.Context())
+	if err != nil {
+		writeRetentionError(c, err, "ошибка получения сроков хранения")
+		return
+	}
+
+	if retentions == nil {
+		retentions = []*models.Retention{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"retentions": retentions,
+	})
+}
+
+// SetFolderRetentionHandler устанавливает срок хранения для префикса имен (администратор)
+func (h *RetentionHandler) SetFolderRetentionHandler(c *gin.Context) {
+	var req FolderRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	retention, err := h.retentionUsecase.SetFolderRetention(c.Request.Context(), userID, req.Prefix, req.RetainUntil, req.Mode)
+	if err != nil {
+		writeRetentionError(c, err, "ошибка установки срока хранения")
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// ClearFolderRetentionHandler снимает срок хранения префикса из параметра prefix (администратор)
+func (h *RetentionHandler) ClearFolderRetentionHandler(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "префикс не указан",
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.retentionUsecase.ClearFolderRetention(c.Request.Context(), userID, prefix); err != nil {
+		writeRetentionError(c, err, "ошибка снятия срока хранения")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "срок хранения снят",
+	})
+}
+
+// SetLegalHoldHandler ставит файл на юридическое удержание (администратор)
+func (h *RetentionHandler) SetLegalHoldHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	var req LegalHoldRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	hold, err := h.retentionUsecase.SetLegalHold(c.Request.Context(), userID, filename, req.Reason)
+	if err != nil {
+		writeRetentionError(c, err, "ошибка установки удержания")
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// ClearLegalHoldHandler снимает юридическое удержание (администратор)
+func (h *RetentionHandler) ClearLegalHoldHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	if err := h.retentionUsecase.ClearLegalHold(c.Request.Context(), userID, filename); err != nil {
+		writeRetentionError(c, err, "ошибка снятия удержания")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "удержание снято",
+	})
+}
+
+func writeFileProtected(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "файл защищен сроком хранения или юридическим удержанием",
+	})
+}
+
+func writeRetentionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRetention):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный срок хранения: " + err.Error(),
+		})
+	case errors.Is(err, usecase.ErrRetentionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "срок хранения файла задает его владелец, режим compliance — администратор",
+		})
+	case errors.Is(err, models.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "файл не найден",
+		})
+	case errors.Is(err, models.ErrRetentionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "срок хранения не установлен или истек",
+		})
+	case errors.Is(err, models.ErrLegalHoldNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "удержание не установлено",
+		})
+	case errors.Is(err, models.ErrRetentionConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "режим защиты не позволяет сократить или снять срок хранения",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	roleUsecase *usecase.RoleUsecase
+}
+
+func NewRoleHandler(roleUsecase *usecase.RoleUsecase) *RoleHandler {
+	return &RoleHandler{
+		roleUsecase: roleUsecase,
+	}
+}
+
+// RoleRequest структура для создания и изменения роли
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// ListHandler возвращает роли и список известных разрешений
+func (h *RoleHandler) ListHandler(c *gin.Context) {
+	roles, err := h.roleUsecase.List(c.Request.Context())
+	if err != nil {
+		writeRoleError(c, err, "ошибка получения списка ролей")
+		return
+	}
+	if roles == nil {
+		roles = []*models.Role{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles":       roles,
+		"permissions": models.Permissions,
+	})
+}
+
+// CreateHandler создает пользовательскую роль
+func (h *RoleHandler) CreateHandler(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := h.roleUsecase.Create(c.Request.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		writeRoleError(c, err, "ошибка создания роли")
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateHandler изменяет описание и разрешения пользовательской роли
+func (h *RoleHandler) UpdateHandler(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := h.roleUsecase.Update(c.Request.Context(), c.Param("name"), req.Description, req.Permissions)
+	if err != nil {
+		writeRoleError(c, err, "ошибка изменения роли")
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteHandler удаляет пользовательскую роль
+func (h *RoleHandler) DeleteHandler(c *gin.Context) {
+	if err := h.roleUsecase.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		writeRoleError(c, err, "ошибка удаления роли")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "роль удалена",
+	})
+}
+
+func writeRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные параметры роли: " + err.Error(),
+		})
+	case errors.Is(err, models.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "роль не найдена",
+		})
+	case errors.Is(err, models.ErrRoleExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "роль с таким именем уже существует",
+		})
+	case errors.Is(err, models.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "роль назначена пользователям",
+		})
+	case errors.Is(err, usecase.ErrBuiltinRole):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "встроенную роль нельзя изменить",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
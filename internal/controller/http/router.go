@@ -1,23 +1,63 @@
 package http
 
 import (
+	"fmt"
+
 	"tages/internal/auth"
+	"tages/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-contrib/cors"
 )
 
-// SetupRouter настраивает роутер для HTTP сервера
-func SetupRouter(fileHandler *FileHandler, authHandler *AuthHandler, tokenManager *auth.TokenManager) *gin.Engine {
+// Источники, с которых разрешены запросы из браузера
+var allowedOrigins = []string{"http://localhost:3000"}
+
+// Handlers набор HTTP обработчиков сервиса
+type Handlers struct {
+	File      *FileHandler
+	Auth      *AuthHandler
+	Search    *SearchHandler
+	Audit     *AuditHandler
+	Events    *EventsHandler
+	Webhook   *WebhookHandler
+	Cache     *CacheHandler
+	Favorites *FavoritesHandler
+	Comments  *CommentsHandler
+	Lock      *LockHandler
+	Retention *RetentionHandler
+	Lifecycle *LifecycleHandler
+	Trash     *TrashHandler
+	Usage     *UsageHandler
+	APITokens *APITokenHandler
+	Roles     *RoleHandler
+	Settings  *SettingsHandler
+}
+
+// SetupRouter настраивает роутер для HTTP сервера. Адрес клиента берется из
+// X-Forwarded-For только для запросов с адресов trustedProxies; пустой список —
+// заголовку не доверять и использовать адрес соединения
+func SetupRouter(h Handlers, tokenManager *auth.TokenManager, trustedProxies []string) (*gin.Engine, error) {
 	router := gin.Default()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(RequestMetaMiddleware())
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
+		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", tagsHeader, attributeHeader, requestIDHeader, lastEventIDHeader, lockTokenHeader, "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{requestIDHeader, "ETag"},
 		AllowCredentials: true,
 	}))
 	// Создаем middleware для авторизации
-	authMiddleware := NewAuthMiddleware(tokenManager)
+	authMiddleware := NewAuthMiddleware(tokenManager, h.Auth.userUsecase)
+	if h.APITokens != nil {
+		authMiddleware.SetAPITokens(h.APITokens.apiTokenUsecase)
+	}
+
+	// Открытые ключи для проверки токенов другими сервисами
+	router.GET("/.well-known/jwks.json", JWKSHandler(tokenManager))
 
 	// API группа
 	api := router.Group("/api")
@@ -25,21 +65,188 @@ func SetupRouter(fileHandler *FileHandler, authHandler *AuthHandler, tokenManage
 	// Маршруты для авторизации (публичные)
 	authRoutes := api.Group("/auth")
 	{
-		authRoutes.POST("/register", authHandler.RegisterHandler)
-		authRoutes.POST("/login", authHandler.LoginHandler)
-		authRoutes.POST("/refresh", authHandler.RefreshTokenHandler)
-		authRoutes.POST("/logout", authHandler.LogoutHandler)
+		authRoutes.POST("/register", h.Auth.RegisterHandler)
+		authRoutes.POST("/login", h.Auth.LoginHandler)
+		authRoutes.POST("/login/mfa", h.Auth.LoginMFAHandler)
+		authRoutes.POST("/refresh", h.Auth.RefreshTokenHandler)
+		authRoutes.POST("/logout", h.Auth.LogoutHandler)
+		authRoutes.POST("/password/forgot", h.Auth.ForgotPasswordHandler)
+		authRoutes.POST("/password/reset", h.Auth.ResetPasswordHandler)
+		authRoutes.GET("/verify-email", h.Auth.VerifyEmailHandler)
+		authRoutes.POST("/verify-email", h.Auth.VerifyEmailHandler)
+	}
+
+	// Сессии текущего пользователя (защищенные)
+	sessionRoutes := authRoutes.Group("/sessions")
+	sessionRoutes.Use(authMiddleware.Middleware())
+	{
+		sessionRoutes.GET("", h.Auth.SessionsHandler)
+		sessionRoutes.DELETE("", h.Auth.RevokeSessionsHandler)
+		sessionRoutes.DELETE("/:id", h.Auth.RevokeSessionHandler)
 	}
+	authRoutes.POST("/logout-all", authMiddleware.Middleware(), h.Auth.LogoutAllHandler)
+	authRoutes.GET("/me", authMiddleware.Middleware(), h.Auth.MeHandler)
+	authRoutes.POST("/password/change", authMiddleware.Middleware(), h.Auth.ChangePasswordHandler)
+	authRoutes.POST("/verify-email/resend", authMiddleware.Middleware(), h.Auth.ResendVerificationHandler)
 
-	// Маршруты для работы с файлами (защищенные)
+	// Двухфакторная аутентификация текущего пользователя
+	mfaRoutes := authRoutes.Group("/mfa")
+	mfaRoutes.Use(authMiddleware.Middleware())
+	{
+		mfaRoutes.GET("", h.Auth.MFAStatusHandler)
+		mfaRoutes.POST("/totp/enroll", h.Auth.EnrollTOTPHandler)
+		mfaRoutes.POST("/totp/confirm", h.Auth.ConfirmTOTPHandler)
+		mfaRoutes.POST("/disable", h.Auth.DisableMFAHandler)
+		mfaRoutes.POST("/recovery-codes", h.Auth.RegenerateRecoveryCodesHandler)
+	}
+
+	// Маршруты для работы с файлами (защищенные). Роль пользователя и область
+	// действия персонального токена должны давать разрешение на операцию
 	filesRoutes := api.Group("/files")
-	filesRoutes.Use(authMiddleware.Middleware())
+	filesRead := filesRoutes.Group("", authMiddleware.RequirePermission(models.PermissionFilesRead))
+	filesWrite := filesRoutes.Group("", authMiddleware.RequirePermission(models.PermissionFilesWrite))
+	filesDelete := filesRoutes.Group("", authMiddleware.RequirePermission(models.PermissionFilesDelete))
 	{
-		filesRoutes.POST("/upload", fileHandler.UploadHandler)
-		filesRoutes.GET("/list", fileHandler.ListHandler)
-		filesRoutes.GET("/download/:filename", fileHandler.DownloadHandler)
-		filesRoutes.DELETE("/delete/:filename", fileHandler.DeleteHandler)
+		filesWrite.POST("/upload", h.File.UploadHandler)
+		filesRead.GET("/list", h.File.ListHandler)
+		filesRead.GET("/download/:filename", h.File.DownloadHandler)
+		filesDelete.DELETE("/delete/:filename", h.File.DeleteHandler)
+		filesWrite.PUT("/meta/:filename", h.File.UpdateMetadataHandler)
+		filesWrite.POST("/move/:filename", h.File.MoveHandler)
+	}
+
+	// Маршруты администратора
+	adminRoutes := api.Group("/admin")
+	adminRoutes.Use(authMiddleware.RequirePermission(models.PermissionAdmin))
+	{
+		adminRoutes.GET("/users", h.Auth.ListUsersHandler)
+		adminRoutes.GET("/users/:id", h.Auth.GetUserHandler)
+		adminRoutes.PUT("/users/:id/role", h.Auth.SetUserRoleHandler)
+		adminRoutes.POST("/users/:id/logout", h.Auth.LogoutUserHandler)
+		adminRoutes.GET("/users/:id/lockout", h.Auth.AccountLockoutHandler)
+		adminRoutes.DELETE("/users/:id/lockout", h.Auth.UnlockAccountHandler)
+	}
+
+	// Возможности, доступные только с PostgreSQL, регистрируются при наличии обработчиков
+	if h.Search != nil {
+		filesRead.GET("/search", h.Search.SearchHandler)
+	}
+	if h.Favorites != nil {
+		filesWrite.PUT("/star/:filename", h.Favorites.StarHandler)
+		filesWrite.DELETE("/star/:filename", h.Favorites.UnstarHandler)
+		filesRead.GET("/starred", h.Favorites.StarredHandler)
+		filesRead.GET("/recent", h.Favorites.RecentHandler)
+	}
+	if h.Lock != nil {
+		filesWrite.POST("/lock/:filename", h.Lock.LockHandler)
+		filesWrite.PUT("/lock/:filename", h.Lock.RefreshHandler)
+		filesWrite.DELETE("/lock/:filename", h.Lock.UnlockHandler)
+		adminRoutes.DELETE("/locks/:filename", h.Lock.BreakHandler)
+	}
+	if h.Retention != nil {
+		filesRead.GET("/protection/:filename", h.Retention.ProtectionHandler)
+		filesWrite.PUT("/retention/:filename", h.Retention.SetRetentionHandler)
+		filesWrite.DELETE("/retention/:filename", h.Retention.ClearRetentionHandler)
+		adminRoutes.PUT("/legal-holds/:filename", h.Retention.SetLegalHoldHandler)
+		adminRoutes.DELETE("/legal-holds/:filename", h.Retention.ClearLegalHoldHandler)
+		adminRoutes.GET("/retention/folders", h.Retention.ListFolderRetentionsHandler)
+		adminRoutes.PUT("/retention/folders", h.Retention.SetFolderRetentionHandler)
+		adminRoutes.DELETE("/retention/folders", h.Retention.ClearFolderRetentionHandler)
+	}
+	if h.Comments != nil {
+		filesRead.GET("/comments/:filename", h.Comments.ListHandler)
+		filesWrite.POST("/comments/:filename", h.Comments.CreateHandler)
+
+		// Комментарии доступны тем же пользователям, что и файлы
+		commentRoutes := api.Group("/comments")
+		{
+			commentRoutes.GET("/mentions", authMiddleware.RequirePermission(models.PermissionFilesRead), h.Comments.MentionsHandler)
+			commentRoutes.PUT("/:id", authMiddleware.RequirePermission(models.PermissionFilesWrite), h.Comments.UpdateHandler)
+			commentRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermissionFilesWrite), h.Comments.DeleteHandler)
+			commentRoutes.POST("/:id/resolve", authMiddleware.RequirePermission(models.PermissionFilesWrite), h.Comments.ResolveHandler)
+			commentRoutes.DELETE("/:id/resolve", authMiddleware.RequirePermission(models.PermissionFilesWrite), h.Comments.ReopenHandler)
+		}
+	}
+	if h.Trash != nil {
+		filesDelete.POST("/trash/:filename", h.Trash.TrashHandler)
+
+		trashRoutes := api.Group("/trash")
+		{
+			trashRoutes.GET("", authMiddleware.RequirePermission(models.PermissionFilesRead), h.Trash.ListHandler)
+			trashRoutes.POST("/:id/restore", authMiddleware.RequirePermission(models.PermissionFilesWrite), h.Trash.RestoreHandler)
+			trashRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermissionFilesDelete), h.Trash.PurgeHandler)
+		}
+	}
+	if h.Lifecycle != nil {
+		// Правила удаляют файлы; правила для всех файлов дополнительно требуют прав администратора
+		lifecycleRoutes := api.Group("/lifecycle")
+		lifecycleRoutes.Use(authMiddleware.RequirePermission(models.PermissionFilesDelete))
+		{
+			lifecycleRoutes.GET("/rules", h.Lifecycle.ListHandler)
+			lifecycleRoutes.POST("/rules", h.Lifecycle.CreateHandler)
+			lifecycleRoutes.GET("/rules/:id", h.Lifecycle.GetHandler)
+			lifecycleRoutes.PUT("/rules/:id", h.Lifecycle.UpdateHandler)
+			lifecycleRoutes.DELETE("/rules/:id", h.Lifecycle.DeleteHandler)
+			lifecycleRoutes.POST("/rules/:id/dry-run", h.Lifecycle.DryRunRuleHandler)
+			lifecycleRoutes.GET("/rules/:id/actions", h.Lifecycle.ActionsHandler)
+			lifecycleRoutes.POST("/dry-run", h.Lifecycle.DryRunHandler)
+		}
+	}
+	if h.Usage != nil {
+		adminRoutes.GET("/usage/history", h.Usage.HistoryHandler)
+		adminRoutes.GET("/usage/largest", h.Usage.LargestFilesHandler)
+		adminRoutes.GET("/usage/users", h.Usage.UsersHandler)
+		adminRoutes.GET("/usage/types", h.Usage.TypesHandler)
+		adminRoutes.POST("/usage/snapshot", h.Usage.SnapshotHandler)
+	}
+	if h.Events != nil {
+		filesRead.GET("/events", h.Events.StreamHandler)
+		filesRead.GET("/events/ws", h.Events.WebSocketHandler)
+	}
+	if h.Audit != nil {
+		adminRoutes.GET("/audit", h.Audit.ListHandler)
+		adminRoutes.GET("/audit/export", h.Audit.ExportHandler)
+	}
+	if h.Cache != nil {
+		adminRoutes.GET("/cache/stats", h.Cache.StatsHandler)
+	}
+	if h.Webhook != nil {
+		// Маршруты для вебхуков пользователя (защищенные)
+		webhookRoutes := api.Group("/webhooks")
+		webhookRoutes.Use(authMiddleware.RequirePermission(models.PermissionFilesRead))
+		{
+			webhookRoutes.POST("", h.Webhook.CreateHandler)
+			webhookRoutes.GET("", h.Webhook.ListHandler)
+			webhookRoutes.DELETE("/:id", h.Webhook.DeleteHandler)
+			webhookRoutes.GET("/:id/deliveries", h.Webhook.DeliveriesHandler)
+		}
+
+		adminRoutes.POST("/webhooks", h.Webhook.AdminCreateHandler)
+		adminRoutes.GET("/webhooks", h.Webhook.AdminListHandler)
+		adminRoutes.DELETE("/webhooks/:id", h.Webhook.AdminDeleteHandler)
+		adminRoutes.GET("/webhooks/:id/deliveries", h.Webhook.AdminDeliveriesHandler)
+	}
+
+	if h.Roles != nil {
+		adminRoutes.GET("/roles", h.Roles.ListHandler)
+		adminRoutes.POST("/roles", h.Roles.CreateHandler)
+		adminRoutes.PUT("/roles/:name", h.Roles.UpdateHandler)
+		adminRoutes.DELETE("/roles/:name", h.Roles.DeleteHandler)
+	}
+	if h.Settings != nil {
+		adminRoutes.GET("/settings", h.Settings.GetHandler)
+		adminRoutes.PUT("/settings", h.Settings.UpdateHandler)
+	}
+	if h.APITokens != nil {
+		// Персональные токены управляются только из сессии пользователя
+		apiTokenRoutes := api.Group("/tokens")
+		apiTokenRoutes.Use(authMiddleware.Middleware())
+		{
+			apiTokenRoutes.POST("", h.APITokens.CreateHandler)
+			apiTokenRoutes.GET("", h.APITokens.ListHandler)
+			apiTokenRoutes.DELETE("/:id", h.APITokens.RevokeHandler)
+		}
 	}
 
-	return router
+	return router, nil
 }
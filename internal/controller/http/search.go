@@ -0,0 +1,68 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	searchUsecase *usecase.SearchUsecase
+}
+
+func NewSearchHandler(searchUsecase *usecase.SearchUsecase) *SearchHandler {
+	return &SearchHandler{
+		searchUsecase: searchUsecase,
+	}
+}
+
+// searchResult найденный файл в ответе поиска
+type searchResult struct {
+	fileInfo
+	Rank             float32 `json:"rank"`
+	NameHighlight    string  `json:"name_highlight"`
+	ContentHighlight string  `json:"content_highlight,omitempty"`
+}
+
+// SearchHandler выполняет полнотекстовый поиск: ?q=...&page=1&page_size=20
+func (h *SearchHandler) SearchHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.searchUsecase.Search(c.Request.Context(), c.Query("q"), page, pageSize)
+	if err != nil {
+		if errors.Is(err, usecase.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "поисковый запрос не указан",
+			})
+			return
+		}
+		log.Printf("ERROR: Failed to search files: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка поиска файлов",
+		})
+		return
+	}
+
+	response := make([]searchResult, 0, len(result.Results))
+	for _, r := range result.Results {
+		response = append(response, searchResult{
+			fileInfo:         newFileInfo(r.File),
+			Rank:             r.Rank,
+			NameHighlight:    r.NameHighlight,
+			ContentHighlight: r.ContentHighlight,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   response,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionInfo описание сессии в ответах API. Сам refresh token не возвращается
+type sessionInfo struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// SessionsHandler возвращает действующие сессии пользователя
+func (h *AuthHandler) SessionsHandler(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "пользователь не авторизован",
+		})
+		return
+	}
+
+	currentToken, _ := getRefreshTokenFromCookie(c)
+	sessions, currentID, err := h.userUsecase.Sessions(c.Request.Context(), userID, currentToken)
+	if err != nil {
+		log.Printf("ERROR: Failed to list sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения списка сессий",
+		})
+		return
+	}
+
+	response := make([]sessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionInfo{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": response,
+	})
+}
+
+// RevokeSessionHandler завершает одну сессию пользователя
+func (h *AuthHandler) RevokeSessionHandler(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "пользователь не авторизован",
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || sessionID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор сессии",
+		})
+		return
+	}
+
+	// Завершение текущей сессии равносильно выходу
+	currentToken, _ := getRefreshTokenFromCookie(c)
+	currentID := h.userUsecase.CurrentSessionID(c.Request.Context(), userID, currentToken)
+
+	if err := h.userUsecase.RevokeSession(c.Request.Context(), userID, uint(sessionID)); err != nil {
+		log.Printf("ERROR: Failed to revoke session: %v", err)
+		if errors.Is(err, models.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "сессия не найдена",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка завершения сессии",
+		})
+		return
+	}
+
+	if currentID == uint(sessionID) {
+		deleteRefreshTokenCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "сессия завершена",
+	})
+}
+
+// RevokeSessionsHandler завершает все сессии пользователя.
+// С ?keep_current=true текущая сессия остается активной
+func (h *AuthHandler) RevokeSessionsHandler(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "пользователь не авторизован",
+		})
+		return
+	}
+
+	keepCurrent := c.Query("keep_current") == "true"
+	var keepToken string
+	if keepCurrent {
+		keepToken, _ = getRefreshTokenFromCookie(c)
+	}
+
+	revoked, err := h.userUsecase.RevokeSessions(c.Request.Context(), userID, keepToken)
+	if err != nil {
+		log.Printf("ERROR: Failed to revoke sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка завершения сессий",
+		})
+		return
+	}
+
+	if !keepCurrent {
+		deleteRefreshTokenCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "сессии завершены",
+		"revoked": revoked,
+	})
+}
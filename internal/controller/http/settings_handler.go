@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	settingsUsecase *usecase.SettingsUsecase
+}
+
+func NewSettingsHandler(settingsUsecase *usecase.SettingsUsecase) *SettingsHandler {
+	return &SettingsHandler{
+		settingsUsecase: settingsUsecase,
+	}
+}
+
+// UpdateSettingsRequest изменение системных настроек. Не переданные поля не меняются
+type UpdateSettingsRequest struct {
+	RegistrationEnabled *bool   `json:"registration_enabled"`
+	DefaultRole         *string `json:"default_role"`
+}
+
+// GetHandler возвращает действующие системные настройки
+func (h *SettingsHandler) GetHandler(c *gin.Context) {
+	settings, err := h.settingsUsecase.Settings(c.Request.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to get settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения настроек",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateHandler изменяет системные настройки
+func (h *SettingsHandler) UpdateHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.settingsUsecase.Update(c.Request.Context(), userID, usecase.SettingsPatch{
+		RegistrationEnabled: req.RegistrationEnabled,
+		DefaultRole:         req.DefaultRole,
+	})
+	if err != nil {
+		log.Printf("ERROR: Failed to update settings: %v", err)
+		if errors.Is(err, usecase.ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверные настройки: " + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка сохранения настроек",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashHandler struct {
+	trashUsecase *usecase.TrashUsecase
+}
+
+func NewTrashHandler(trashUsecase *usecase.TrashUsecase) *TrashHandler {
+	return &TrashHandler{
+		trashUsecase: trashUsecase,
+	}
+}
+
+// ListHandler возвращает содержимое корзины: ?page=1&page_size=20
+func (h *TrashHandler) ListHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.trashUsecase.List(c.Request.Context(), page, pageSize)
+	if err != nil {
+		writeTrashError(c, err, "ошибка получения корзины")
+		return
+	}
+
+	if result.Files == nil {
+		result.Files = []*models.TrashedFile{}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// TrashHandler переносит файл в корзину. Учитывает Lock-Token и If-Match
+func (h *TrashHandler) TrashHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "имя файла не указано",
+		})
+		return
+	}
+
+	trashed, err := h.trashUsecase.Trash(c.Request.Context(), filename, parsePreconditions(c), 0)
+	if err != nil {
+		writeTrashError(c, err, "ошибка переноса файла в корзину")
+		return
+	}
+
+	c.JSON(http.StatusOK, trashed)
+}
+
+// RestoreHandler возвращает файл из корзины под прежним именем
+func (h *TrashHandler) RestoreHandler(c *gin.Context) {
+	id, ok := parseTrashID(c)
+	if !ok {
+		return
+	}
+
+	meta, err := h.trashUsecase.Restore(c.Request.Context(), id)
+	if err != nil {
+		writeTrashError(c, err, "ошибка восстановления файла")
+		return
+	}
+
+	c.Header("ETag", formatETag(meta.ETag))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "файл восстановлен",
+		"file":    newFileInfo(meta),
+	})
+}
+
+// PurgeHandler окончательно удаляет файл из корзины
+func (h *TrashHandler) PurgeHandler(c *gin.Context) {
+	id, ok := parseTrashID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashUsecase.Purge(c.Request.Context(), id); err != nil {
+		writeTrashError(c, err, "ошибка очистки корзины")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "файл удален окончательно",
+	})
+}
+
+func parseTrashID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор файла в корзине",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeTrashError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "файл не найден",
+		})
+	case errors.Is(err, models.ErrTrashedFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "файл в корзине не найден",
+		})
+	case errors.Is(err, models.ErrFileExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "файл с таким именем уже существует",
+		})
+	case errors.Is(err, models.ErrFileLocked):
+		writeFileLocked(c)
+	case errors.Is(err, models.ErrFileProtected):
+		writeFileProtected(c)
+	case errors.Is(err, models.ErrPreconditionFailed):
+		writePreconditionFailed(c)
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Значение параметра format для выгрузки в CSV
+const csvFormat = "csv"
+
+type UsageHandler struct {
+	usageUsecase *usecase.UsageUsecase
+}
+
+func NewUsageHandler(usageUsecase *usecase.UsageUsecase) *UsageHandler {
+	return &UsageHandler{
+		usageUsecase: usageUsecase,
+	}
+}
+
+// HistoryHandler возвращает дневные снимки занятого места:
+// ?from=2025-01-01&to=2025-01-31&group=total|user|type&owner_id=&format=csv
+func (h *UsageHandler) HistoryHandler(c *gin.Context) {
+	filter, err := parseUsageFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные параметры фильтра: " + err.Error(),
+		})
+		return
+	}
+
+	points, err := h.usageUsecase.History(c.Request.Context(), filter)
+	if err != nil {
+		writeUsageError(c, err, "ошибка получения истории занятого места")
+		return
+	}
+
+	if c.Query("format") == csvFormat {
+		rows := make([][]string, 0, len(points))
+		for _, p := range points {
+			rows = append(rows, []string{
+				p.Date.Format(time.DateOnly),
+				formatOwnerID(p.OwnerID),
+				p.OwnerEmail,
+				p.MimeType,
+				strconv.FormatInt(p.Bytes, 10),
+				strconv.FormatInt(p.Files, 10),
+			})
+		}
+		writeCSV(c, "usage-history.csv", []string{"date", "owner_id", "owner_email", "mime_type", "bytes", "files"}, rows)
+		return
+	}
+
+	if points == nil {
+		points = []*models.UsagePoint{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"group":  filter.GroupBy,
+		"from":   filter.From.Format(time.DateOnly),
+		"to":     filter.To.Format(time.DateOnly),
+		"points": points,
+	})
+}
+
+// LargestFilesHandler возвращает самые большие файлы: ?limit=50&format=csv
+func (h *UsageHandler) LargestFilesHandler(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	files, err := h.usageUsecase.LargestFiles(c.Request.Context(), limit)
+	if err != nil {
+		writeUsageError(c, err, "ошибка получения крупнейших файлов")
+		return
+	}
+
+	if c.Query("format") == csvFormat {
+		rows := make([][]string, 0, len(files))
+		for _, f := range files {
+			rows = append(rows, []string{
+				f.Name,
+				strconv.FormatInt(f.Size, 10),
+				f.MimeType,
+				formatOwnerID(f.OwnerID),
+				f.OwnerEmail,
+				f.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+		writeCSV(c, "largest-files.csv", []string{"name", "size", "mime_type", "owner_id", "owner_email", "updated_at"}, rows)
+		return
+	}
+
+	if files == nil {
+		files = []*models.LargestFile{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"files": files,
+	})
+}
+
+// UsersHandler возвращает пользователей, занимающих больше всего места: ?limit=50&format=csv
+func (h *UsageHandler) UsersHandler(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	users, err := h.usageUsecase.HeaviestUsers(c.Request.Context(), limit)
+	if err != nil {
+		writeUsageError(c, err, "ошибка получения статистики пользователей")
+		return
+	}
+
+	if c.Query("format") == csvFormat {
+		rows := make([][]string, 0, len(users))
+		for _, u := range users {
+			rows = append(rows, []string{
+				formatOwnerID(u.OwnerID),
+				u.OwnerEmail,
+				strconv.FormatInt(u.Bytes, 10),
+				strconv.FormatInt(u.Files, 10),
+			})
+		}
+		writeCSV(c, "usage-users.csv", []string{"owner_id", "owner_email", "bytes", "files"}, rows)
+		return
+	}
+
+	if users == nil {
+		users = []*models.UserUsage{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+	})
+}
+
+// TypesHandler возвращает занятое место по MIME-типам: ?format=csv
+func (h *UsageHandler) TypesHandler(c *gin.Context) {
+	types, err := h.usageUsecase.Types(c.Request.Context())
+	if err != nil {
+		writeUsageError(c, err, "ошибка получения статистики типов")
+		return
+	}
+
+	if c.Query("format") == csvFormat {
+		rows := make([][]string, 0, len(types))
+		for _, t := range types {
+			rows = append(rows, []string{
+				t.MimeType,
+				strconv.FormatInt(t.Bytes, 10),
+				strconv.FormatInt(t.Files, 10),
+			})
+		}
+		writeCSV(c, "usage-types.csv", []string{"mime_type", "bytes", "files"}, rows)
+		return
+	}
+
+	if types == nil {
+		types = []*models.TypeUsage{}
+	}
+	var bytes, files int64
+	for _, t := range types {
+		bytes += t.Bytes
+		files += t.Files
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"types":       types,
+		"total_bytes": bytes,
+		"total_files": files,
+	})
+}
+
+// SnapshotHandler снимает статистику за текущий день вне расписания
+func (h *UsageHandler) SnapshotHandler(c *gin.Context) {
+	if err := h.usageUsecase.TakeSnapshot(c.Request.Context()); err != nil {
+		writeUsageError(c, err, "ошибка снятия статистики")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "статистика за текущий день обновлена",
+	})
+}
+
+func parseUsageFilter(c *gin.Context) (*models.UsageFilter, error) {
+	filter := &models.UsageFilter{
+		GroupBy: c.Query("group"),
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.To = to
+	}
+	if value := c.Query("owner_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ownerID := uint(id)
+		filter.OwnerID = &ownerID
+	}
+
+	return filter, nil
+}
+
+// Выгрузка таблицы в CSV; первая строка — названия столбцов
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	// Ошибка записи в соединение сохраняется в буфере и возвращается из WriteAll
+	_ = writer.Write(header)
+	if err := writer.WriteAll(rows); err != nil {
+		// Заголовки уже отправлены, поэтому только логируем
+		log.Printf("ERROR: Failed to write %s: %v", filename, err)
+	}
+}
+
+// Файлы без владельца выгружаются с пустым owner_id
+func formatOwnerID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func writeUsageError(c *gin.Context, err error, message string) {
+	if errors.Is(err, usecase.ErrInvalidUsageFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверные параметры фильтра: " + err.Error(),
+		})
+		return
+	}
+	log.Printf("ERROR: %s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetUserRoleRequest структура для назначения роли пользователю
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// MeHandler возвращает текущего пользователя, его роль и разрешения
+func (h *AuthHandler) MeHandler(c *gin.Context) {
+	userID, _ := GetUserID(c)
+
+	user, err := h.userUsecase.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to get current user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения пользователя",
+		})
+		return
+	}
+
+	permissions, err := h.userUsecase.Permissions(c.Request.Context(), user.Role)
+	if err != nil {
+		log.Printf("ERROR: Failed to get permissions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения прав доступа",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":        user,
+		"permissions": permissions,
+	})
+}
+
+// ListUsersHandler возвращает пользователей: ?page=1&page_size=50
+func (h *AuthHandler) ListUsersHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	result, err := h.userUsecase.ListUsers(c.Request.Context(), page, pageSize)
+	if err != nil {
+		log.Printf("ERROR: Failed to list users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения списка пользователей",
+		})
+		return
+	}
+
+	if result.Users == nil {
+		result.Users = []*models.User{}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUserHandler возвращает пользователя по ID
+func (h *AuthHandler) GetUserHandler(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUsecase.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		writeUserError(c, err, "ошибка получения пользователя")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SetUserRoleHandler назначает пользователю роль
+func (h *AuthHandler) SetUserRoleHandler(c *gin.Context) {
+	actorID, _ := GetUserID(c)
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userUsecase.SetUserRole(c.Request.Context(), actorID, userID, req.Role); err != nil {
+		writeUserError(c, err, "ошибка назначения роли")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "роль назначена",
+	})
+}
+
+// LogoutUserHandler завершает все сессии пользователя и отзывает его access токены
+func (h *AuthHandler) LogoutUserHandler(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.userUsecase.GetUser(c.Request.Context(), userID); err != nil {
+		writeUserError(c, err, "ошибка получения пользователя")
+		return
+	}
+	if err := h.userUsecase.LogoutAll(c.Request.Context(), userID); err != nil {
+		writeUserError(c, err, "ошибка завершения сессий пользователя")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "сессии пользователя завершены",
+	})
+}
+
+// AccountLockoutResponse состояние блокировки входа в учетную запись
+type AccountLockoutResponse struct {
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// AccountLockoutHandler возвращает состояние блокировки входа пользователя
+// после неудачных попыток
+func (h *AuthHandler) AccountLockoutHandler(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	until, err := h.userUsecase.AccountLockout(c.Request.Context(), userID)
+	if err != nil {
+		writeUserError(c, err, "ошибка получения блокировки пользователя")
+		return
+	}
+
+	response := AccountLockoutResponse{Locked: !until.IsZero()}
+	if response.Locked {
+		response.LockedUntil = &until
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UnlockAccountHandler снимает блокировку входа пользователя
+func (h *AuthHandler) UnlockAccountHandler(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userUsecase.UnlockAccount(c.Request.Context(), userID); err != nil {
+		writeUserError(c, err, "ошибка снятия блокировки пользователя")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "блокировка входа снята",
+	})
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор пользователя",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeUserError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "пользователь не найден",
+		})
+	case errors.Is(err, models.ErrRoleNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "роль не найдена",
+		})
+	case errors.Is(err, usecase.ErrSelfRoleChange):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "нельзя изменить собственную роль",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
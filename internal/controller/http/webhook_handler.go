@@ -0,0 +1,191 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookUsecase *usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(webhookUsecase *usecase.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{
+		webhookUsecase: webhookUsecase,
+	}
+}
+
+// CreateWebhookRequest структура для регистрации вебхука
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+}
+
+// CreateWebhookResponse ответ с созданным вебхуком. Секрет показывается только здесь
+type CreateWebhookResponse struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+// CreateHandler регистрирует вебхук пользователя
+func (h *WebhookHandler) CreateHandler(c *gin.Context) {
+	h.create(c, false)
+}
+
+// AdminCreateHandler регистрирует системный вебхук, которому доступны события пользователей
+func (h *WebhookHandler) AdminCreateHandler(c *gin.Context) {
+	h.create(c, true)
+}
+
+// ListHandler возвращает вебхуки пользователя
+func (h *WebhookHandler) ListHandler(c *gin.Context) {
+	h.list(c, false)
+}
+
+// AdminListHandler возвращает вебхуки всех пользователей
+func (h *WebhookHandler) AdminListHandler(c *gin.Context) {
+	h.list(c, true)
+}
+
+// DeleteHandler удаляет вебхук пользователя
+func (h *WebhookHandler) DeleteHandler(c *gin.Context) {
+	h.delete(c, false)
+}
+
+// AdminDeleteHandler удаляет любой вебхук
+func (h *WebhookHandler) AdminDeleteHandler(c *gin.Context) {
+	h.delete(c, true)
+}
+
+// DeliveriesHandler возвращает историю доставок вебхука пользователя
+func (h *WebhookHandler) DeliveriesHandler(c *gin.Context) {
+	h.deliveries(c, false)
+}
+
+// AdminDeliveriesHandler возвращает историю доставок любого вебхука
+func (h *WebhookHandler) AdminDeliveriesHandler(c *gin.Context) {
+	h.deliveries(c, true)
+}
+
+func (h *WebhookHandler) create(c *gin.Context, system bool) {
+	userID, _ := GetUserID(c)
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный формат данных: " + err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookUsecase.Create(c.Request.Context(), userID, system, req.URL, req.Events)
+	if err != nil {
+		log.Printf("ERROR: Failed to create webhook: %v", err)
+		if errors.Is(err, usecase.ErrInvalidWebhook) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "неверные параметры вебхука: " + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка создания вебхука",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResponse{
+		Webhook: webhook,
+		Secret:  webhook.Secret,
+	})
+}
+
+func (h *WebhookHandler) list(c *gin.Context, all bool) {
+	userID, _ := GetUserID(c)
+
+	webhooks, err := h.webhookUsecase.List(c.Request.Context(), userID, all)
+	if err != nil {
+		log.Printf("ERROR: Failed to list webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "ошибка получения списка вебхуков",
+		})
+		return
+	}
+
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+func (h *WebhookHandler) delete(c *gin.Context, admin bool) {
+	userID, _ := GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор вебхука",
+		})
+		return
+	}
+
+	if err := h.webhookUsecase.Delete(c.Request.Context(), userID, admin, uint(id)); err != nil {
+		writeWebhookError(c, err, "ошибка удаления вебхука")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "вебхук удален",
+	})
+}
+
+func (h *WebhookHandler) deliveries(c *gin.Context, admin bool) {
+	userID, _ := GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "неверный идентификатор вебхука",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	deliveries, err := h.webhookUsecase.Deliveries(c.Request.Context(), userID, admin, uint(id), limit, offset)
+	if err != nil {
+		writeWebhookError(c, err, "ошибка получения истории доставок")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+	})
+}
+
+func writeWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound), errors.Is(err, usecase.ErrWebhookForbidden):
+		// Не раскрываем существование чужих вебхуков
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "вебхук не найден",
+		})
+	default:
+		log.Printf("ERROR: %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
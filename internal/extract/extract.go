@@ -0,0 +1,125 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ErrUnsupported формат файла не поддерживается для извлечения текста
+var ErrUnsupported = errors.New("unsupported file format")
+
+// maxLength — сколько байт текста достаточно; extractor может вернуть больше,
+// но прекращает разбор, набрав столько. 0 — без ограничения
+type extractor func(data []byte, maxLength int) (string, error)
+
+var extractors = map[string]extractor{
+	".txt":      plainText,
+	".text":     plainText,
+	".log":      plainText,
+	".md":       plainText,
+	".markdown": plainText,
+	".html":     htmlText,
+	".htm":      htmlText,
+	".csv":      csvText,
+	".docx":     docxText,
+	".xlsx":     xlsxText,
+}
+
+// Supported сообщает, умеем ли мы извлекать текст из файла с таким именем
+func Supported(filename string) bool {
+	_, ok := extractors[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// Text извлекает текст из содержимого файла. Формат определяется по расширению.
+// Разбор прекращается, когда набрано maxLength байт текста (0 — без ограничения)
+func Text(filename string, data []byte, maxLength int) (string, error) {
+	fn, ok := extractors[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return "", ErrUnsupported
+	}
+
+	text, err := fn(data, maxLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text from %s: %w", filename, err)
+	}
+	return text, nil
+}
+
+func plainText(data []byte, _ int) (string, error) {
+	if !utf8.Valid(data) {
+		return strings.ToValidUTF8(string(data), " "), nil
+	}
+	return string(data), nil
+}
+
+func htmlText(data []byte, maxLength int) (string, error) {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	skip := 0
+
+	for maxLength == 0 || sb.Len() < maxLength {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return "", err
+			}
+			return strings.TrimSpace(sb.String()), nil
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if isInvisibleTag(name) {
+				skip++
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if isInvisibleTag(name) && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			if text := strings.TrimSpace(string(tokenizer.Text())); text != "" {
+				sb.WriteString(text)
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func isInvisibleTag(name []byte) bool {
+	switch string(name) {
+	case "script", "style", "noscript", "template":
+		return true
+	}
+	return false
+}
+
+func csvText(data []byte, maxLength int) (string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var sb strings.Builder
+	for maxLength == 0 || sb.Len() < maxLength {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(strings.Join(record, " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,136 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Документы Office Open XML — это zip-архивы с XML частями.
+// Текст лежит в элементах <w:t> (docx) и <t> (xlsx)
+
+// Ограничение распакованного объема XML частей одного документа: сжатый
+// архив небольшого размера может распаковываться в гигабайты
+const maxUncompressedSize = 64 << 20
+
+func docxText(data []byte, maxLength int) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not an OOXML archive: %w", err)
+	}
+
+	c := newTextCollector(maxLength)
+	for _, name := range matchParts(archive, "word/document.xml", "word/header*.xml", "word/footer*.xml", "word/footnotes.xml") {
+		if c.full() {
+			break
+		}
+		if err := c.collect(archive, name, "t", "p"); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(c.sb.String()), nil
+}
+
+func xlsxText(data []byte, maxLength int) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not an OOXML archive: %w", err)
+	}
+
+	// Строковые значения ячеек хранятся в общей таблице, встроенные — прямо в листах
+	c := newTextCollector(maxLength)
+	for _, name := range matchParts(archive, "xl/sharedStrings.xml", "xl/worksheets/sheet*.xml") {
+		if c.full() {
+			break
+		}
+		if err := c.collect(archive, name, "t", "si"); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(c.sb.String()), nil
+}
+
+// Список частей архива, подходящих под шаблоны, в порядке шаблонов
+func matchParts(archive *zip.Reader, patterns ...string) []string {
+	var result []string
+	for _, pattern := range patterns {
+		var matched []string
+		for _, file := range archive.File {
+			if ok, _ := path.Match(pattern, file.Name); ok {
+				matched = append(matched, file.Name)
+			}
+		}
+		sort.Strings(matched)
+		result = append(result, matched...)
+	}
+	return result
+}
+
+// textCollector собирает текст частей документа, пока не наберет maxLength байт
+// или не исчерпает лимит распаковки
+type textCollector struct {
+	sb        strings.Builder
+	maxLength int   // 0 — без ограничения
+	remaining int64 // сколько еще байт можно распаковать
+}
+
+func newTextCollector(maxLength int) *textCollector {
+	return &textCollector{maxLength: maxLength, remaining: maxUncompressedSize}
+}
+
+func (c *textCollector) full() bool {
+	return c.remaining <= 0 || (c.maxLength > 0 && c.sb.Len() >= c.maxLength)
+}
+
+// Сбор текста из элементов textElem; после каждого blockElem добавляется перевод строки
+func (c *textCollector) collect(archive *zip.Reader, name, textElem, blockElem string) error {
+	part, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open part %s: %w", name, err)
+	}
+	defer part.Close()
+
+	limited := &io.LimitedReader{R: part, N: c.remaining}
+	defer func() { c.remaining = limited.N }()
+
+	decoder := xml.NewDecoder(limited)
+	inText := false
+	for !c.full() {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// Лимит распаковки исчерпан посреди части: оставляем уже собранный текст
+			if limited.N <= 0 {
+				return nil
+			}
+			return fmt.Errorf("failed to parse part %s: %w", name, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == textElem {
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case textElem:
+				inText = false
+			case blockElem:
+				c.sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				c.sb.Write(t)
+				c.sb.WriteByte(' ')
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Архив docx с одной частью word/document.xml; write пишет ее содержимое
+func buildDocx(t *testing.T, write func(w io.Writer) error) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	part, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if err := write(part); err != nil {
+		t.Fatalf("write part: %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDocxStopsAtMaxLength(t *testing.T) {
+	data := buildDocx(t, func(w io.Writer) error {
+		io.WriteString(w, `<w:document><w:body>`)
+		for range 10000 {
+			io.WriteString(w, `<w:p><w:t>абзац</w:t></w:p>`)
+		}
+		_, err := io.WriteString(w, `</w:body></w:document>`)
+		return err
+	})
+
+	text, err := Text("report.docx", data, 100)
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	if len(text) < 100 || len(text) > 120 {
+		t.Errorf("len(text) = %d, want about 100", len(text))
+	}
+	if !strings.HasPrefix(text, "абзац") {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestDocxBoundsDecompressedSize(t *testing.T) {
+	// Несколько сотен килобайт в архиве, больше maxUncompressedSize после распаковки
+	data := buildDocx(t, func(w io.Writer) error {
+		io.WriteString(w, `<w:document><w:body><w:p><w:t>начало</w:t></w:p><!--`)
+		filler := bytes.Repeat([]byte(" "), 1<<20)
+		for range maxUncompressedSize>>20 + 16 {
+			if _, err := w.Write(filler); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, `--><w:p><w:t>конец</w:t></w:p></w:body></w:document>`)
+		return err
+	})
+
+	text, err := Text("bomb.docx", data, 0)
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	if text != "начало" {
+		t.Errorf("text = %q, want text before the decompression limit", text)
+	}
+}
@@ -0,0 +1,305 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Письмо, принятое тестовым сервером
+type receivedMail struct {
+	from string
+	to   []string
+	auth string
+	data string
+}
+
+// fakeSMTPServer принимает одно соединение и записывает конверт и текст письма
+type fakeSMTPServer struct {
+	listener net.Listener
+	received chan receivedMail
+	errs     chan error
+	// Не отвечать после приветствия, чтобы проверить прерывание по контексту
+	stall bool
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	return &fakeSMTPServer{
+		listener: listener,
+		received: make(chan receivedMail, 1),
+		errs:     make(chan error, 1),
+	}
+}
+
+func (s *fakeSMTPServer) start() {
+	go func() {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.errs <- err
+			return
+		}
+		defer conn.Close()
+		if err := s.serve(conn); err != nil {
+			s.errs <- err
+		}
+	}()
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(line string) error {
+		_, err := io.WriteString(conn, line+"\r\n")
+		return err
+	}
+
+	if err := reply("220 localhost ESMTP test"); err != nil {
+		return err
+	}
+	if s.stall {
+		// Читаем команды без ответа, пока клиент не закроет соединение
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+
+	var msg receivedMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			err = reply("250-localhost\r\n250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			decoded, decodeErr := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			if decodeErr != nil {
+				return decodeErr
+			}
+			msg.auth = string(decoded)
+			err = reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = line[len("MAIL FROM:"):]
+			err = reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, line[len("RCPT TO:"):])
+			err = reply("250 OK")
+		case cmd == "DATA":
+			if err := reply("354 End data with <CR><LF>.<CR><LF>"); err != nil {
+				return err
+			}
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return err
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			msg.data = data.String()
+			err = reply("250 OK queued")
+		case cmd == "QUIT":
+			s.received <- msg
+			return reply("221 Bye")
+		default:
+			err = reply("502 Command not implemented")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *fakeSMTPServer) wait(t *testing.T) receivedMail {
+	t.Helper()
+	select {
+	case msg := <-s.received:
+		return msg
+	case err := <-s.errs:
+		t.Fatalf("smtp server: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp server did not receive a message")
+	}
+	return receivedMail{}
+}
+
+func newTestMailer(t *testing.T, server *fakeSMTPServer, cfg Config) *SMTPMailer {
+	t.Helper()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = server.port()
+	cfg.TLSMode = TLSModeNone
+	if cfg.From == "" {
+		cfg.From = "Tages <noreply@example.com>"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	mailer, err := NewSMTPMailer(cfg)
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+	return mailer
+}
+
+func TestSendPlain(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.start()
+	mailer := newTestMailer(t, server, Config{})
+
+	err := mailer.Send(context.Background(), &models.MailMessage{
+		To:      "Анна <ann@example.com>",
+		Subject: "Сброс пароля",
+		Body:    "Ссылка для сброса:\nhttps://example.com/reset?token=abc=def\n.\nКонец",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got := server.wait(t)
+
+	if got.from != "<noreply@example.com>" {
+		t.Errorf("MAIL FROM = %q", got.from)
+	}
+	if len(got.to) != 1 || got.to[0] != "<ann@example.com>" {
+		t.Errorf("RCPT TO = %q", got.to)
+	}
+	if got.auth != "" {
+		t.Errorf("unexpected AUTH without credentials: %q", got.auth)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("parse message: %v\n%s", err, got.data)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil || from.Address != "noreply@example.com" || from.Name != "Tages" {
+		t.Errorf("From = %q (%v)", msg.Header.Get("From"), err)
+	}
+	var dec mail.AddressParser
+	dec.WordDecoder = &mime.WordDecoder{}
+	to, err := dec.Parse(msg.Header.Get("To"))
+	if err != nil || to.Address != "ann@example.com" || to.Name != "Анна" {
+		t.Errorf("To = %q (%v)", msg.Header.Get("To"), err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Сброс пароля" {
+		t.Errorf("Subject = %q (%v)", msg.Header.Get("Subject"), err)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+	if ct := msg.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cte := msg.Header.Get("Content-Transfer-Encoding"); cte != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", cte)
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	// Строка из одной точки не завершает письмо; DATA дописывает перевод строки в конце
+	want := "Ссылка для сброса:\r\nhttps://example.com/reset?token=abc=def\r\n.\r\nКонец\r\n"
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSendAuth(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.start()
+	mailer := newTestMailer(t, server, Config{Username: "mailer", Password: "secret"})
+
+	if err := mailer.Send(context.Background(), &models.MailMessage{To: "ann@example.com", Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got := server.wait(t)
+	if got.auth != "\x00mailer\x00secret" {
+		t.Errorf("AUTH PLAIN = %q", got.auth)
+	}
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	mailer := newTestMailer(t, server, Config{})
+
+	err := mailer.Send(context.Background(), &models.MailMessage{
+		To:      "ann@example.com",
+		Subject: "Привет\r\nBcc: victim@example.com",
+		Body:    "b",
+	})
+	if !errors.Is(err, errHeaderInjection) {
+		t.Errorf("Send: got %v, want errHeaderInjection", err)
+	}
+	if err := mailer.Send(context.Background(), &models.MailMessage{To: "ann@example.com\r\nBcc: x@example.com", Subject: "s"}); err == nil {
+		t.Error("Send accepted recipient with line break")
+	}
+}
+
+func TestSendCancelledByContext(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.stall = true
+	server.start()
+	mailer := newTestMailer(t, server, Config{Timeout: 200 * time.Millisecond})
+
+	start := time.Now()
+	err := mailer.Send(context.Background(), &models.MailMessage{To: "ann@example.com", Subject: "s", Body: "b"})
+	if err == nil {
+		t.Fatal("Send succeeded against a stalled server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send returned after %s, want about the configured timeout", elapsed)
+	}
+}
+
+func TestNewSMTPMailerValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "bad sender", cfg: Config{From: "not an address", TLSMode: TLSModeNone}},
+		{name: "unknown tls mode", cfg: Config{From: "noreply@example.com", TLSMode: "ssl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSMTPMailer(tt.cfg); err == nil {
+				t.Error("NewSMTPMailer accepted invalid config")
+			}
+		})
+	}
+	if _, err := NewSMTPMailer(Config{From: "noreply@example.com", TLSMode: TLSModeStartTLS, Port: 587}); err != nil {
+		t.Errorf("NewSMTPMailer: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+// APITokenPrefix префикс персональных токенов, по которому их легко отличить от JWT
+const APITokenPrefix = "tgs_"
+
+// Области действия персональных токенов совпадают с разрешениями ролей:
+// токен может только то, что разрешено и ему, и роли владельца
+const (
+	ScopeFilesRead   = PermissionFilesRead
+	ScopeFilesWrite  = PermissionFilesWrite
+	ScopeFilesDelete = PermissionFilesDelete
+	ScopeAdmin       = PermissionAdmin
+)
+
+// APIToken персональный токен доступа к API. Сам токен показывается только
+// при создании, в базе хранится его хеш
+type APIToken struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+}
+
+// HasScope проверяет, разрешено ли токену действие
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Действия, фиксируемые в журнале аудита
+const (
+	AuditActionRegister       = "auth.register"
+	AuditActionLogin          = "auth.login"
+	AuditActionLoginFailed    = "auth.login_failed"
+	AuditActionTokenRefresh   = "auth.token_refresh"
+	AuditActionTokenReuse     = "auth.token_reuse"
+	AuditActionLogout         = "auth.logout"
+	AuditActionLogoutAll      = "auth.logout_all"
+	AuditActionSessionRevoke  = "auth.session_revoke"
+	AuditActionSessionsRevoke = "auth.sessions_revoke"
+	AuditActionFileUpload     = "file.upload"
+	AuditActionFileDownload   = "file.download"
+	AuditActionFileDelete     = "file.delete"
+	AuditActionMetadataUpdate = "file.metadata_update"
+	AuditActionFileMove       = "file.move"
+	AuditActionFileLockBreak  = "file.lock_break"
+	AuditActionFileTrash      = "file.trash"
+	AuditActionFileRestore    = "file.restore"
+	AuditActionFilePurge      = "file.purge"
+
+	AuditActionRetentionSet         = "retention.set"
+	AuditActionRetentionClear       = "retention.clear"
+	AuditActionFolderRetentionSet   = "retention.folder_set"
+	AuditActionFolderRetentionClear = "retention.folder_clear"
+	AuditActionLegalHoldSet         = "legal_hold.set"
+	AuditActionLegalHoldClear       = "legal_hold.clear"
+
+	AuditActionLifecycleRuleCreate = "lifecycle.rule_create"
+	AuditActionLifecycleRuleUpdate = "lifecycle.rule_update"
+	AuditActionLifecycleRuleDelete = "lifecycle.rule_delete"
+
+	AuditActionAPITokenCreate = "api_token.create"
+	AuditActionAPITokenRevoke = "api_token.revoke"
+
+	AuditActionUserRoleChange = "user.role_change"
+	AuditActionRoleCreate     = "role.create"
+	AuditActionRoleUpdate     = "role.update"
+	AuditActionRoleDelete     = "role.delete"
+	AuditActionSettingsUpdate = "settings.update"
+
+	AuditActionMFAEnable               = "auth.mfa_enable"
+	AuditActionMFADisable              = "auth.mfa_disable"
+	AuditActionRecoveryCodesRegenerate = "auth.recovery_codes_regenerate"
+	AuditActionRecoveryCodeUsed        = "auth.recovery_code_used"
+
+	AuditActionPasswordResetRequest = "auth.password_reset_request"
+	AuditActionPasswordReset        = "auth.password_reset"
+	AuditActionPasswordChange       = "auth.password_change"
+	AuditActionEmailVerify          = "auth.email_verify"
+	AuditActionLockout              = "auth.lockout"
+	AuditActionAccountUnlock        = "auth.account_unlock"
+)
+
+// Результат действия
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent запись журнала аудита
+type AuditEvent struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Action     string            `json:"action"`
+	Outcome    string            `json:"outcome"`
+	ActorID    uint              `json:"actor_id,omitempty"`
+	ActorEmail string            `json:"actor_email,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// AuditFilter условия выборки журнала аудита. Пустые поля не учитываются
+type AuditFilter struct {
+	ActorID *uint
+	Action  *string
+	Target  *string
+	Outcome *string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Offset  int
+}
+
+// RequestMeta сведения о запросе, в рамках которого выполняется операция
+type RequestMeta struct {
+	ActorID   uint
+	IP        string
+	UserAgent string
+	RequestID string
+}
@@ -0,0 +1,9 @@
+package models
+
+// CacheStats счетчики попаданий и промахов кэша
+type CacheStats struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Comment комментарий к файлу. Ответы ссылаются на родительский комментарий,
+// решенной помечается только ветка целиком (комментарий верхнего уровня)
+type Comment struct {
+	ID          int64      `json:"id"`
+	FileName    string     `json:"file_name"`
+	ParentID    int64      `json:"parent_id,omitempty"`
+	AuthorID    uint       `json:"author_id"`
+	AuthorEmail string     `json:"author_email"`
+	Body        string     `json:"body"`
+	Mentions    []string   `json:"mentions"`
+	Resolved    bool       `json:"resolved"`
+	ResolvedBy  uint       `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Replies     []*Comment `json:"replies,omitempty"`
+}
+
+// CommentPage страница комментариев
+type CommentPage struct {
+	Comments []*Comment
+	Total    int
+	Page     int
+	PageSize int
+}
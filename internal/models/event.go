@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Типы событий изменения файлов
+const (
+	FileEventCreated = "created"
+	FileEventUpdated = "updated"
+	FileEventDeleted = "deleted"
+)
+
+// FileEvent событие изменения файла для ленты изменений
+type FileEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	FileName   string    `json:"file_name"`
+	ActorID    uint      `json:"actor_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Действия, после которых файл попадает в недавние
+const (
+	RecentActionUpload   = "upload"
+	RecentActionDownload = "download"
+)
+
+// StarredFile файл в избранном пользователя
+type StarredFile struct {
+	File      *FileMeta
+	StarredAt time.Time
+}
+
+// RecentFile файл, с которым пользователь недавно работал
+type RecentFile struct {
+	File       *FileMeta
+	Action     string
+	AccessedAt time.Time
+}
+
+// StarredPage страница избранных файлов
+type StarredPage struct {
+	Files    []*StarredFile
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// RecentPage страница недавних файлов
+type RecentPage struct {
+	Files    []*RecentFile
+	Total    int
+	Page     int
+	PageSize int
+}
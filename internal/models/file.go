@@ -1,11 +1,43 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrFileExists   = errors.New("file already exists")
+	// Условие If-Match / If-None-Match не выполнено
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
 
 type FileMeta struct {
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Name       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Tags       []string
+	Attributes map[string]string
+	// Версия файла; меняется при каждой перезаписи содержимого или метаданных
+	ETag string
+	// Размер содержимого в байтах и MIME-тип; пустой тип — еще не определены
+	Size     int64
+	MimeType string
+	// Пользователь, загрузивший файл; 0 — неизвестен
+	OwnerID uint
+	// Активная блокировка файла, заполняется при выводе списка
+	Lock *FileLock
+}
+
+// FileFilter условия отбора файлов в списке.
+// Файл подходит, если содержит все теги и все пары ключ/значение.
+type FileFilter struct {
+	Tags       []string
+	Attributes map[string]string
+}
+
+func (f *FileFilter) IsEmpty() bool {
+	return f == nil || (len(f.Tags) == 0 && len(f.Attributes) == 0)
 }
 
 type FileReader interface {
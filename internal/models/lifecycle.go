@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrLifecycleRuleNotFound = errors.New("lifecycle rule not found")
+	ErrTrashedFileNotFound   = errors.New("trashed file not found")
+)
+
+// Условия срабатывания правила жизненного цикла.
+// age — прошло N дней с последнего изменения файла;
+// idle — к файлу не обращались N дней
+const (
+	LifecycleConditionAge  = "age"
+	LifecycleConditionIdle = "idle"
+)
+
+// Действия правила жизненного цикла
+const (
+	LifecycleActionDelete = "delete"
+	LifecycleActionTrash  = "trash"
+)
+
+// Результат применения правила к файлу
+const (
+	LifecycleOutcomeApplied = "applied"
+	// Файл защищен, заблокирован или изменился после отбора
+	LifecycleOutcomeSkipped = "skipped"
+	LifecycleOutcomeFailed  = "failed"
+)
+
+// LifecycleRule правило автоматического удаления файлов.
+// Файл подходит, если имя начинается с Prefix и среди тегов есть Tag; пустые поля не учитываются
+type LifecycleRule struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	OwnerID   uint       `json:"owner_id"`
+	Prefix    string     `json:"prefix"`
+	Tag       string     `json:"tag"`
+	Condition string     `json:"condition"`
+	AfterDays int        `json:"after_days"`
+	Action    string     `json:"action"`
+	Enabled   bool       `json:"enabled"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+}
+
+// LifecycleCandidate файл, подходящий под правило
+type LifecycleCandidate struct {
+	Name       string    `json:"name"`
+	ETag       string    `json:"etag"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	AccessedAt time.Time `json:"accessed_at"`
+	// Заполняется при предпросмотре: файл будет пропущен из-за срока хранения или удержания
+	Protected bool `json:"protected"`
+}
+
+// LifecycleDryRun результат предпросмотра правила
+type LifecycleDryRun struct {
+	Rule  *LifecycleRule        `json:"rule"`
+	Files []*LifecycleCandidate `json:"files"`
+	Total int                   `json:"total"`
+}
+
+// LifecycleAction запись истории применения правила
+type LifecycleAction struct {
+	ID        int64     `json:"id"`
+	RuleID    int64     `json:"rule_id"`
+	FileName  string    `json:"file_name"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// LifecycleActionPage страница истории правила
+type LifecycleActionPage struct {
+	Actions  []*LifecycleAction `json:"actions"`
+	Total    int                `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+// TrashedFile файл в корзине. Содержимое хранится под служебным именем до восстановления или очистки
+type TrashedFile struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+	ETag       string            `json:"etag"`
+	Size       int64             `json:"size"`
+	MimeType   string            `json:"mime_type"`
+	OwnerID    uint              `json:"owner_id,omitempty"`
+	TrashedAt  time.Time         `json:"trashed_at"`
+	TrashedBy  uint              `json:"trashed_by,omitempty"`
+	RuleID     int64             `json:"rule_id,omitempty"`
+}
+
+// TrashPage страница корзины
+type TrashPage struct {
+	Files    []*TrashedFile `json:"files"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrFileLocked   = errors.New("file is locked")
+	ErrLockNotFound = errors.New("lock not found")
+)
+
+// FileLock блокировка файла пользователем (check-out). Изменять заблокированный
+// файл может только тот, кто предъявит токен блокировки
+type FileLock struct {
+	FileName   string    `json:"file_name"`
+	Token      string    `json:"token,omitempty"`
+	OwnerID    uint      `json:"owner_id"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
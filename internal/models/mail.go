@@ -0,0 +1,8 @@
+package models
+
+// MailMessage текстовое письмо пользователю
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
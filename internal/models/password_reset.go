@@ -0,0 +1,17 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+
+// PasswordResetToken одноразовый токен сброса пароля. Сам токен
+// отправляется пользователю по почте, хранится только его хеш
+type PasswordResetToken struct {
+	UserID    uint
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// Файл защищен сроком хранения или юридическим удержанием
+	ErrFileProtected = errors.New("file is protected by retention or legal hold")
+	// Изменение срока хранения запрещено режимом защиты
+	ErrRetentionConflict = errors.New("retention change is not allowed")
+	ErrRetentionNotFound = errors.New("retention not found")
+	ErrLegalHoldNotFound = errors.New("legal hold not found")
+)
+
+// Режимы срока хранения.
+// governance — администратор может сократить или снять срок;
+// compliance — срок нельзя сократить или снять никому, только продлить
+const (
+	RetentionModeGovernance = "governance"
+	RetentionModeCompliance = "compliance"
+)
+
+// Retention срок хранения файла (FileName) или всех файлов с префиксом имени (Prefix).
+// До RetainUntil файл нельзя перезаписать, переместить или удалить
+type Retention struct {
+	FileName    string    `json:"file_name,omitempty"`
+	Prefix      string    `json:"prefix,omitempty"`
+	RetainUntil time.Time `json:"retain_until"`
+	Mode        string    `json:"mode"`
+	SetBy       uint      `json:"set_by"`
+	SetAt       time.Time `json:"set_at"`
+}
+
+// LegalHold бессрочное удержание файла, снимается только администратором
+type LegalHold struct {
+	FileName string    `json:"file_name"`
+	Reason   string    `json:"reason"`
+	SetBy    uint      `json:"set_by"`
+	SetAt    time.Time `json:"set_at"`
+}
+
+// FileProtection действующие защиты файла
+type FileProtection struct {
+	Retention        *Retention   `json:"retention,omitempty"`
+	FolderRetentions []*Retention `json:"folder_retentions"`
+	LegalHold        *LegalHold   `json:"legal_hold,omitempty"`
+}
+
+// Protected сообщает, действует ли хотя бы одна защита
+func (p *FileProtection) Protected() bool {
+	return p.Retention != nil || len(p.FolderRetentions) > 0 || p.LegalHold != nil
+}
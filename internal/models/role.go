@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrRoleInUse    = errors.New("role is assigned to users")
+)
+
+// Разрешения, из которых составляются роли
+const (
+	PermissionFilesRead   = "files:read"
+	PermissionFilesWrite  = "files:write"
+	PermissionFilesDelete = "files:delete"
+	PermissionAdmin       = "admin"
+)
+
+// Permissions все известные разрешения
+var Permissions = []string{
+	PermissionFilesRead,
+	PermissionFilesWrite,
+	PermissionFilesDelete,
+	PermissionAdmin,
+}
+
+// Встроенные роли. Их нельзя изменить или удалить
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "readonly"
+)
+
+// Role именованный набор разрешений, назначаемый пользователям
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HasPermission проверяет, входит ли разрешение в роль
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinRoles встроенные роли. Те же роли создаются миграцией в PostgreSQL
+func BuiltinRoles() []*Role {
+	return []*Role{
+		{
+			Name:        RoleAdmin,
+			Description: "Полный доступ, управление пользователями и настройками",
+			Permissions: []string{PermissionFilesRead, PermissionFilesWrite, PermissionFilesDelete, PermissionAdmin},
+			Builtin:     true,
+		},
+		{
+			Name:        RoleMember,
+			Description: "Работа с файлами",
+			Permissions: []string{PermissionFilesRead, PermissionFilesWrite, PermissionFilesDelete},
+			Builtin:     true,
+		},
+		{
+			Name:        RoleReadOnly,
+			Description: "Только чтение файлов",
+			Permissions: []string{PermissionFilesRead},
+			Builtin:     true,
+		},
+	}
+}
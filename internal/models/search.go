@@ -0,0 +1,18 @@
+package models
+
+// SearchResult найденный файл с рангом и подсвеченными фрагментами.
+// Фрагменты — экранированный HTML, найденные слова обернуты в <mark>
+type SearchResult struct {
+	File             *FileMeta
+	Rank             float32
+	NameHighlight    string
+	ContentHighlight string
+}
+
+// SearchPage страница результатов поиска
+type SearchPage struct {
+	Results  []*SearchResult
+	Total    int
+	Page     int
+	PageSize int
+}
@@ -0,0 +1,23 @@
+package models
+
+// Ключи системных настроек
+const (
+	SettingRegistrationEnabled = "registration_enabled"
+	SettingDefaultRole         = "default_role"
+)
+
+// Settings системные настройки, которые администратор меняет без перезапуска
+type Settings struct {
+	// Открыта ли самостоятельная регистрация пользователей
+	RegistrationEnabled bool `json:"registration_enabled"`
+	// Роль, назначаемая новым пользователям
+	DefaultRole string `json:"default_role"`
+}
+
+// DefaultSettings настройки, действующие, пока администратор их не изменил
+func DefaultSettings() *Settings {
+	return &Settings{
+		RegistrationEnabled: true,
+		DefaultRole:         RoleMember,
+	}
+}
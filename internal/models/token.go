@@ -1,10 +1,23 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
+var ErrSessionNotFound = errors.New("session not found")
+
+// RefreshToken refresh token сессии пользователя. Каждый вход с устройства
+// создает отдельную сессию — семейство токенов: при обновлении токен
+// заменяется в той же сессии. Сам токен не хранится, только его хеш
 type RefreshToken struct {
-	ID        uint
-	UserID    uint
-	Token     string
-	ExpiresAt time.Time
+	ID         uint
+	UserID     uint
+	TokenHash  string
+	ExpiresAt  time.Time
+	DeviceName string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
 }
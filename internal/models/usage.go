@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Группировка истории занятого места
+const (
+	UsageGroupTotal = "total"
+	UsageGroupUser  = "user"
+	UsageGroupType  = "type"
+)
+
+// UsageFilter условия выборки истории по дневным снимкам
+type UsageFilter struct {
+	From    time.Time
+	To      time.Time
+	GroupBy string
+	// Только файлы пользователя; не применяется при группировке по пользователям
+	OwnerID *uint
+}
+
+// UsagePoint занятое место за день. OwnerID и MimeType заполняются при соответствующей группировке
+type UsagePoint struct {
+	Date       time.Time `json:"date"`
+	OwnerID    uint      `json:"owner_id,omitempty"`
+	OwnerEmail string    `json:"owner_email,omitempty"`
+	MimeType   string    `json:"mime_type,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	Files      int64     `json:"files"`
+}
+
+// LargestFile файл в списке самых больших
+type LargestFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	MimeType   string    `json:"mime_type"`
+	OwnerID    uint      `json:"owner_id,omitempty"`
+	OwnerEmail string    `json:"owner_email,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// UserUsage занятое место пользователя; OwnerID = 0 — файлы без владельца
+type UserUsage struct {
+	OwnerID    uint   `json:"owner_id"`
+	OwnerEmail string `json:"owner_email,omitempty"`
+	Bytes      int64  `json:"bytes"`
+	Files      int64  `json:"files"`
+}
+
+// TypeUsage занятое место по MIME-типу; пустой тип — файлы, размер которых еще не определен
+type TypeUsage struct {
+	MimeType string `json:"mime_type"`
+	Bytes    int64  `json:"bytes"`
+	Files    int64  `json:"files"`
+}
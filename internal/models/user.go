@@ -1,15 +1,38 @@
 package models
 
 import (
+	"errors"
 	"time"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Email     string    `json:"email" gorm:"unique;not null"`
 	Password  string    `json:"-" gorm:"not null"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Когда были отозваны все токены пользователя (nil — не отзывались)
+	TokensValidAfter *time.Time `json:"-"`
+	// Поколение токенов: access token действителен, пока номер в нем совпадает с этим
+	TokenVersion int `json:"-"`
+
+	// Email подтвержден по ссылке из письма
+	EmailVerified bool `json:"email_verified"`
+	// Когда отправлено последнее письмо для подтверждения (nil — не отправлялось)
+	VerificationSentAt *time.Time `json:"-"`
+
+	// Вход требует кода TOTP или кода восстановления
+	MFAEnabled bool `json:"mfa_enabled"`
+	// Секрет TOTP в base32; задается при подключении, действует после подтверждения
+	TOTPSecret string `json:"-"`
+	// Последний принятый шаг TOTP: один код нельзя использовать дважды
+	TOTPLastStep int64 `json:"-"`
+	// Хеши неиспользованных кодов восстановления
+	RecoveryCodes []string `json:"-"`
 }
 
 type UserRepository interface {
@@ -23,3 +46,11 @@ type UserUseCase interface {
 	Login(email, password string) (string, error)
 	ValidateToken(token string) (*User, error)
 }
+
+// UserPage страница списка пользователей
+type UserPage struct {
+	Users    []*User `json:"users"`
+	Total    int     `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
+}
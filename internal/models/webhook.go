@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Типы событий, на которые можно подписать вебхук
+const (
+	WebhookEventFileUploaded   = "file.uploaded"
+	WebhookEventFileUpdated    = "file.updated"
+	WebhookEventFileDeleted    = "file.deleted"
+	WebhookEventUserRegistered = "user.registered"
+)
+
+// Статусы доставки вебхука
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook подписка внешней системы на события.
+// Системные вебхуки создаются администраторами и могут получать события пользователей
+type Webhook struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	System    bool      `json:"system"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery попытка доставки события на вебхук
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      uint       `json:"webhook_id"`
+	EventType      string     `json:"event_type"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Заполняются при выборке на отправку
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
@@ -0,0 +1,82 @@
+// Package boltdb хранит метаданные файлов, пользователей и refresh токены во
+// встроенной базе bbolt. Используется для развертывания на одном узле без PostgreSQL
+package boltdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	filesBucket          = []byte("files")
+	usersBucket          = []byte("users")
+	usersByEmailBucket   = []byte("users_by_email")
+	refreshTokensBucket  = []byte("refresh_tokens")
+	sessionsByUserBucket = []byte("sessions_by_user")
+
+	// Замененные refresh токены: хеш -> сессия и обратный индекс для удаления
+	rotatedTokensBucket    = []byte("rotated_refresh_tokens")
+	rotatedBySessionBucket = []byte("rotated_refresh_tokens_by_session")
+
+	// Отозванные access токены: jti -> срок действия
+	revokedTokensBucket = []byte("revoked_tokens")
+
+	// Токены сброса пароля: хеш -> токен
+	passwordResetTokensBucket = []byte("password_reset_tokens")
+
+	// Неудачные попытки входа: account:<email> или ip:<адрес> -> счетчик
+	loginAttemptsBucket = []byte("login_attempts")
+
+	// Индекс прежнего формата: один токен на пользователя
+	tokensByUserBucket = []byte("refresh_tokens_by_user")
+)
+
+type Repository struct {
+	db      *bolt.DB
+	mutexes fileMutexes
+}
+
+// New открывает (или создает) файл базы и необходимые бакеты
+func New(path string) (*Repository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{filesBucket, usersBucket, usersByEmailBucket, refreshTokensBucket, sessionsByUserBucket, rotatedTokensBucket, rotatedBySessionBucket, revokedTokensBucket, passwordResetTokensBucket, loginAttemptsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", name, err)
+			}
+		}
+		if err := migrateSessionsIndex(tx); err != nil {
+			return err
+		}
+		return migrateRefreshTokenHashes(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() {
+	r.db.Close()
+}
+
+func idKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
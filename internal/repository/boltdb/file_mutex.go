@@ -0,0 +1,73 @@
+package boltdb
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Мьютексы изменений файлов. Встроенная база используется одним процессом,
+// поэтому достаточно блокировок в памяти
+type fileMutexes struct {
+	mu    sync.Mutex
+	locks map[string]*fileMutex
+}
+
+type fileMutex struct {
+	ch   chan struct{}
+	refs int
+}
+
+// WithFileMutex ждет, пока другие запросы закончат изменять файлы, и выполняет fn.
+// Мьютексы захватываются в порядке имен, чтобы встречные операции не зависли
+func (r *Repository) WithFileMutex(ctx context.Context, filenames []string, fn func(ctx context.Context) error) error {
+	names := slices.Clone(filenames)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	for _, name := range names {
+		release, err := r.acquireFileMutex(ctx, name)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	return fn(ctx)
+}
+
+// acquireFileMutex захватывает файл до вызова release
+func (r *Repository) acquireFileMutex(ctx context.Context, filename string) (release func(), err error) {
+	m := &r.mutexes
+
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*fileMutex)
+	}
+	lock, ok := m.locks[filename]
+	if !ok {
+		lock = &fileMutex{ch: make(chan struct{}, 1)}
+		m.locks[filename] = lock
+	}
+	lock.refs++
+	m.mu.Unlock()
+
+	done := func() {
+		m.mu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(m.locks, filename)
+		}
+		m.mu.Unlock()
+	}
+
+	select {
+	case lock.ch <- struct{}{}:
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-lock.ch
+		done()
+	}, nil
+}
@@ -0,0 +1,240 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"tages/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (r *Repository) SaveFileMeta(ctx context.Context, file *models.FileMeta) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(filesBucket)
+
+		stored, err := getFile(bucket, file.Name)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			stored = &models.FileMeta{
+				Name:       file.Name,
+				CreatedAt:  file.CreatedAt,
+				Tags:       []string{},
+				Attributes: map[string]string{},
+			}
+		}
+		// Владелец не меняется при перезаписи; неизвестного владельца заменяет загрузивший
+		if stored.OwnerID == 0 {
+			stored.OwnerID = file.OwnerID
+		}
+
+		// Как и в PostgreSQL: при перезаписи теги и атрибуты меняются, только если заданы
+		stored.UpdatedAt = file.UpdatedAt
+		stored.ETag = file.ETag
+		stored.Size = file.Size
+		stored.MimeType = file.MimeType
+		if len(file.Tags) > 0 {
+			stored.Tags = file.Tags
+		}
+		if len(file.Attributes) > 0 {
+			stored.Attributes = file.Attributes
+		}
+		return putFile(bucket, stored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save file meta for %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func (r *Repository) IsFileExists(ctx context.Context, filename string) (bool, error) {
+	var exists bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(filesBucket).Get([]byte(filename)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence of file %s: %w", filename, err)
+	}
+	return exists, nil
+}
+
+func (r *Repository) UpdateFileMeta(ctx context.Context, file *models.FileMeta) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(filesBucket)
+
+		stored, err := getFile(bucket, file.Name)
+		if err != nil || stored == nil {
+			return err
+		}
+		stored.CreatedAt = file.CreatedAt
+		stored.UpdatedAt = file.UpdatedAt
+		return putFile(bucket, stored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update file meta for %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func (r *Repository) GetFilesMeta(ctx context.Context, filter *models.FileFilter) ([]*models.FileMeta, error) {
+	var files []*models.FileMeta
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).ForEach(func(_, value []byte) error {
+			var file models.FileMeta
+			if err := json.Unmarshal(value, &file); err != nil {
+				return err
+			}
+			if matchesFilter(&file, filter) {
+				files = append(files, &file)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files meta: %w", err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].UpdatedAt.After(files[j].UpdatedAt)
+	})
+	return files, nil
+}
+
+func (r *Repository) GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error) {
+	var file *models.FileMeta
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		file, err = getFile(tx.Bucket(filesBucket), filename)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file meta for %s: %w", filename, err)
+	}
+	if file == nil {
+		return nil, models.ErrFileNotFound
+	}
+	return file, nil
+}
+
+// Замена тегов и атрибутов файла
+func (r *Repository) UpdateFileMetadata(ctx context.Context, file *models.FileMeta) error {
+	var found bool
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(filesBucket)
+
+		stored, err := getFile(bucket, file.Name)
+		if err != nil || stored == nil {
+			return err
+		}
+		found = true
+
+		stored.Tags = file.Tags
+		if stored.Tags == nil {
+			stored.Tags = []string{}
+		}
+		stored.Attributes = file.Attributes
+		if stored.Attributes == nil {
+			stored.Attributes = map[string]string{}
+		}
+		stored.UpdatedAt = file.UpdatedAt
+		stored.ETag = file.ETag
+		*file = *stored
+		return putFile(bucket, stored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update metadata for %s: %w", file.Name, err)
+	}
+	if !found {
+		return models.ErrFileNotFound
+	}
+	return nil
+}
+
+func (r *Repository) DeleteFileMeta(ctx context.Context, filename string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).Delete([]byte(filename))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file meta for %s: %w", filename, err)
+	}
+	return nil
+}
+
+// Переименование файла; имя назначения должно быть свободно
+func (r *Repository) RenameFileMeta(ctx context.Context, from, to string, updatedAt time.Time) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(filesBucket)
+
+		stored, err := getFile(bucket, from)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return models.ErrFileNotFound
+		}
+		if bucket.Get([]byte(to)) != nil {
+			return models.ErrFileExists
+		}
+
+		stored.Name = to
+		stored.UpdatedAt = updatedAt
+		if err := putFile(bucket, stored); err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(from))
+	})
+	if errors.Is(err, models.ErrFileNotFound) || errors.Is(err, models.ErrFileExists) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename file meta %s to %s: %w", from, to, err)
+	}
+	return nil
+}
+
+func getFile(bucket *bolt.Bucket, filename string) (*models.FileMeta, error) {
+	value := bucket.Get([]byte(filename))
+	if value == nil {
+		return nil, nil
+	}
+
+	var file models.FileMeta
+	if err := json.Unmarshal(value, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode file meta %s: %w", filename, err)
+	}
+	return &file, nil
+}
+
+func putFile(bucket *bolt.Bucket, file *models.FileMeta) error {
+	value, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode file meta %s: %w", file.Name, err)
+	}
+	return bucket.Put([]byte(file.Name), value)
+}
+
+// Файл подходит, если содержит все теги и все пары атрибутов фильтра
+func matchesFilter(file *models.FileMeta, filter *models.FileFilter) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+	for _, tag := range filter.Tags {
+		if !slices.Contains(file.Tags, tag) {
+			return false
+		}
+	}
+	for key, value := range filter.Attributes {
+		if stored, ok := file.Attributes[key]; !ok || stored != value {
+			return false
+		}
+	}
+	return true
+}
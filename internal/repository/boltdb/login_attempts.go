@@ -0,0 +1,153 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// storedLoginAttempts счетчик неудачных попыток. Ключ записи — subject
+type storedLoginAttempts struct {
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// Время, после которого счетчик можно обнулить: последняя неудача или конец блокировки
+func (a *storedLoginAttempts) lastActivity() time.Time {
+	if a.LockedUntil != nil && a.LockedUntil.After(a.LastFailureAt) {
+		return *a.LockedUntil
+	}
+	return a.LastFailureAt
+}
+
+// Учет неудачной попытки. Возвращает количество неудач подряд; счетчик
+// начинается заново, если последняя неудача и блокировка были раньше resetBefore
+func (r *Repository) RecordLoginFailure(ctx context.Context, subject string, now, resetBefore time.Time) (int, error) {
+	var failures int
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateLoginAttempts(tx, subject, func(attempts *storedLoginAttempts) {
+			if attempts.lastActivity().Before(resetBefore) {
+				attempts.Failures = 0
+			}
+			attempts.Failures++
+			attempts.LastFailureAt = now
+			failures = attempts.Failures
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+// Блокировка попыток до until. Более долгая блокировка не сокращается
+func (r *Repository) LockLoginAttempts(ctx context.Context, subject string, until time.Time) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(loginAttemptsBucket).Get([]byte(subject)) == nil {
+			return nil
+		}
+		return updateLoginAttempts(tx, subject, func(attempts *storedLoginAttempts) {
+			if attempts.LockedUntil == nil || attempts.LockedUntil.Before(until) {
+				attempts.LockedUntil = &until
+			}
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to lock login attempts: %w", err)
+	}
+	return nil
+}
+
+// Время окончания самой долгой действующей блокировки из subjects. Нулевое
+// время — блокировок нет
+func (r *Repository) LoginLockedUntil(ctx context.Context, subjects []string, now time.Time) (time.Time, error) {
+	var until time.Time
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(loginAttemptsBucket)
+		for _, subject := range subjects {
+			value := bucket.Get([]byte(subject))
+			if value == nil {
+				continue
+			}
+			var attempts storedLoginAttempts
+			if err := json.Unmarshal(value, &attempts); err != nil {
+				return fmt.Errorf("failed to decode login attempts: %w", err)
+			}
+			if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) && attempts.LockedUntil.After(until) {
+				until = *attempts.LockedUntil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check login lockout: %w", err)
+	}
+	return until, nil
+}
+
+func (r *Repository) DeleteLoginAttempts(ctx context.Context, subject string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(loginAttemptsBucket).Delete([]byte(subject))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+	return nil
+}
+
+// Удаление счетчиков, которые обнулились бы при следующей неудаче
+func (r *Repository) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(loginAttemptsBucket)
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var attempts storedLoginAttempts
+			if err := json.Unmarshal(v, &attempts); err != nil {
+				return fmt.Errorf("failed to decode login attempts: %w", err)
+			}
+			if attempts.lastActivity().Before(before) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired login attempts: %w", err)
+	}
+	return deleted, nil
+}
+
+// Чтение, изменение и запись счетчика. Отсутствующий счетчик создается
+func updateLoginAttempts(tx *bolt.Tx, subject string, fn func(attempts *storedLoginAttempts)) error {
+	bucket := tx.Bucket(loginAttemptsBucket)
+
+	var attempts storedLoginAttempts
+	if value := bucket.Get([]byte(subject)); value != nil {
+		if err := json.Unmarshal(value, &attempts); err != nil {
+			return fmt.Errorf("failed to decode login attempts: %w", err)
+		}
+	}
+	fn(&attempts)
+
+	value, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(subject), value)
+}
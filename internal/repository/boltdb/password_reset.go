@@ -0,0 +1,169 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// storedPasswordResetToken токен сброса пароля в базе. Ключ записи — хеш токена
+type storedPasswordResetToken struct {
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Замена хеша пароля пользователя
+func (r *Repository) SetPassword(ctx context.Context, userID uint, passwordHash string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			user.Password = passwordHash
+			user.UpdatedAt = time.Now()
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set password of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *Repository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(storedPasswordResetToken{
+			UserID:    token.UserID,
+			ExpiresAt: token.ExpiresAt,
+			CreatedAt: token.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(passwordResetTokensBucket).Put([]byte(token.TokenHash), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+	return nil
+}
+
+// Количество токенов сброса пароля, выпущенных пользователю после since
+func (r *Repository) CountPasswordResetTokens(ctx context.Context, userID uint, since time.Time) (int, error) {
+	var count int
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return forEachPasswordResetToken(tx, func(_ []byte, token *storedPasswordResetToken) error {
+			if token.UserID == userID && token.CreatedAt.After(since) {
+				count++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count password reset tokens: %w", err)
+	}
+	return count, nil
+}
+
+// Использование действующего токена сброса пароля. Токен удаляется
+func (r *Repository) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	var token *models.PasswordResetToken
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(passwordResetTokensBucket)
+		value := bucket.Get([]byte(tokenHash))
+		if value == nil {
+			return models.ErrPasswordResetTokenNotFound
+		}
+
+		var stored storedPasswordResetToken
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return fmt.Errorf("failed to decode password reset token: %w", err)
+		}
+		if !stored.ExpiresAt.After(now) {
+			return models.ErrPasswordResetTokenNotFound
+		}
+		if err := bucket.Delete([]byte(tokenHash)); err != nil {
+			return err
+		}
+
+		token = &models.PasswordResetToken{
+			UserID:    stored.UserID,
+			TokenHash: tokenHash,
+			ExpiresAt: stored.ExpiresAt,
+			CreatedAt: stored.CreatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrPasswordResetTokenNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	return token, nil
+}
+
+// Удаление всех токенов сброса пароля пользователя
+func (r *Repository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	_, err := r.deletePasswordResetTokens(func(token *storedPasswordResetToken) bool {
+		return token.UserID == userID
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteExpiredPasswordResetTokens(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.deletePasswordResetTokens(func(token *storedPasswordResetToken) bool {
+		return !token.ExpiresAt.After(before)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired password reset tokens: %w", err)
+	}
+	return deleted, nil
+}
+
+// Удаление токенов, для которых match возвращает true. Токенов немного,
+// поэтому бакет просматривается целиком
+func (r *Repository) deletePasswordResetTokens(match func(token *storedPasswordResetToken) bool) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		err := forEachPasswordResetToken(tx, func(k []byte, token *storedPasswordResetToken) error {
+			if match(token) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket(passwordResetTokensBucket)
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func forEachPasswordResetToken(tx *bolt.Tx, fn func(k []byte, token *storedPasswordResetToken) error) error {
+	return tx.Bucket(passwordResetTokensBucket).ForEach(func(k, v []byte) error {
+		var token storedPasswordResetToken
+		if err := json.Unmarshal(v, &token); err != nil {
+			return fmt.Errorf("failed to decode password reset token: %w", err)
+		}
+		return fn(k, &token)
+	})
+}
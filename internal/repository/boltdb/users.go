@@ -0,0 +1,877 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"tages/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	errUserNotFound         = models.ErrUserNotFound
+	errUserEmailExists      = errors.New("user with this email already exists")
+	errRefreshTokenNotFound = errors.New("refresh token not found")
+)
+
+// Методы для работы с пользователями
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		byEmail := tx.Bucket(usersByEmailBucket)
+		if byEmail.Get([]byte(user.Email)) != nil {
+			return errUserEmailExists
+		}
+
+		users := tx.Bucket(usersBucket)
+		id, err := users.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		stored := *user
+		stored.ID = uint(id)
+		value, err := json.Marshal(newStoredUser(&stored))
+		if err != nil {
+			return err
+		}
+		if err := users.Put(idKey(id), value); err != nil {
+			return err
+		}
+		if err := byEmail.Put([]byte(user.Email), idKey(id)); err != nil {
+			return err
+		}
+
+		user.ID = stored.ID
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user *models.User
+	err := r.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(usersByEmailBucket).Get([]byte(email))
+		if id == nil {
+			return errUserNotFound
+		}
+		var err error
+		user, err = getUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return user, nil
+}
+
+func (r *Repository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user *models.User
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		user, err = getUser(tx, idKey(uint64(id)))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	return user, nil
+}
+
+// storedUser пользователь в базе. Пароль и служебные поля models.User
+// не сериализуются в JSON, поэтому хранятся отдельными полями
+type storedUser struct {
+	models.User
+	Password         string     `json:"password"`
+	TokensValidAfter *time.Time `json:"tokens_valid_after,omitempty"`
+	TokenVersion     int        `json:"token_version,omitempty"`
+	TOTPSecret       string     `json:"totp_secret,omitempty"`
+	TOTPLastStep     int64      `json:"totp_last_step,omitempty"`
+	RecoveryCodes    []string   `json:"recovery_codes,omitempty"`
+
+	// Заменяет поле models.User: у записей, созданных до подтверждения email,
+	// поля нет, и такие пользователи считаются подтвержденными
+	EmailVerified      *bool      `json:"email_verified,omitempty"`
+	VerificationSentAt *time.Time `json:"verification_sent_at,omitempty"`
+}
+
+// Запись пользователя со служебными полями
+func newStoredUser(user *models.User) storedUser {
+	emailVerified := user.EmailVerified
+	return storedUser{
+		User:               *user,
+		Password:           user.Password,
+		TokensValidAfter:   user.TokensValidAfter,
+		TokenVersion:       user.TokenVersion,
+		TOTPSecret:         user.TOTPSecret,
+		TOTPLastStep:       user.TOTPLastStep,
+		RecoveryCodes:      user.RecoveryCodes,
+		EmailVerified:      &emailVerified,
+		VerificationSentAt: user.VerificationSentAt,
+	}
+}
+
+func getUser(tx *bolt.Tx, key []byte) (*models.User, error) {
+	value := tx.Bucket(usersBucket).Get(key)
+	if value == nil {
+		return nil, errUserNotFound
+	}
+
+	var stored storedUser
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode user: %w", err)
+	}
+	stored.User.Password = stored.Password
+	stored.User.TokensValidAfter = stored.TokensValidAfter
+	stored.User.TokenVersion = stored.TokenVersion
+	stored.User.TOTPSecret = stored.TOTPSecret
+	stored.User.TOTPLastStep = stored.TOTPLastStep
+	stored.User.RecoveryCodes = stored.RecoveryCodes
+	stored.User.EmailVerified = stored.EmailVerified == nil || *stored.EmailVerified
+	stored.User.VerificationSentAt = stored.VerificationSentAt
+	// Пользователи, созданные до появления ролей
+	if stored.User.Role == "" {
+		stored.User.Role = models.RoleMember
+	}
+	return &stored.User, nil
+}
+
+// Изменение записи пользователя внутри транзакции
+func updateUser(tx *bolt.Tx, id uint, update func(user *models.User)) error {
+	key := idKey(uint64(id))
+	user, err := getUser(tx, key)
+	if err != nil {
+		return err
+	}
+
+	update(user)
+	value, err := json.Marshal(newStoredUser(user))
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(usersBucket).Put(key, value)
+}
+
+// Изменение записи пользователя, если update вернул true. Иначе
+// поведение совпадает с условным UPDATE в Postgres: запись не найдена
+func updateUserIf(tx *bolt.Tx, id uint, update func(user *models.User) bool) error {
+	applied := true
+	err := updateUser(tx, id, func(user *models.User) {
+		applied = update(user)
+	})
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return errUserNotFound
+	}
+	return nil
+}
+
+// Страница списка пользователей в порядке регистрации
+func (r *Repository) GetUsers(ctx context.Context, limit, offset int) (*models.UserPage, error) {
+	page := &models.UserPage{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		page.Total = bucket.Stats().KeyN
+
+		c := bucket.Cursor()
+		skipped := 0
+		for k, _ := c.First(); k != nil && len(page.Users) < limit; k, _ = c.Next() {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			user, err := getUser(tx, k)
+			if err != nil {
+				return err
+			}
+			page.Users = append(page.Users, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return page, nil
+}
+
+// Назначение роли пользователю
+func (r *Repository) SetUserRole(ctx context.Context, userID uint, role string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			user.Role = role
+			user.UpdatedAt = time.Now()
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set role of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Отзыв всех выпущенных токенов пользователя: новое поколение токенов и момент отзыва
+func (r *Repository) RevokeUserTokens(ctx context.Context, userID uint, revokedAt time.Time) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			user.TokensValidAfter = &revokedAt
+			user.TokenVersion++
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Отметка email пользователя как подтвержденного
+func (r *Repository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			user.EmailVerified = true
+			user.UpdatedAt = time.Now()
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to mark email of user %d verified: %w", userID, err)
+	}
+	return nil
+}
+
+// Отметка об отправке письма для подтверждения. false — email уже подтвержден
+// или предыдущее письмо отправлено позже resendAfter
+func (r *Repository) MarkVerificationSent(ctx context.Context, userID uint, sentAt, resendAfter time.Time) (bool, error) {
+	var marked bool
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			if user.EmailVerified || (user.VerificationSentAt != nil && user.VerificationSentAt.After(resendAfter)) {
+				return
+			}
+			user.VerificationSentAt = &sentAt
+			marked = true
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark verification sent for user %d: %w", userID, err)
+	}
+	return marked, nil
+}
+
+// Сохранение секрета TOTP до подтверждения. Пока двухфакторная аутентификация
+// включена, секрет не заменяется
+func (r *Repository) SetTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUserIf(tx, userID, func(user *models.User) bool {
+			if user.MFAEnabled {
+				return false
+			}
+			user.TOTPSecret = secret
+			user.UpdatedAt = time.Now()
+			return true
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set totp secret of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Включение двухфакторной аутентификации с подтвержденным шагом TOTP и хешами кодов восстановления
+func (r *Repository) EnableMFA(ctx context.Context, userID uint, step int64, recoveryCodes []string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUserIf(tx, userID, func(user *models.User) bool {
+			if user.TOTPSecret == "" || user.MFAEnabled {
+				return false
+			}
+			user.MFAEnabled = true
+			user.TOTPLastStep = step
+			user.RecoveryCodes = recoveryCodes
+			user.UpdatedAt = time.Now()
+			return true
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to enable mfa of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Отключение двухфакторной аутентификации: секрет и коды восстановления удаляются
+func (r *Repository) DisableMFA(ctx context.Context, userID uint) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			user.MFAEnabled = false
+			user.TOTPSecret = ""
+			user.TOTPLastStep = 0
+			user.RecoveryCodes = nil
+			user.UpdatedAt = time.Now()
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to disable mfa of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Замена кодов восстановления новым набором
+func (r *Repository) SetRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUserIf(tx, userID, func(user *models.User) bool {
+			if !user.MFAEnabled {
+				return false
+			}
+			user.RecoveryCodes = recoveryCodes
+			user.UpdatedAt = time.Now()
+			return true
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set recovery codes of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Использование кода восстановления. false — кода нет или он уже использован
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	var used bool
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			for i, code := range user.RecoveryCodes {
+				if code == codeHash {
+					user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+					used = true
+					return
+				}
+			}
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code of user %d: %w", userID, err)
+	}
+	return used, nil
+}
+
+// Отметка шага TOTP как использованного. false — код этого или более позднего шага уже принят
+func (r *Repository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	var used bool
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return updateUser(tx, userID, func(user *models.User) {
+			if user.TOTPLastStep < step {
+				user.TOTPLastStep = step
+				used = true
+			}
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step of user %d: %w", userID, err)
+	}
+	return used, nil
+}
+
+// Добавление access token в список отозванных до истечения его срока действия
+func (r *Repository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		value, err := expiresAt.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(revokedTokensBucket).Put([]byte(jti), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		revoked = tx.Bucket(revokedTokensBucket).Get([]byte(jti)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Удаление записей об отозванных токенах, срок действия которых истек
+func (r *Repository) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(revokedTokensBucket)
+
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var expiresAt time.Time
+			if err := expiresAt.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to decode revoked token: %w", err)
+			}
+			if !expiresAt.After(before) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+	return deleted, nil
+}
+
+// storedRefreshToken refresh token сессии в базе. Ключ записи — хеш токена
+type storedRefreshToken struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func (t *storedRefreshToken) model() *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		TokenHash:  t.TokenHash,
+		ExpiresAt:  t.ExpiresAt,
+		DeviceName: t.DeviceName,
+		IP:         t.IP,
+		UserAgent:  t.UserAgent,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+// sessionKey ключ индекса сессий: ID пользователя, затем ID сессии
+func sessionKey(userID, sessionID uint) []byte {
+	return append(idKey(uint64(userID)), idKey(uint64(sessionID))...)
+}
+
+// Добавление refresh token новой сессии
+func (r *Repository) StoreRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+		id, err := tokens.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		stored := storedRefreshToken{
+			ID:         uint(id),
+			UserID:     token.UserID,
+			TokenHash:  token.TokenHash,
+			ExpiresAt:  token.ExpiresAt,
+			DeviceName: token.DeviceName,
+			IP:         token.IP,
+			UserAgent:  token.UserAgent,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.CreatedAt,
+		}
+		if err := putRefreshToken(tx, &stored); err != nil {
+			return err
+		}
+
+		token.ID = stored.ID
+		token.LastUsedAt = stored.LastUsedAt
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// Замена refresh token в существующей сессии. Хеш старого токена
+// сохраняется в семействе для обнаружения повторного использования
+func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash string, token *models.RefreshToken) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+
+		stored, err := getRefreshToken(tokens, []byte(oldTokenHash))
+		if err != nil {
+			if errors.Is(err, errRefreshTokenNotFound) {
+				return models.ErrSessionNotFound
+			}
+			return err
+		}
+		if !stored.ExpiresAt.After(token.LastUsedAt) {
+			return models.ErrSessionNotFound
+		}
+		if err := tokens.Delete([]byte(oldTokenHash)); err != nil {
+			return err
+		}
+		if err := putRotatedRefreshToken(tx, stored, oldTokenHash); err != nil {
+			return err
+		}
+
+		stored.TokenHash = token.TokenHash
+		stored.ExpiresAt = token.ExpiresAt
+		stored.IP = token.IP
+		stored.UserAgent = token.UserAgent
+		stored.LastUsedAt = token.LastUsedAt
+		if err := putRefreshToken(tx, stored); err != nil {
+			return err
+		}
+
+		*token = *stored.model()
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return nil
+}
+
+// Удаление refresh token при выходе из системы
+func (r *Repository) DeleteRefreshToken(ctx context.Context, tokenHash string) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		stored, err := getRefreshToken(tx.Bucket(refreshTokensBucket), []byte(tokenHash))
+		if err != nil {
+			if errors.Is(err, errRefreshTokenNotFound) {
+				return nil
+			}
+			return err
+		}
+		return deleteRefreshToken(tx, stored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete refresh token: %w", err)
+	}
+	return nil
+}
+
+// Получение действующего refresh token по хешу
+func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var stored *storedRefreshToken
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		stored, err = getRefreshToken(tx.Bucket(refreshTokensBucket), []byte(tokenHash))
+		return err
+	})
+	if err == nil && !stored.ExpiresAt.After(time.Now()) {
+		err = errRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return stored.model(), nil
+}
+
+// Сессия, в которой токен с этим хешем уже был заменен
+func (r *Repository) GetRotatedRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var stored *storedRefreshToken
+	err := r.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(rotatedTokensBucket).Get([]byte(tokenHash))
+		if key == nil {
+			return models.ErrSessionNotFound
+		}
+		current := tx.Bucket(sessionsByUserBucket).Get(key)
+		if current == nil {
+			return models.ErrSessionNotFound
+		}
+		var err error
+		stored, err = getRefreshToken(tx.Bucket(refreshTokensBucket), current)
+		if errors.Is(err, errRefreshTokenNotFound) {
+			return models.ErrSessionNotFound
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get rotated refresh token: %w", err)
+	}
+	return stored.model(), nil
+}
+
+// Действующие сессии пользователя, последние использованные первыми
+func (r *Repository) GetUserSessions(ctx context.Context, userID uint, now time.Time) ([]*models.RefreshToken, error) {
+	var sessions []*models.RefreshToken
+	err := r.db.View(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+		prefix := idKey(uint64(userID))
+
+		c := tx.Bucket(sessionsByUserBucket).Cursor()
+		for k, token := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, token = c.Next() {
+			stored, err := getRefreshToken(tokens, token)
+			if err != nil {
+				if errors.Is(err, errRefreshTokenNotFound) {
+					continue
+				}
+				return err
+			}
+			if stored.ExpiresAt.After(now) {
+				sessions = append(sessions, stored.model())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions of user %d: %w", userID, err)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// Завершение одной сессии пользователя
+func (r *Repository) DeleteUserSession(ctx context.Context, userID, sessionID uint) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		token := tx.Bucket(sessionsByUserBucket).Get(sessionKey(userID, sessionID))
+		if token == nil {
+			return models.ErrSessionNotFound
+		}
+		stored, err := getRefreshToken(tx.Bucket(refreshTokensBucket), token)
+		if err != nil {
+			return err
+		}
+		return deleteRefreshToken(tx, stored)
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete session %d: %w", sessionID, err)
+	}
+	return nil
+}
+
+// Завершение всех сессий пользователя, кроме exceptID (0 — завершить все)
+func (r *Repository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+		prefix := idKey(uint64(userID))
+		except := sessionKey(userID, exceptID)
+
+		// Сессии собираем заранее: удаление во время обхода сбивает курсор
+		var sessions []*storedRefreshToken
+		c := tx.Bucket(sessionsByUserBucket).Cursor()
+		for k, token := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, token = c.Next() {
+			if exceptID != 0 && bytes.Equal(k, except) {
+				continue
+			}
+			stored, err := getRefreshToken(tokens, token)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, stored)
+		}
+
+		for _, stored := range sessions {
+			if err := deleteRefreshToken(tx, stored); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions of user %d: %w", userID, err)
+	}
+	return deleted, nil
+}
+
+// Удаление сессий, срок действия которых истек
+func (r *Repository) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var expired []*storedRefreshToken
+		err := tx.Bucket(refreshTokensBucket).ForEach(func(k, v []byte) error {
+			var stored storedRefreshToken
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return fmt.Errorf("failed to decode refresh token: %w", err)
+			}
+			if !stored.ExpiresAt.After(before) {
+				expired = append(expired, &stored)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, stored := range expired {
+			if err := deleteRefreshToken(tx, stored); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+	return deleted, nil
+}
+
+func getRefreshToken(bucket *bolt.Bucket, tokenHash []byte) (*storedRefreshToken, error) {
+	value := bucket.Get(tokenHash)
+	if value == nil {
+		return nil, errRefreshTokenNotFound
+	}
+
+	var stored storedRefreshToken
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode refresh token: %w", err)
+	}
+	return &stored, nil
+}
+
+// Запись токена и индекса сессий пользователя
+func putRefreshToken(tx *bolt.Tx, stored *storedRefreshToken) error {
+	value, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(refreshTokensBucket).Put([]byte(stored.TokenHash), value); err != nil {
+		return err
+	}
+	return tx.Bucket(sessionsByUserBucket).Put(sessionKey(stored.UserID, stored.ID), []byte(stored.TokenHash))
+}
+
+// Запоминание замененного токена семейства
+func putRotatedRefreshToken(tx *bolt.Tx, stored *storedRefreshToken, tokenHash string) error {
+	key := sessionKey(stored.UserID, stored.ID)
+	if err := tx.Bucket(rotatedTokensBucket).Put([]byte(tokenHash), key); err != nil {
+		return err
+	}
+	return tx.Bucket(rotatedBySessionBucket).Put(append(key, tokenHash...), nil)
+}
+
+// Удаление сессии вместе с замененными токенами ее семейства
+func deleteRefreshToken(tx *bolt.Tx, stored *storedRefreshToken) error {
+	key := sessionKey(stored.UserID, stored.ID)
+	if err := tx.Bucket(refreshTokensBucket).Delete([]byte(stored.TokenHash)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(sessionsByUserBucket).Delete(key); err != nil {
+		return err
+	}
+
+	rotated := tx.Bucket(rotatedTokensBucket)
+	bySession := tx.Bucket(rotatedBySessionBucket)
+
+	var keys [][]byte
+	c := bySession.Cursor()
+	for k, _ := c.Seek(key); k != nil && bytes.HasPrefix(k, key); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := rotated.Delete(k[len(key):]); err != nil {
+			return err
+		}
+		if err := bySession.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSessionsIndex переносит токены из индекса прежнего формата
+// (один токен на пользователя) в индекс сессий
+func migrateSessionsIndex(tx *bolt.Tx) error {
+	if tx.Bucket(tokensByUserBucket) == nil {
+		return nil
+	}
+
+	err := tx.Bucket(refreshTokensBucket).ForEach(func(k, v []byte) error {
+		var stored storedRefreshToken
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return fmt.Errorf("failed to decode refresh token: %w", err)
+		}
+		return tx.Bucket(sessionsByUserBucket).Put(sessionKey(stored.UserID, stored.ID), k)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate sessions index: %w", err)
+	}
+	return tx.DeleteBucket(tokensByUserBucket)
+}
+
+// migrateRefreshTokenHashes заменяет токены, сохраненные в открытом виде,
+// их хешами. Алгоритм совпадает с хешированием токенов в usecase: SHA-256 в hex
+func migrateRefreshTokenHashes(tx *bolt.Tx) error {
+	tokens := tx.Bucket(refreshTokensBucket)
+
+	var legacy []*storedRefreshToken
+	var keys [][]byte
+	err := tokens.ForEach(func(k, v []byte) error {
+		var stored storedRefreshToken
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return fmt.Errorf("failed to decode refresh token: %w", err)
+		}
+		if stored.TokenHash == "" {
+			sum := sha256.Sum256(k)
+			stored.TokenHash = hex.EncodeToString(sum[:])
+			legacy = append(legacy, &stored)
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate refresh token hashes: %w", err)
+	}
+
+	for i, stored := range legacy {
+		if err := tokens.Delete(keys[i]); err != nil {
+			return err
+		}
+		if err := putRefreshToken(tx, stored); err != nil {
+			return err
+		}
+	}
+	return nil
+}
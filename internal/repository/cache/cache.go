@@ -0,0 +1,161 @@
+// Package cache содержит кэширующие обертки над репозиториями метаданных.
+//
+// Записи через обертку сразу сбрасывают локальный кэш и рассылают инвалидацию
+// остальным экземплярам через PubSub (NOTIFY в PostgreSQL)
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+)
+
+const invalidationChannel = "cache_invalidation"
+
+// Виды инвалидируемых записей
+const (
+	kindFile         = "file"
+	kindUser         = "user"
+	kindRevokedToken = "revoked_token"
+)
+
+type Config struct {
+	Size int           // максимальное число записей в каждом кэше
+	TTL  time.Duration // время жизни записи
+}
+
+// Cache хранит кэши метаданных файлов и пользователей одного экземпляра
+type Cache struct {
+	pubsub usecase.PubSub
+	origin string
+
+	files  *lru[string, *models.FileMeta]
+	exists *lru[string, bool]
+	lists  *lru[string, []*models.FileMeta]
+	users  *lru[uint, *models.User]
+
+	// Результаты проверки access токенов по списку отозванных, по jti
+	revoked *lru[string, bool]
+}
+
+type invalidation struct {
+	Origin string `json:"origin"`
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+}
+
+// New создает кэш. Если pubsub равен nil, инвалидация выполняется только локально
+func New(cfg Config, pubsub usecase.PubSub) *Cache {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+
+	return &Cache{
+		pubsub: pubsub,
+		origin: hex.EncodeToString(origin),
+		files:  newLRU[string, *models.FileMeta](cfg.Size, cfg.TTL),
+		exists: newLRU[string, bool](cfg.Size, cfg.TTL),
+		lists:  newLRU[string, []*models.FileMeta](cfg.Size, cfg.TTL),
+		users:  newLRU[uint, *models.User](cfg.Size, cfg.TTL),
+
+		revoked: newLRU[string, bool](cfg.Size, cfg.TTL),
+	}
+}
+
+// Run слушает инвалидации других экземпляров до отмены контекста
+func (c *Cache) Run(ctx context.Context) {
+	if c.pubsub == nil {
+		return
+	}
+	// Уведомления, пришедшие во время обрыва соединения, потеряны — сбрасываем все
+	c.pubsub.Listen(ctx, invalidationChannel, c.purge, c.handleNotification)
+}
+
+// Stats возвращает счетчики попаданий и промахов всех кэшей
+func (c *Cache) Stats() []models.CacheStats {
+	return []models.CacheStats{
+		c.files.stats("file_meta"),
+		c.exists.stats("file_exists"),
+		c.lists.stats("file_lists"),
+		c.users.stats("users"),
+		c.revoked.stats("revoked_tokens"),
+	}
+}
+
+func (c *Cache) purge() {
+	c.files.purge()
+	c.exists.purge()
+	c.lists.purge()
+	c.users.purge()
+	c.revoked.purge()
+}
+
+func (c *Cache) invalidateFile(ctx context.Context, filename string) {
+	c.dropFile(filename)
+	c.broadcast(ctx, kindFile, filename)
+}
+
+func (c *Cache) dropFile(filename string) {
+	c.files.remove(filename)
+	c.exists.remove(filename)
+	// Изменение любого файла может повлиять на любой список
+	c.lists.purge()
+}
+
+func (c *Cache) invalidateUser(ctx context.Context, id uint) {
+	c.users.remove(id)
+	c.broadcast(ctx, kindUser, strconv.FormatUint(uint64(id), 10))
+}
+
+func (c *Cache) invalidateRevokedToken(ctx context.Context, jti string) {
+	c.revoked.remove(jti)
+	c.broadcast(ctx, kindRevokedToken, jti)
+}
+
+func (c *Cache) broadcast(ctx context.Context, kind, key string) {
+	if c.pubsub == nil {
+		return
+	}
+
+	payload, err := json.Marshal(invalidation{Origin: c.origin, Kind: kind, Key: key})
+	if err != nil {
+		log.Printf("ERROR: Failed to encode cache invalidation: %v", err)
+		return
+	}
+	if err := c.pubsub.Notify(ctx, invalidationChannel, string(payload)); err != nil {
+		log.Printf("ERROR: Failed to broadcast cache invalidation for %s %s: %v", kind, key, err)
+	}
+}
+
+func (c *Cache) handleNotification(payload string) {
+	var msg invalidation
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("ERROR: Failed to decode cache invalidation: %v", err)
+		return
+	}
+	if msg.Origin == c.origin {
+		return
+	}
+
+	switch msg.Kind {
+	case kindFile:
+		c.dropFile(msg.Key)
+	case kindUser:
+		id, err := strconv.ParseUint(msg.Key, 10, 64)
+		if err != nil {
+			log.Printf("ERROR: Invalid user id in cache invalidation: %q", msg.Key)
+			return
+		}
+		c.users.remove(uint(id))
+	case kindRevokedToken:
+		c.revoked.remove(msg.Key)
+	default:
+		log.Printf("WARN: Unknown cache invalidation kind %q", msg.Kind)
+	}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+)
+
+// FileRepository кэширует чтение метаданных файлов.
+// Методы, не переопределенные здесь, обращаются к репозиторию напрямую
+type FileRepository struct {
+	usecase.Repository
+	cache *Cache
+}
+
+// Files оборачивает репозиторий файлов кэшем
+func (c *Cache) Files(r usecase.Repository) *FileRepository {
+	return &FileRepository{Repository: r, cache: c}
+}
+
+func (r *FileRepository) IsFileExists(ctx context.Context, filename string) (bool, error) {
+	exists, gen, ok := r.cache.exists.get(filename)
+	if ok {
+		return exists, nil
+	}
+
+	exists, err := r.Repository.IsFileExists(ctx, filename)
+	if err != nil {
+		return false, err
+	}
+	r.cache.exists.put(filename, exists, gen)
+	return exists, nil
+}
+
+func (r *FileRepository) GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error) {
+	file, gen, ok := r.cache.files.get(filename)
+	if ok {
+		return cloneFileMeta(file), nil
+	}
+
+	file, err := r.Repository.GetFileMeta(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.files.put(filename, cloneFileMeta(file), gen)
+	return file, nil
+}
+
+func (r *FileRepository) GetFilesMeta(ctx context.Context, filter *models.FileFilter) ([]*models.FileMeta, error) {
+	key, err := filterKey(filter)
+	if err != nil {
+		return r.Repository.GetFilesMeta(ctx, filter)
+	}
+
+	files, gen, ok := r.cache.lists.get(key)
+	if ok {
+		return cloneFileList(files), nil
+	}
+
+	files, err = r.Repository.GetFilesMeta(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.lists.put(key, cloneFileList(files), gen)
+	return files, nil
+}
+
+func (r *FileRepository) SaveFileMeta(ctx context.Context, file *models.FileMeta) error {
+	defer r.cache.invalidateFile(ctx, file.Name)
+	return r.Repository.SaveFileMeta(ctx, file)
+}
+
+func (r *FileRepository) UpdateFileMeta(ctx context.Context, file *models.FileMeta) error {
+	defer r.cache.invalidateFile(ctx, file.Name)
+	return r.Repository.UpdateFileMeta(ctx, file)
+}
+
+func (r *FileRepository) UpdateFileMetadata(ctx context.Context, file *models.FileMeta) error {
+	defer r.cache.invalidateFile(ctx, file.Name)
+	return r.Repository.UpdateFileMetadata(ctx, file)
+}
+
+func (r *FileRepository) DeleteFileMeta(ctx context.Context, filename string) error {
+	defer r.cache.invalidateFile(ctx, filename)
+	return r.Repository.DeleteFileMeta(ctx, filename)
+}
+
+func (r *FileRepository) RenameFileMeta(ctx context.Context, from, to string, updatedAt time.Time) error {
+	defer r.cache.invalidateFile(ctx, to)
+	defer r.cache.invalidateFile(ctx, from)
+	return r.Repository.RenameFileMeta(ctx, from, to, updatedAt)
+}
+
+// WithFileMutex сбрасывает локальные записи файлов после захвата: под мьютексом
+// условия записи проверяются по актуальным данным, а не по кэшу, который мог
+// еще не получить инвалидацию от другого экземпляра. После фиксации записи
+// сбрасываются снова: во время операции в кэш могли попасть еще не
+// зафиксированные или уже устаревшие данные
+func (r *FileRepository) WithFileMutex(ctx context.Context, filenames []string, fn func(ctx context.Context) error) error {
+	defer r.dropFiles(filenames)
+	return r.Repository.WithFileMutex(ctx, filenames, func(ctx context.Context) error {
+		r.dropFiles(filenames)
+		return fn(ctx)
+	})
+}
+
+func (r *FileRepository) dropFiles(filenames []string) {
+	for _, filename := range filenames {
+		r.cache.dropFile(filename)
+	}
+}
+
+// filterKey строит ключ кэша списка; json сортирует ключи атрибутов
+func filterKey(filter *models.FileFilter) (string, error) {
+	if filter.IsEmpty() {
+		return "", nil
+	}
+	tags := slices.Clone(filter.Tags)
+	slices.Sort(tags)
+
+	key, err := json.Marshal(models.FileFilter{Tags: tags, Attributes: filter.Attributes})
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// Вызывающий код может изменять полученные структуры, поэтому кэш хранит копии
+func cloneFileMeta(file *models.FileMeta) *models.FileMeta {
+	clone := *file
+	clone.Tags = slices.Clone(file.Tags)
+	clone.Attributes = maps.Clone(file.Attributes)
+	return &clone
+}
+
+func cloneFileList(files []*models.FileMeta) []*models.FileMeta {
+	clone := make([]*models.FileMeta, len(files))
+	for i, file := range files {
+		clone[i] = cloneFileMeta(file)
+	}
+	return clone
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/usecase"
+)
+
+// UserRepository кэширует чтение пользователей по ID и проверку отзыва access токенов.
+// Методы, не переопределенные здесь, обращаются к репозиторию напрямую
+type UserRepository struct {
+	usecase.UserRepository
+	cache *Cache
+}
+
+// Users оборачивает репозиторий пользователей кэшем
+func (c *Cache) Users(r usecase.UserRepository) *UserRepository {
+	return &UserRepository{UserRepository: r, cache: c}
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	user, gen, ok := r.cache.users.get(id)
+	if ok {
+		clone := *user
+		return &clone, nil
+	}
+
+	user, err := r.UserRepository.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	clone := *user
+	r.cache.users.put(id, &clone, gen)
+	return user, nil
+}
+
+func (r *UserRepository) RevokeUserTokens(ctx context.Context, userID uint, revokedAt time.Time) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.RevokeUserTokens(ctx, userID, revokedAt)
+}
+
+func (r *UserRepository) SetUserRole(ctx context.Context, userID uint, role string) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.SetUserRole(ctx, userID, role)
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.MarkEmailVerified(ctx, userID)
+}
+
+func (r *UserRepository) MarkVerificationSent(ctx context.Context, userID uint, sentAt, resendAfter time.Time) (bool, error) {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.MarkVerificationSent(ctx, userID, sentAt, resendAfter)
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, userID uint, passwordHash string) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.SetPassword(ctx, userID, passwordHash)
+}
+
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.SetTOTPSecret(ctx, userID, secret)
+}
+
+func (r *UserRepository) EnableMFA(ctx context.Context, userID uint, step int64, recoveryCodes []string) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.EnableMFA(ctx, userID, step, recoveryCodes)
+}
+
+func (r *UserRepository) DisableMFA(ctx context.Context, userID uint) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.DisableMFA(ctx, userID)
+}
+
+func (r *UserRepository) SetRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.SetRecoveryCodes(ctx, userID, recoveryCodes)
+}
+
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.UseRecoveryCode(ctx, userID, codeHash)
+}
+
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	defer r.cache.invalidateUser(ctx, userID)
+	return r.UserRepository.UseTOTPStep(ctx, userID, step)
+}
+
+func (r *UserRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, gen, ok := r.cache.revoked.get(jti)
+	if ok {
+		return revoked, nil
+	}
+
+	revoked, err := r.UserRepository.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	r.cache.revoked.put(jti, revoked, gen)
+	return revoked, nil
+}
+
+func (r *UserRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	defer r.cache.invalidateRevokedToken(ctx, jti)
+	return r.UserRepository.RevokeAccessToken(ctx, jti, expiresAt)
+}
@@ -0,0 +1,639 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+	"tages/internal/repository/boltdb"
+	"tages/internal/repository/pg"
+	"tages/internal/usecase"
+	"tages/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Строка подключения к тестовой базе PostgreSQL. Без нее проверки pg пропускаются.
+// Все таблицы базы очищаются перед каждым случаем
+const pgDSNEnv = "TAGES_TEST_PG_DSN"
+
+// Хранилище метаданных, общее для обеих реализаций
+type metadataRepository interface {
+	usecase.Repository
+	usecase.UserRepository
+}
+
+type backend struct {
+	name string
+	open func(t *testing.T) metadataRepository
+}
+
+var backends = []backend{
+	{name: "bolt", open: openBolt},
+	{name: "pg", open: openPG},
+}
+
+func openBolt(t *testing.T) metadataRepository {
+	repo, err := boltdb.New(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("open bolt: %v", err)
+	}
+	t.Cleanup(repo.Close)
+	return repo
+}
+
+func openPG(t *testing.T) metadataRepository {
+	dsn := os.Getenv(pgDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", pgDSNEnv)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := pg.Migrate(ctx, pool, migrations.FS, pg.MigrateAuto); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		DO $$
+		DECLARE t text;
+		BEGIN
+			FOR t IN SELECT tablename FROM pg_tables
+				WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+			LOOP
+				EXECUTE format('TRUNCATE %I RESTART IDENTITY CASCADE', t);
+			END LOOP;
+		END $$`)
+	if err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	return pg.New(pool)
+}
+
+var conformanceCases = []struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, repo metadataRepository)
+}{
+	{name: "file not found", run: testFileNotFound},
+	{name: "save and get file", run: testSaveAndGetFile},
+	{name: "save keeps metadata and owner", run: testSaveKeepsMetadata},
+	{name: "filter files", run: testFilterFiles},
+	{name: "update file metadata", run: testUpdateFileMetadata},
+	{name: "rename file", run: testRenameFile},
+	{name: "delete file", run: testDeleteFile},
+	{name: "file mutex", run: testFileMutex},
+	{name: "file mutex writes", run: testFileMutexWrites},
+	{name: "create and get user", run: testCreateAndGetUser},
+	{name: "user not found", run: testUserNotFound},
+	{name: "duplicate email", run: testDuplicateEmail},
+	{name: "set user role", run: testSetUserRole},
+	{name: "refresh token rotation", run: testRefreshTokenRotation},
+	{name: "user sessions", run: testUserSessions},
+	{name: "login attempts", run: testLoginAttempts},
+}
+
+// TestConformance проверяет, что bolt и pg одинаково ведут себя в сценариях,
+// на которые опираются usecase
+func TestConformance(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			for _, tc := range conformanceCases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(t, context.Background(), b.open(t))
+				})
+			}
+		})
+	}
+}
+
+// Время в UTC без долей секунды: так его без потерь возвращают обе базы
+func testTime(offset time.Duration) time.Time {
+	return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Add(offset)
+}
+
+func saveFile(t *testing.T, ctx context.Context, repo metadataRepository, file *models.FileMeta) {
+	t.Helper()
+	if err := repo.SaveFileMeta(ctx, file); err != nil {
+		t.Fatalf("SaveFileMeta(%s): %v", file.Name, err)
+	}
+}
+
+func createUser(t *testing.T, ctx context.Context, repo metadataRepository, email string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Email:     email,
+		Password:  "hash",
+		Role:      models.RoleMember,
+		CreatedAt: testTime(0),
+		UpdatedAt: testTime(0),
+	}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", email, err)
+	}
+	if user.ID == 0 {
+		t.Fatalf("CreateUser(%s) did not set ID", email)
+	}
+	return user
+}
+
+func fileNames(files []*models.FileMeta) []string {
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func testFileNotFound(t *testing.T, ctx context.Context, repo metadataRepository) {
+	if _, err := repo.GetFileMeta(ctx, "missing.txt"); !errors.Is(err, models.ErrFileNotFound) {
+		t.Errorf("GetFileMeta: got %v, want ErrFileNotFound", err)
+	}
+	exists, err := repo.IsFileExists(ctx, "missing.txt")
+	if err != nil || exists {
+		t.Errorf("IsFileExists = %v, %v; want false, nil", exists, err)
+	}
+	err = repo.UpdateFileMetadata(ctx, &models.FileMeta{Name: "missing.txt", UpdatedAt: testTime(0), ETag: "e"})
+	if !errors.Is(err, models.ErrFileNotFound) {
+		t.Errorf("UpdateFileMetadata: got %v, want ErrFileNotFound", err)
+	}
+	if err := repo.DeleteFileMeta(ctx, "missing.txt"); err != nil {
+		t.Errorf("DeleteFileMeta of missing file: %v", err)
+	}
+}
+
+func testSaveAndGetFile(t *testing.T, ctx context.Context, repo metadataRepository) {
+	owner := createUser(t, ctx, repo, "ann@example.com")
+	saveFile(t, ctx, repo, &models.FileMeta{
+		Name:       "docs/report.pdf",
+		CreatedAt:  testTime(0),
+		UpdatedAt:  testTime(time.Minute),
+		Tags:       []string{"finance", "q1"},
+		Attributes: map[string]string{"author": "ann"},
+		ETag:       "etag-1",
+		Size:       42,
+		MimeType:   "application/pdf",
+		OwnerID:    owner.ID,
+	})
+
+	exists, err := repo.IsFileExists(ctx, "docs/report.pdf")
+	if err != nil || !exists {
+		t.Fatalf("IsFileExists = %v, %v; want true, nil", exists, err)
+	}
+
+	file, err := repo.GetFileMeta(ctx, "docs/report.pdf")
+	if err != nil {
+		t.Fatalf("GetFileMeta: %v", err)
+	}
+	if !file.CreatedAt.Equal(testTime(0)) || !file.UpdatedAt.Equal(testTime(time.Minute)) {
+		t.Errorf("times = %v, %v", file.CreatedAt, file.UpdatedAt)
+	}
+	if !slices.Equal(file.Tags, []string{"finance", "q1"}) {
+		t.Errorf("Tags = %v", file.Tags)
+	}
+	if file.Attributes["author"] != "ann" || len(file.Attributes) != 1 {
+		t.Errorf("Attributes = %v", file.Attributes)
+	}
+	if file.ETag != "etag-1" || file.Size != 42 || file.MimeType != "application/pdf" || file.OwnerID != owner.ID {
+		t.Errorf("got ETag %q, Size %d, MimeType %q, OwnerID %d", file.ETag, file.Size, file.MimeType, file.OwnerID)
+	}
+}
+
+// Перезапись содержимого без метаданных не стирает теги, атрибуты и владельца
+func testSaveKeepsMetadata(t *testing.T, ctx context.Context, repo metadataRepository) {
+	owner := createUser(t, ctx, repo, "ann@example.com")
+	other := createUser(t, ctx, repo, "bob@example.com")
+	saveFile(t, ctx, repo, &models.FileMeta{
+		Name:       "a.txt",
+		CreatedAt:  testTime(0),
+		UpdatedAt:  testTime(0),
+		Tags:       []string{"keep"},
+		Attributes: map[string]string{"k": "v"},
+		ETag:       "etag-1",
+		OwnerID:    owner.ID,
+	})
+	saveFile(t, ctx, repo, &models.FileMeta{
+		Name:      "a.txt",
+		CreatedAt: testTime(time.Hour),
+		UpdatedAt: testTime(time.Hour),
+		ETag:      "etag-2",
+		Size:      10,
+		OwnerID:   other.ID,
+	})
+
+	file, err := repo.GetFileMeta(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("GetFileMeta: %v", err)
+	}
+	if !slices.Equal(file.Tags, []string{"keep"}) || file.Attributes["k"] != "v" {
+		t.Errorf("metadata lost: tags %v, attributes %v", file.Tags, file.Attributes)
+	}
+	if file.OwnerID != owner.ID {
+		t.Errorf("OwnerID = %d, want %d", file.OwnerID, owner.ID)
+	}
+	if file.ETag != "etag-2" || file.Size != 10 || !file.UpdatedAt.Equal(testTime(time.Hour)) {
+		t.Errorf("content not updated: ETag %q, Size %d, UpdatedAt %v", file.ETag, file.Size, file.UpdatedAt)
+	}
+}
+
+func testFilterFiles(t *testing.T, ctx context.Context, repo metadataRepository) {
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "a", CreatedAt: testTime(0), UpdatedAt: testTime(0),
+		Tags: []string{"x", "y"}, Attributes: map[string]string{"team": "red"}, ETag: "a"})
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "b", CreatedAt: testTime(0), UpdatedAt: testTime(time.Second),
+		Tags: []string{"x"}, Attributes: map[string]string{"team": "blue"}, ETag: "b"})
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "c", CreatedAt: testTime(0), UpdatedAt: testTime(2 * time.Second),
+		ETag: "c"})
+
+	tests := []struct {
+		filter *models.FileFilter
+		want   []string
+	}{
+		{filter: nil, want: []string{"a", "b", "c"}},
+		{filter: &models.FileFilter{Tags: []string{"x"}}, want: []string{"a", "b"}},
+		{filter: &models.FileFilter{Tags: []string{"x", "y"}}, want: []string{"a"}},
+		{filter: &models.FileFilter{Attributes: map[string]string{"team": "blue"}}, want: []string{"b"}},
+		{filter: &models.FileFilter{Tags: []string{"y"}, Attributes: map[string]string{"team": "blue"}}, want: []string{}},
+	}
+	for _, tt := range tests {
+		files, err := repo.GetFilesMeta(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("GetFilesMeta(%+v): %v", tt.filter, err)
+		}
+		if got := fileNames(files); !slices.Equal(got, tt.want) {
+			t.Errorf("GetFilesMeta(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// Последние измененные первыми
+	files, err := repo.GetFilesMeta(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetFilesMeta: %v", err)
+	}
+	if len(files) != 3 || files[0].Name != "c" || files[2].Name != "a" {
+		t.Errorf("order = %v, want c, b, a", fileNames(files))
+	}
+}
+
+func testUpdateFileMetadata(t *testing.T, ctx context.Context, repo metadataRepository) {
+	owner := createUser(t, ctx, repo, "ann@example.com")
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "a", CreatedAt: testTime(0), UpdatedAt: testTime(0),
+		Tags: []string{"old"}, Attributes: map[string]string{"k": "v"}, ETag: "e1", Size: 5, OwnerID: owner.ID})
+
+	file := &models.FileMeta{Name: "a", Tags: []string{"new"}, UpdatedAt: testTime(time.Minute), ETag: "e2"}
+	if err := repo.UpdateFileMetadata(ctx, file); err != nil {
+		t.Fatalf("UpdateFileMetadata: %v", err)
+	}
+	// Метод возвращает сохраненную запись целиком
+	if file.Size != 5 || file.OwnerID != owner.ID || !file.CreatedAt.Equal(testTime(0)) {
+		t.Errorf("returned file = %+v", file)
+	}
+
+	stored, err := repo.GetFileMeta(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetFileMeta: %v", err)
+	}
+	if !slices.Equal(stored.Tags, []string{"new"}) {
+		t.Errorf("Tags = %v, want [new]", stored.Tags)
+	}
+	if len(stored.Attributes) != 0 {
+		t.Errorf("Attributes = %v, want empty", stored.Attributes)
+	}
+	if stored.ETag != "e2" || !stored.UpdatedAt.Equal(testTime(time.Minute)) {
+		t.Errorf("ETag %q, UpdatedAt %v", stored.ETag, stored.UpdatedAt)
+	}
+}
+
+func testRenameFile(t *testing.T, ctx context.Context, repo metadataRepository) {
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "a", CreatedAt: testTime(0), UpdatedAt: testTime(0),
+		Tags: []string{"t"}, ETag: "a"})
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "b", CreatedAt: testTime(0), UpdatedAt: testTime(0), ETag: "b"})
+
+	if err := repo.RenameFileMeta(ctx, "a", "b", testTime(time.Hour)); !errors.Is(err, models.ErrFileExists) {
+		t.Errorf("rename onto existing file: got %v, want ErrFileExists", err)
+	}
+	if err := repo.RenameFileMeta(ctx, "missing", "c", testTime(time.Hour)); !errors.Is(err, models.ErrFileNotFound) {
+		t.Errorf("rename missing file: got %v, want ErrFileNotFound", err)
+	}
+	if err := repo.RenameFileMeta(ctx, "a", "c", testTime(time.Hour)); err != nil {
+		t.Fatalf("RenameFileMeta: %v", err)
+	}
+
+	if _, err := repo.GetFileMeta(ctx, "a"); !errors.Is(err, models.ErrFileNotFound) {
+		t.Errorf("old name still present: %v", err)
+	}
+	file, err := repo.GetFileMeta(ctx, "c")
+	if err != nil {
+		t.Fatalf("GetFileMeta(c): %v", err)
+	}
+	if !slices.Equal(file.Tags, []string{"t"}) || !file.UpdatedAt.Equal(testTime(time.Hour)) {
+		t.Errorf("renamed file = %+v", file)
+	}
+}
+
+func testDeleteFile(t *testing.T, ctx context.Context, repo metadataRepository) {
+	saveFile(t, ctx, repo, &models.FileMeta{Name: "a", CreatedAt: testTime(0), UpdatedAt: testTime(0), ETag: "a"})
+	if err := repo.DeleteFileMeta(ctx, "a"); err != nil {
+		t.Fatalf("DeleteFileMeta: %v", err)
+	}
+	if _, err := repo.GetFileMeta(ctx, "a"); !errors.Is(err, models.ErrFileNotFound) {
+		t.Errorf("GetFileMeta after delete: got %v, want ErrFileNotFound", err)
+	}
+}
+
+// Второй захват ждет освобождения и прерывается по контексту
+func testFileMutex(t *testing.T, ctx context.Context, repo metadataRepository) {
+	held := make(chan struct{})
+	release := make(chan struct{})
+	holder := make(chan error, 1)
+	go func() {
+		holder <- repo.WithFileMutex(ctx, []string{"a"}, func(ctx context.Context) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+
+	err := repo.WithFileMutex(ctx, []string{"b"}, func(ctx context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("WithFileMutex of another file: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = repo.WithFileMutex(waitCtx, []string{"b", "a"}, func(ctx context.Context) error {
+		t.Error("fn ran while the mutex is held")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("second WithFileMutex succeeded while the mutex is held")
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- repo.WithFileMutex(ctx, []string{"a", "b"}, func(ctx context.Context) error { return nil })
+	}()
+	close(release)
+
+	for _, ch := range []chan error{holder, acquired} {
+		select {
+		case err := <-ch:
+			if err != nil {
+				t.Errorf("WithFileMutex: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("WithFileMutex did not return after release")
+		}
+	}
+}
+
+// Изменения под мьютексом видны после выхода, ошибка fn возвращается вызывающему
+func testFileMutexWrites(t *testing.T, ctx context.Context, repo metadataRepository) {
+	err := repo.WithFileMutex(ctx, []string{"a"}, func(ctx context.Context) error {
+		if _, err := repo.GetFileMeta(ctx, "a"); !errors.Is(err, models.ErrFileNotFound) {
+			t.Errorf("GetFileMeta under mutex: got %v, want ErrFileNotFound", err)
+		}
+		saveFile(t, ctx, repo, &models.FileMeta{Name: "a", CreatedAt: testTime(0), UpdatedAt: testTime(0), ETag: "a"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithFileMutex: %v", err)
+	}
+	if file, err := repo.GetFileMeta(ctx, "a"); err != nil || file.ETag != "a" {
+		t.Errorf("GetFileMeta after commit = %+v, %v", file, err)
+	}
+
+	errAbort := errors.New("abort")
+	err = repo.WithFileMutex(ctx, []string{"a"}, func(ctx context.Context) error { return errAbort })
+	if !errors.Is(err, errAbort) {
+		t.Errorf("WithFileMutex: got %v, want fn error", err)
+	}
+}
+
+func testCreateAndGetUser(t *testing.T, ctx context.Context, repo metadataRepository) {
+	created := createUser(t, ctx, repo, "ann@example.com")
+
+	byEmail, err := repo.GetUserByEmail(ctx, "ann@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	byID, err := repo.GetUserByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	for _, user := range []*models.User{byEmail, byID} {
+		if user.ID != created.ID || user.Email != "ann@example.com" || user.Password != "hash" || user.Role != models.RoleMember {
+			t.Errorf("user = %+v", user)
+		}
+	}
+
+	second := createUser(t, ctx, repo, "bob@example.com")
+	if second.ID == created.ID {
+		t.Errorf("users share ID %d", second.ID)
+	}
+	page, err := repo.GetUsers(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if page.Total != 2 || len(page.Users) != 1 {
+		t.Errorf("GetUsers(1, 1) = total %d, %d users", page.Total, len(page.Users))
+	}
+}
+
+func testUserNotFound(t *testing.T, ctx context.Context, repo metadataRepository) {
+	if _, err := repo.GetUserByID(ctx, 404); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("GetUserByID: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("GetUserByEmail: got %v, want ErrUserNotFound", err)
+	}
+	if err := repo.SetUserRole(ctx, 404, models.RoleAdmin); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("SetUserRole: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func testDuplicateEmail(t *testing.T, ctx context.Context, repo metadataRepository) {
+	createUser(t, ctx, repo, "ann@example.com")
+	err := repo.CreateUser(ctx, &models.User{Email: "ann@example.com", Password: "hash", Role: models.RoleMember,
+		CreatedAt: testTime(0), UpdatedAt: testTime(0)})
+	if err == nil {
+		t.Error("CreateUser with duplicate email succeeded")
+	}
+}
+
+func testSetUserRole(t *testing.T, ctx context.Context, repo metadataRepository) {
+	user := createUser(t, ctx, repo, "ann@example.com")
+	if err := repo.SetUserRole(ctx, user.ID, models.RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	stored, err := repo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if stored.Role != models.RoleAdmin {
+		t.Errorf("Role = %q, want %q", stored.Role, models.RoleAdmin)
+	}
+}
+
+func testRefreshTokenRotation(t *testing.T, ctx context.Context, repo metadataRepository) {
+	user := createUser(t, ctx, repo, "ann@example.com")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	token := &models.RefreshToken{UserID: user.ID, TokenHash: "h1", ExpiresAt: now.Add(time.Hour),
+		DeviceName: "laptop", CreatedAt: now}
+	if err := repo.StoreRefreshToken(ctx, token); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+	if token.ID == 0 {
+		t.Fatal("StoreRefreshToken did not set ID")
+	}
+
+	stored, err := repo.GetRefreshToken(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if stored.ID != token.ID || stored.UserID != user.ID || stored.DeviceName != "laptop" {
+		t.Errorf("stored token = %+v", stored)
+	}
+
+	rotated := &models.RefreshToken{TokenHash: "h2", ExpiresAt: now.Add(2 * time.Hour), LastUsedAt: now}
+	if err := repo.RotateRefreshToken(ctx, "h1", rotated); err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if rotated.ID != token.ID || rotated.UserID != user.ID {
+		t.Errorf("rotation changed session: %+v", rotated)
+	}
+	if _, err := repo.GetRefreshToken(ctx, "h1"); err == nil {
+		t.Error("replaced token is still valid")
+	}
+	if _, err := repo.GetRefreshToken(ctx, "h2"); err != nil {
+		t.Errorf("GetRefreshToken(h2): %v", err)
+	}
+
+	// Повторное использование старого токена находит текущую сессию
+	session, err := repo.GetRotatedRefreshToken(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetRotatedRefreshToken: %v", err)
+	}
+	if session.TokenHash != "h2" {
+		t.Errorf("rotated session token = %q, want h2", session.TokenHash)
+	}
+	err = repo.RotateRefreshToken(ctx, "h1", &models.RefreshToken{TokenHash: "h3", ExpiresAt: now.Add(time.Hour), LastUsedAt: now})
+	if !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("rotating replaced token: got %v, want ErrSessionNotFound", err)
+	}
+
+	if err := repo.DeleteRefreshToken(ctx, "h2"); err != nil {
+		t.Fatalf("DeleteRefreshToken: %v", err)
+	}
+	if _, err := repo.GetRefreshToken(ctx, "h2"); err == nil {
+		t.Error("deleted token is still valid")
+	}
+	if _, err := repo.GetRotatedRefreshToken(ctx, "h1"); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("GetRotatedRefreshToken after delete: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testUserSessions(t *testing.T, ctx context.Context, repo metadataRepository) {
+	user := createUser(t, ctx, repo, "ann@example.com")
+	other := createUser(t, ctx, repo, "bob@example.com")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var ids []uint
+	for i, owner := range []uint{user.ID, user.ID, user.ID, other.ID} {
+		token := &models.RefreshToken{UserID: owner, TokenHash: string(rune('a' + i)),
+			ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if err := repo.StoreRefreshToken(ctx, token); err != nil {
+			t.Fatalf("StoreRefreshToken: %v", err)
+		}
+		ids = append(ids, token.ID)
+	}
+
+	sessions, err := repo.GetUserSessions(ctx, user.ID, now)
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 3 || sessions[0].ID != ids[2] || sessions[2].ID != ids[0] {
+		t.Errorf("sessions = %+v, want newest first", sessions)
+	}
+
+	if err := repo.DeleteUserSession(ctx, other.ID, ids[0]); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("deleting another user's session: got %v, want ErrSessionNotFound", err)
+	}
+	if err := repo.DeleteUserSession(ctx, user.ID, ids[0]); err != nil {
+		t.Fatalf("DeleteUserSession: %v", err)
+	}
+
+	deleted, err := repo.DeleteUserSessions(ctx, user.ID, ids[2])
+	if err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteUserSessions deleted %d, want 1", deleted)
+	}
+	sessions, err = repo.GetUserSessions(ctx, user.ID, now)
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != ids[2] {
+		t.Errorf("remaining sessions = %+v", sessions)
+	}
+
+	sessions, err = repo.GetUserSessions(ctx, other.ID, now)
+	if err != nil || len(sessions) != 1 {
+		t.Errorf("other user's sessions = %v, %v", sessions, err)
+	}
+}
+
+func testLoginAttempts(t *testing.T, ctx context.Context, repo metadataRepository) {
+	now := testTime(0)
+	for want := 1; want <= 3; want++ {
+		failures, err := repo.RecordLoginFailure(ctx, "ip:10.0.0.1", now, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+		if failures != want {
+			t.Errorf("failures = %d, want %d", failures, want)
+		}
+	}
+
+	// Счетчик обнуляется, если последняя неудача была раньше resetBefore
+	later := now.Add(2 * time.Hour)
+	failures, err := repo.RecordLoginFailure(ctx, "ip:10.0.0.1", later, later.Add(-time.Hour))
+	if err != nil || failures != 1 {
+		t.Errorf("RecordLoginFailure after reset window = %d, %v; want 1", failures, err)
+	}
+
+	until := later.Add(time.Minute)
+	if err := repo.LockLoginAttempts(ctx, "ip:10.0.0.1", until); err != nil {
+		t.Fatalf("LockLoginAttempts: %v", err)
+	}
+	locked, err := repo.LoginLockedUntil(ctx, []string{"account:ann@example.com", "ip:10.0.0.1"}, later)
+	if err != nil {
+		t.Fatalf("LoginLockedUntil: %v", err)
+	}
+	if !locked.Equal(until) {
+		t.Errorf("LoginLockedUntil = %v, want %v", locked, until)
+	}
+	locked, err = repo.LoginLockedUntil(ctx, []string{"ip:10.0.0.1"}, until.Add(time.Second))
+	if err != nil || !locked.IsZero() {
+		t.Errorf("LoginLockedUntil after lockout = %v, %v; want zero", locked, err)
+	}
+
+	if err := repo.DeleteLoginAttempts(ctx, "ip:10.0.0.1"); err != nil {
+		t.Fatalf("DeleteLoginAttempts: %v", err)
+	}
+	locked, err = repo.LoginLockedUntil(ctx, []string{"ip:10.0.0.1"}, later)
+	if err != nil || !locked.IsZero() {
+		t.Errorf("LoginLockedUntil after delete = %v, %v; want zero", locked, err)
+	}
+}
@@ -83,3 +83,26 @@ func (ds *Storage) Delete(filename string) error {
 	log.Printf("INFO: File %s successfully deleted", path)
 	return nil
 }
+
+func (ds *Storage) Rename(from, to string) error {
+	oldPath := filepath.Join(ds.basePath, from)
+	newPath := filepath.Join(ds.basePath, to)
+	log.Printf("INFO: Renaming file %s to %s", oldPath, newPath)
+
+	// os.Rename молча заменяет существующий файл
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("failed to rename file %s: %w", oldPath, os.ErrExist)
+	}
+
+	// Каталог назначения может еще не существовать (например, корзина)
+	if err := os.MkdirAll(filepath.Dir(newPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", newPath, err)
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename file %s to %s: %w", oldPath, newPath, err)
+	}
+
+	log.Printf("INFO: File %s successfully renamed to %s", oldPath, newPath)
+	return nil
+}
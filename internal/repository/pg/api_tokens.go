@@ -0,0 +1,112 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Количество персональных токенов пользователя
+func (p *Repository) CountAPITokens(ctx context.Context, userID uint) (int, error) {
+	var count int
+	if err := p.db(ctx).QueryRow(ctx, CountAPITokensQuery, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count api tokens of user %d: %w", userID, err)
+	}
+	return count, nil
+}
+
+// Создание персонального токена
+func (p *Repository) CreateAPIToken(ctx context.Context, token *models.APIToken) error {
+	scopes, allowedIPs := token.Scopes, token.AllowedIPs
+	if scopes == nil {
+		scopes = []string{}
+	}
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+
+	err := p.db(ctx).QueryRow(ctx, CreateAPITokenQuery,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.Prefix,
+		scopes,
+		allowedIPs,
+		token.ExpiresAt,
+		token.CreatedAt).Scan(&token.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create api token: %w", err)
+	}
+	return nil
+}
+
+// Персональные токены пользователя, новые первыми
+func (p *Repository) GetAPITokens(ctx context.Context, userID uint) ([]*models.APIToken, error) {
+	rows, err := p.db(ctx).Query(ctx, GetAPITokensQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api tokens of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token row: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Получение персонального токена по хешу
+func (p *Repository) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(p.db(ctx).QueryRow(ctx, GetAPITokenByHashQuery, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+	return token, nil
+}
+
+// Отзыв персонального токена пользователя
+func (p *Repository) DeleteAPIToken(ctx context.Context, userID, id uint) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteAPITokenQuery, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api token %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Отметка об использовании персонального токена
+func (p *Repository) TouchAPIToken(ctx context.Context, id uint, at time.Time, ip string) error {
+	if _, err := p.db(ctx).Exec(ctx, TouchAPITokenQuery, id, at, ip); err != nil {
+		return fmt.Errorf("failed to update api token %d usage: %w", id, err)
+	}
+	return nil
+}
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var t models.APIToken
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &t.Prefix, &t.Scopes, &t.AllowedIPs,
+		&t.ExpiresAt, &t.CreatedAt, &t.LastUsedAt, &t.LastUsedIP); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
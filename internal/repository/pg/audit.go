@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Добавление события в журнал аудита
+func (p *Repository) SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	details := event.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	err := p.db(ctx).QueryRow(ctx, SaveAuditEventQuery,
+		event.OccurredAt,
+		event.Action,
+		event.Outcome,
+		event.ActorID,
+		event.ActorEmail,
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		event.Target,
+		details).Scan(&event.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save audit event %s: %w", event.Action, err)
+	}
+	return nil
+}
+
+// Выборка событий журнала, новые первыми
+func (p *Repository) GetAuditEvents(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEvent, error) {
+	args := append(auditFilterArgs(filter), filter.Limit, filter.Offset)
+	rows, err := p.db(ctx).Query(ctx, GetAuditEventsQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// Потоковая выгрузка событий журнала в хронологическом порядке
+func (p *Repository) ExportAuditEvents(ctx context.Context, filter *models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	rows, err := p.db(ctx).Query(ctx, ExportAuditEventsQuery, auditFilterArgs(filter)...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
+// Удаление событий старше указанного момента
+func (p *Repository) DeleteAuditEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteAuditEventsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete audit events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func auditFilterArgs(filter *models.AuditFilter) []any {
+	return []any{filter.ActorID, filter.Action, filter.Target, filter.Outcome, filter.From, filter.To}
+}
+
+func scanAuditEvent(rows pgx.Rows) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	if err := rows.Scan(
+		&event.ID,
+		&event.OccurredAt,
+		&event.Action,
+		&event.Outcome,
+		&event.ActorID,
+		&event.ActorEmail,
+		&event.IP,
+		&event.UserAgent,
+		&event.RequestID,
+		&event.Target,
+		&event.Details); err != nil {
+		return nil, fmt.Errorf("failed to scan audit event row: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,178 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Создание комментария вместе с упоминаниями. Упоминания неизвестных email пропускаются
+func (p *Repository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	tx, err := p.db(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, CreateCommentQuery,
+		comment.FileName,
+		comment.ParentID,
+		comment.AuthorID,
+		comment.Body,
+		comment.CreatedAt).Scan(&comment.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return models.ErrFileNotFound
+		}
+		return fmt.Errorf("failed to create comment on %s: %w", comment.FileName, err)
+	}
+
+	if _, err := tx.Exec(ctx, SaveCommentMentionsQuery, comment.ID, comment.Mentions); err != nil {
+		return fmt.Errorf("failed to save comment mentions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit comment: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) GetComment(ctx context.Context, id int64) (*models.Comment, error) {
+	comment, err := scanComment(p.db(ctx).QueryRow(ctx, GetCommentQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to get comment %d: %w", id, err)
+	}
+	return comment, nil
+}
+
+// Все комментарии файла в порядке создания
+func (p *Repository) GetFileComments(ctx context.Context, filename string) ([]*models.Comment, error) {
+	rows, err := p.db(ctx).Query(ctx, GetFileCommentsQuery, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments of %s: %w", filename, err)
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return comments, nil
+}
+
+// Комментарии, в которых упомянут пользователь, новые первыми
+func (p *Repository) GetMentionedComments(ctx context.Context, userID uint, limit, offset int) (*models.CommentPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetMentionedCommentsQuery, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mentioned comments: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.CommentPage{}
+	for rows.Next() {
+		comment, err := scanComment(rows, &page.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		page.Comments = append(page.Comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// Изменение текста комментария с заменой упоминаний
+func (p *Repository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	tx, err := p.db(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, UpdateCommentBodyQuery, comment.ID, comment.Body, comment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update comment %d: %w", comment.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCommentNotFound
+	}
+
+	if _, err := tx.Exec(ctx, DeleteCommentMentionsQuery, comment.ID); err != nil {
+		return fmt.Errorf("failed to delete comment mentions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, SaveCommentMentionsQuery, comment.ID, comment.Mentions); err != nil {
+		return fmt.Errorf("failed to save comment mentions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit comment: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) SetCommentResolved(ctx context.Context, id int64, resolved bool, userID uint, at time.Time) error {
+	tag, err := p.db(ctx).Exec(ctx, SetCommentResolvedQuery, id, resolved, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to resolve comment %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCommentNotFound
+	}
+	return nil
+}
+
+// Удаление комментария вместе с ответами
+func (p *Repository) DeleteComment(ctx context.Context, id int64) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteCommentQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrCommentNotFound
+	}
+	return nil
+}
+
+func scanComment(row pgx.Row, extra ...any) (*models.Comment, error) {
+	var comment models.Comment
+	dest := []any{
+		&comment.ID,
+		&comment.FileName,
+		&comment.ParentID,
+		&comment.AuthorID,
+		&comment.AuthorEmail,
+		&comment.Body,
+		&comment.Mentions,
+		&comment.Resolved,
+		&comment.ResolvedBy,
+		&comment.ResolvedAt,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
@@ -0,0 +1,31 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Отметка email пользователя как подтвержденного
+func (p *Repository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	tag, err := p.db(ctx).Exec(ctx, MarkEmailVerifiedQuery, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark email of user %d verified: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Отметка об отправке письма для подтверждения. false — email уже подтвержден
+// или предыдущее письмо отправлено позже resendAfter
+func (p *Repository) MarkVerificationSent(ctx context.Context, userID uint, sentAt, resendAfter time.Time) (bool, error) {
+	tag, err := p.db(ctx).Exec(ctx, MarkVerificationSentQuery, userID, sentAt, resendAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark verification sent for user %d: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
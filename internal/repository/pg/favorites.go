@@ -0,0 +1,109 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Добавление файла в избранное. Повторное добавление не меняет дату
+func (p *Repository) StarFile(ctx context.Context, userID uint, filename string, starredAt time.Time) error {
+	tag, err := p.db(ctx).Exec(ctx, StarFileQuery, userID, filename, starredAt)
+	if err != nil {
+		return fmt.Errorf("failed to star file %s: %w", filename, err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// Ничего не вставлено: файл уже в избранном или его нет
+	exists, err := p.IsFileExists(ctx, filename)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return models.ErrFileNotFound
+	}
+	return nil
+}
+
+func (p *Repository) UnstarFile(ctx context.Context, userID uint, filename string) error {
+	if _, err := p.db(ctx).Exec(ctx, UnstarFileQuery, userID, filename); err != nil {
+		return fmt.Errorf("failed to unstar file %s: %w", filename, err)
+	}
+	return nil
+}
+
+func (p *Repository) GetStarredFiles(ctx context.Context, userID uint, limit, offset int) (*models.StarredPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetStarredFilesQuery, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query starred files: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.StarredPage{}
+	for rows.Next() {
+		starred := &models.StarredFile{File: &models.FileMeta{}}
+		if err := rows.Scan(
+			&starred.File.Name,
+			&starred.File.CreatedAt,
+			&starred.File.UpdatedAt,
+			&starred.File.Tags,
+			&starred.File.Attributes,
+			&starred.StarredAt,
+			&page.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan starred file row: %w", err)
+		}
+		page.Files = append(page.Files, starred)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// Отметка о работе с файлом; список недавних ограничивается keep записями
+func (p *Repository) SaveRecentFile(ctx context.Context, userID uint, filename, action string, accessedAt time.Time, keep int) error {
+	if _, err := p.db(ctx).Exec(ctx, SaveRecentFileQuery, userID, filename, action, accessedAt); err != nil {
+		return fmt.Errorf("failed to save recent file %s: %w", filename, err)
+	}
+	if _, err := p.db(ctx).Exec(ctx, TrimRecentFilesQuery, userID, keep); err != nil {
+		return fmt.Errorf("failed to trim recent files: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) GetRecentFiles(ctx context.Context, userID uint, limit, offset int) (*models.RecentPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetRecentFilesQuery, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent files: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.RecentPage{}
+	for rows.Next() {
+		recent := &models.RecentFile{File: &models.FileMeta{}}
+		if err := rows.Scan(
+			&recent.File.Name,
+			&recent.File.CreatedAt,
+			&recent.File.UpdatedAt,
+			&recent.File.Tags,
+			&recent.File.Attributes,
+			&recent.Action,
+			&recent.AccessedAt,
+			&page.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan recent file row: %w", err)
+		}
+		page.Files = append(page.Files, recent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
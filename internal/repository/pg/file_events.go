@@ -0,0 +1,56 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Сохранение события изменения файла
+func (p *Repository) SaveFileEvent(ctx context.Context, event *models.FileEvent) error {
+	err := p.db(ctx).QueryRow(ctx, SaveFileEventQuery,
+		event.OccurredAt,
+		event.Type,
+		event.FileName,
+		event.ActorID).Scan(&event.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save file event for %s: %w", event.FileName, err)
+	}
+	return nil
+}
+
+// События после указанного ID, в порядке возрастания
+func (p *Repository) GetFileEventsSince(ctx context.Context, afterID int64, limit int) ([]*models.FileEvent, error) {
+	rows, err := p.db(ctx).Query(ctx, GetFileEventsSinceQuery, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.FileEvent
+	for rows.Next() {
+		var event models.FileEvent
+		if err := rows.Scan(&event.ID, &event.OccurredAt, &event.Type, &event.FileName, &event.ActorID); err != nil {
+			return nil, fmt.Errorf("failed to scan file event row: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// Удаление событий старше указанного момента
+func (p *Repository) DeleteFileEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteFileEventsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete file events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
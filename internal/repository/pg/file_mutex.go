@@ -0,0 +1,81 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Пространство ключей advisory lock для изменений файлов; двухключевая форма
+// не пересекается с ключом миграций
+const fileMutexClassID int32 = 0x74616773 // "tags"
+
+// querier общие методы пула и транзакции
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// db возвращает транзакцию мьютекса файла, если запрос выполняется под ним, иначе пул.
+// Все запросы операции идут через одно соединение, поэтому операции под мьютексом
+// не ждут свободного соединения, удерживая свое, и не могут исчерпать пул
+func (p *Repository) db(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return p.pool
+}
+
+// WithFileMutex ждет, пока другие экземпляры закончат изменять файлы, и выполняет fn
+// в транзакции, которая держит pg_advisory_xact_lock на каждый файл. Проверка условий
+// и запись внутри fn фиксируются вместе; ошибка fn откатывает изменения в базе.
+// Блокировки захватываются в порядке имен, чтобы встречные операции не зависли
+func (p *Repository) WithFileMutex(ctx context.Context, filenames []string, fn func(ctx context.Context) error) error {
+	names := slices.Clone(filenames)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	// Вложенный вызов продолжает уже открытую транзакцию
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		if err := lockFiles(ctx, tx, names); err != nil {
+			return err
+		}
+		return fn(ctx)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := lockFiles(ctx, tx, names); err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit changes of %s: %w", strings.Join(names, ", "), err)
+	}
+	return nil
+}
+
+func lockFiles(ctx context.Context, tx pgx.Tx, names []string) error {
+	for _, name := range names {
+		if _, err := tx.Exec(ctx, AcquireFileMutexQuery, fileMutexClassID, name); err != nil {
+			return fmt.Errorf("failed to lock file %s for writing: %w", name, err)
+		}
+	}
+	return nil
+}
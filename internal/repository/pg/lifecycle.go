@@ -0,0 +1,209 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Отметка об обращении к файлу; время только увеличивается
+func (p *Repository) TouchFileAccess(ctx context.Context, filename string, accessedAt time.Time) error {
+	if _, err := p.db(ctx).Exec(ctx, TouchFileAccessQuery, filename, accessedAt); err != nil {
+		return fmt.Errorf("failed to touch file %s: %w", filename, err)
+	}
+	return nil
+}
+
+func (p *Repository) CreateLifecycleRule(ctx context.Context, rule *models.LifecycleRule) error {
+	err := p.db(ctx).QueryRow(ctx, CreateLifecycleRuleQuery,
+		rule.Name,
+		rule.OwnerID,
+		rule.Prefix,
+		rule.Tag,
+		rule.Condition,
+		rule.AfterDays,
+		rule.Action,
+		rule.Enabled,
+		rule.CreatedAt).Scan(&rule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create lifecycle rule: %w", err)
+	}
+	rule.UpdatedAt = rule.CreatedAt
+	return nil
+}
+
+func (p *Repository) UpdateLifecycleRule(ctx context.Context, rule *models.LifecycleRule) error {
+	tag, err := p.db(ctx).Exec(ctx, UpdateLifecycleRuleQuery,
+		rule.ID,
+		rule.Name,
+		rule.Prefix,
+		rule.Tag,
+		rule.Condition,
+		rule.AfterDays,
+		rule.Action,
+		rule.Enabled,
+		rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update lifecycle rule %d: %w", rule.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLifecycleRuleNotFound
+	}
+	return nil
+}
+
+func (p *Repository) DeleteLifecycleRule(ctx context.Context, id int64) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteLifecycleRuleQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete lifecycle rule %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLifecycleRuleNotFound
+	}
+	return nil
+}
+
+func (p *Repository) GetLifecycleRule(ctx context.Context, id int64) (*models.LifecycleRule, error) {
+	rule, err := scanLifecycleRule(p.db(ctx).QueryRow(ctx, GetLifecycleRuleQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrLifecycleRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get lifecycle rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// Правила пользователя; ownerID = 0 — правила всех пользователей
+func (p *Repository) GetLifecycleRules(ctx context.Context, ownerID uint) ([]*models.LifecycleRule, error) {
+	return p.queryLifecycleRules(ctx, GetLifecycleRulesQuery, ownerID)
+}
+
+func (p *Repository) GetEnabledLifecycleRules(ctx context.Context) ([]*models.LifecycleRule, error) {
+	return p.queryLifecycleRules(ctx, GetEnabledLifecycleRulesQuery)
+}
+
+// Отметка о запуске правила. false — правило уже запускалось после notBefore
+// (другим экземпляром) или отключено
+func (p *Repository) ClaimLifecycleRule(ctx context.Context, id int64, now, notBefore time.Time) (bool, error) {
+	tag, err := p.db(ctx).Exec(ctx, ClaimLifecycleRuleQuery, id, now, notBefore)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim lifecycle rule %d: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Файлы, подходящие под правило: изменены (или открыты) раньше before, с именем больше after
+func (p *Repository) GetLifecycleCandidates(ctx context.Context, rule *models.LifecycleRule, ownerID uint, before time.Time, after string, limit int) ([]*models.LifecycleCandidate, int, error) {
+	rows, err := p.db(ctx).Query(ctx, GetLifecycleCandidatesQuery, rule.Prefix, rule.Tag, rule.Condition, before, after, limit, ownerID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query lifecycle candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*models.LifecycleCandidate
+	var total int
+	for rows.Next() {
+		var c models.LifecycleCandidate
+		if err := rows.Scan(&c.Name, &c.ETag, &c.UpdatedAt, &c.AccessedAt, &total); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan lifecycle candidate row: %w", err)
+		}
+		candidates = append(candidates, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return candidates, total, nil
+}
+
+func (p *Repository) SaveLifecycleActions(ctx context.Context, actions []*models.LifecycleAction) error {
+	batch := &pgx.Batch{}
+	for _, a := range actions {
+		batch.Queue(SaveLifecycleActionQuery, a.RuleID, a.FileName, a.Action, a.Outcome, a.Error, a.AppliedAt)
+	}
+	if err := p.db(ctx).SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save lifecycle actions: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) GetLifecycleActions(ctx context.Context, ruleID int64, limit, offset int) (*models.LifecycleActionPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetLifecycleActionsQuery, ruleID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lifecycle actions: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.LifecycleActionPage{}
+	for rows.Next() {
+		var a models.LifecycleAction
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.FileName, &a.Action, &a.Outcome, &a.Error, &a.AppliedAt, &page.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle action row: %w", err)
+		}
+		page.Actions = append(page.Actions, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+func (p *Repository) DeleteLifecycleActionsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteLifecycleActionsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete lifecycle actions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (p *Repository) queryLifecycleRules(ctx context.Context, query string, args ...any) ([]*models.LifecycleRule, error) {
+	rows, err := p.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.LifecycleRule
+	for rows.Next() {
+		rule, err := scanLifecycleRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle rule row: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return rules, nil
+}
+
+func scanLifecycleRule(row pgx.Row) (*models.LifecycleRule, error) {
+	var r models.LifecycleRule
+	if err := row.Scan(
+		&r.ID,
+		&r.Name,
+		&r.OwnerID,
+		&r.Prefix,
+		&r.Tag,
+		&r.Condition,
+		&r.AfterDays,
+		&r.Action,
+		&r.Enabled,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+		&r.LastRunAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
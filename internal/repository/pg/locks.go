@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Захват блокировки. Истекшая блокировка или блокировка того же владельца заменяется
+func (p *Repository) AcquireLock(ctx context.Context, lock *models.FileLock) (*models.FileLock, error) {
+	acquired, err := scanLock(p.db(ctx).QueryRow(ctx, AcquireLockQuery,
+		lock.FileName,
+		lock.Token,
+		lock.OwnerID,
+		lock.AcquiredAt,
+		lock.ExpiresAt), true)
+	if err == nil {
+		return acquired, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to acquire lock on %s: %w", lock.FileName, err)
+	}
+
+	// Ничего не вставлено: файла нет или он заблокирован другим пользователем
+	exists, err := p.IsFileExists(ctx, lock.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrFileNotFound
+	}
+	return nil, models.ErrFileLocked
+}
+
+// Продление активной блокировки по токену
+func (p *Repository) RefreshLock(ctx context.Context, filename, token string, expiresAt, now time.Time) (*models.FileLock, error) {
+	lock, err := scanLock(p.db(ctx).QueryRow(ctx, RefreshLockQuery, filename, token, expiresAt, now), true)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrLockNotFound
+		}
+		return nil, fmt.Errorf("failed to refresh lock on %s: %w", filename, err)
+	}
+	return lock, nil
+}
+
+// Снятие активной блокировки по токену
+func (p *Repository) ReleaseLock(ctx context.Context, filename, token string, now time.Time) error {
+	tag, err := p.db(ctx).Exec(ctx, ReleaseLockQuery, filename, token, now)
+	if err != nil {
+		return fmt.Errorf("failed to release lock on %s: %w", filename, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLockNotFound
+	}
+	return nil
+}
+
+// Принудительное снятие блокировки без токена
+func (p *Repository) BreakLock(ctx context.Context, filename string, now time.Time) (*models.FileLock, error) {
+	lock, err := scanLock(p.db(ctx).QueryRow(ctx, BreakLockQuery, filename, now), false)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrLockNotFound
+		}
+		return nil, fmt.Errorf("failed to break lock on %s: %w", filename, err)
+	}
+	return lock, nil
+}
+
+// Активная блокировка файла вместе с токеном
+func (p *Repository) GetLock(ctx context.Context, filename string, now time.Time) (*models.FileLock, error) {
+	lock, err := scanLock(p.db(ctx).QueryRow(ctx, GetLockQuery, filename, now), true)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrLockNotFound
+		}
+		return nil, fmt.Errorf("failed to get lock on %s: %w", filename, err)
+	}
+	return lock, nil
+}
+
+// Все активные блокировки без токенов
+func (p *Repository) GetActiveLocks(ctx context.Context, now time.Time) ([]*models.FileLock, error) {
+	rows, err := p.db(ctx).Query(ctx, GetActiveLocksQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active locks: %w", err)
+	}
+	defer rows.Close()
+
+	var locks []*models.FileLock
+	for rows.Next() {
+		lock, err := scanLock(rows, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lock row: %w", err)
+		}
+		locks = append(locks, lock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return locks, nil
+}
+
+func scanLock(row pgx.Row, withToken bool) (*models.FileLock, error) {
+	var lock models.FileLock
+	dest := []any{&lock.FileName}
+	if withToken {
+		dest = append(dest, &lock.Token)
+	}
+	dest = append(dest, &lock.OwnerID, &lock.AcquiredAt, &lock.ExpiresAt)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
@@ -0,0 +1,54 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Учет неудачной попытки. Возвращает количество неудач подряд; счетчик
+// начинается заново, если последняя неудача и блокировка были раньше resetBefore
+func (p *Repository) RecordLoginFailure(ctx context.Context, subject string, now, resetBefore time.Time) (int, error) {
+	var failures int
+	if err := p.db(ctx).QueryRow(ctx, RecordLoginFailureQuery, subject, now, resetBefore).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+// Блокировка попыток до until. Более долгая блокировка не сокращается
+func (p *Repository) LockLoginAttempts(ctx context.Context, subject string, until time.Time) error {
+	if _, err := p.db(ctx).Exec(ctx, LockLoginAttemptsQuery, subject, until); err != nil {
+		return fmt.Errorf("failed to lock login attempts: %w", err)
+	}
+	return nil
+}
+
+// Время окончания самой долгой действующей блокировки из subjects. Нулевое
+// время — блокировок нет
+func (p *Repository) LoginLockedUntil(ctx context.Context, subjects []string, now time.Time) (time.Time, error) {
+	var until *time.Time
+	if err := p.db(ctx).QueryRow(ctx, LoginLockedUntilQuery, subjects, now).Scan(&until); err != nil {
+		return time.Time{}, fmt.Errorf("failed to check login lockout: %w", err)
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+func (p *Repository) DeleteLoginAttempts(ctx context.Context, subject string) error {
+	if _, err := p.db(ctx).Exec(ctx, DeleteLoginAttemptsQuery, subject); err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+	return nil
+}
+
+// Удаление счетчиков, которые обнулились бы при следующей неудаче
+func (p *Repository) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteExpiredLoginAttemptsQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired login attempts: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
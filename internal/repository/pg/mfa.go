@@ -0,0 +1,76 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Сохранение секрета TOTP до подтверждения. Пока двухфакторная аутентификация
+// включена, секрет не заменяется
+func (p *Repository) SetTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	tag, err := p.db(ctx).Exec(ctx, SetTOTPSecretQuery, userID, secret, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Включение двухфакторной аутентификации с подтвержденным шагом TOTP и хешами кодов восстановления
+func (p *Repository) EnableMFA(ctx context.Context, userID uint, step int64, recoveryCodes []string) error {
+	tag, err := p.db(ctx).Exec(ctx, EnableMFAQuery, userID, step, recoveryCodes, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Отключение двухфакторной аутентификации: секрет и коды восстановления удаляются
+func (p *Repository) DisableMFA(ctx context.Context, userID uint) error {
+	tag, err := p.db(ctx).Exec(ctx, DisableMFAQuery, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to disable mfa of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Замена кодов восстановления новым набором
+func (p *Repository) SetRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error {
+	tag, err := p.db(ctx).Exec(ctx, SetRecoveryCodesQuery, userID, recoveryCodes, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set recovery codes of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// Использование кода восстановления. false — кода нет или он уже использован
+func (p *Repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	tag, err := p.db(ctx).Exec(ctx, UseRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code of user %d: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Отметка шага TOTP как использованного. false — код этого или более позднего шага уже принят
+func (p *Repository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	tag, err := p.db(ctx).Exec(ctx, UseTOTPStepQuery, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step of user %d: %w", userID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,135 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Режимы применения миграций при запуске
+const (
+	MigrateOff   = "off"   // не проверять и не применять
+	MigrateAuto  = "auto"  // применить недостающие миграции
+	MigrateCheck = "check" // только проверить, что схема соответствует бинарному файлу
+)
+
+// Ключ advisory lock, под которым экземпляры сервиса по очереди применяют миграции
+const migrationLockKey int64 = 0x7461676573 // "tages"
+
+var (
+	ErrSchemaDirty  = errors.New("database schema is dirty")
+	ErrSchemaAhead  = errors.New("database schema is newer than this binary")
+	ErrSchemaBehind = errors.New("database schema has pending migrations")
+)
+
+// Migrate проверяет и при необходимости применяет встроенные миграции.
+// Запуск отклоняется, если предыдущая миграция завершилась с ошибкой (dirty)
+// или схема новее, чем знает бинарный файл
+func Migrate(ctx context.Context, pool *pgxpool.Pool, migrations fs.FS, mode string) error {
+	switch mode {
+	case MigrateOff:
+		log.Printf("INFO: Schema migrations are disabled")
+		return nil
+	case MigrateAuto, MigrateCheck:
+	default:
+		return fmt.Errorf("unknown migrate mode %q, expected %s, %s or %s", mode, MigrateOff, MigrateAuto, MigrateCheck)
+	}
+
+	// Держим блокировку на отдельном соединении, пока идет проверка и миграция
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migration lock: %w", err)
+	}
+	defer conn.Release()
+
+	log.Printf("INFO: Waiting for schema migration lock")
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("ERROR: Failed to release migration lock: %v", err)
+		}
+	}()
+
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		return fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	latest, err := latestVersion(src)
+	if err != nil {
+		return err
+	}
+
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to create migrator: %w", err)
+	}
+	// Драйвер держит соединение из пула до закрытия мигратора
+	defer func() {
+		if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+			log.Printf("ERROR: Failed to close migrator: source: %v, database: %v", srcErr, dbErr)
+		}
+	}()
+
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("%w at version %d: fix it manually and run `make migrate-force`", ErrSchemaDirty, current)
+	case current > latest:
+		return fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaAhead, current, latest)
+	case current == latest:
+		log.Printf("INFO: Database schema is up to date (version %d)", current)
+		return nil
+	case mode == MigrateCheck:
+		return fmt.Errorf("%w: database is at version %d, binary expects %d", ErrSchemaBehind, current, latest)
+	}
+
+	log.Printf("INFO: Migrating database schema from version %d to %d", current, latest)
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	log.Printf("INFO: Database schema migrated to version %d", latest)
+	return nil
+}
+
+// Номер последней встроенной миграции
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read embedded migrations: %w", err)
+		}
+		version = next
+	}
+}
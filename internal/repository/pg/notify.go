@@ -0,0 +1,75 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// Notify отправляет уведомление через NOTIFY всем экземплярам, слушающим канал
+func (p *Repository) Notify(ctx context.Context, channel, payload string) error {
+	if _, err := p.db(ctx).Exec(ctx, NotifyQuery, channel, payload); err != nil {
+		return fmt.Errorf("failed to notify channel %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Listen подписывается на канал через LISTEN и вызывает handler для каждого уведомления
+// до отмены контекста. При обрыве соединения переподключается; onListen вызывается
+// после каждой успешной подписки, чтобы можно было догнать пропущенное
+func (p *Repository) Listen(ctx context.Context, channel string, onListen func(), handler func(payload string)) {
+	retry := listenRetryMin
+	for ctx.Err() == nil {
+		err := p.listen(ctx, channel, func() {
+			retry = listenRetryMin
+			if onListen != nil {
+				onListen()
+			}
+		}, handler)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("ERROR: Listener on channel %s failed, retrying in %s: %v", channel, retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > listenRetryMax {
+			retry = listenRetryMax
+		}
+	}
+}
+
+func (p *Repository) listen(ctx context.Context, channel string, onListen func(), handler func(payload string)) error {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// Соединение с активной подпиской не возвращаем в пул
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen channel %s: %w", channel, err)
+	}
+	log.Printf("INFO: Listening channel %s", channel)
+	onListen()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		handler(notification.Payload)
+	}
+}
@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Замена хеша пароля пользователя
+func (p *Repository) SetPassword(ctx context.Context, userID uint, passwordHash string) error {
+	tag, err := p.db(ctx).Exec(ctx, SetPasswordQuery, userID, passwordHash, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set password of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (p *Repository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	_, err := p.db(ctx).Exec(ctx, CreatePasswordResetTokenQuery,
+		token.TokenHash,
+		token.UserID,
+		token.ExpiresAt,
+		token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+	return nil
+}
+
+// Количество токенов сброса пароля, выпущенных пользователю после since
+func (p *Repository) CountPasswordResetTokens(ctx context.Context, userID uint, since time.Time) (int, error) {
+	var count int
+	if err := p.db(ctx).QueryRow(ctx, CountPasswordResetTokensQuery, userID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count password reset tokens: %w", err)
+	}
+	return count, nil
+}
+
+// Использование действующего токена сброса пароля. Токен удаляется
+func (p *Repository) ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := p.db(ctx).QueryRow(ctx, ConsumePasswordResetTokenQuery, tokenHash, now).Scan(
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrPasswordResetTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	return &token, nil
+}
+
+// Удаление всех токенов сброса пароля пользователя
+func (p *Repository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	if _, err := p.db(ctx).Exec(ctx, DeletePasswordResetTokensQuery, userID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (p *Repository) DeleteExpiredPasswordResetTokens(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteExpiredPasswordResetTokensQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired password reset tokens: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
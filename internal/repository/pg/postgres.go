@@ -2,13 +2,24 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"tages/internal/models"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Коды ошибок PostgreSQL
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
 type Repository struct {
 	pool *pgxpool.Pool
 }
@@ -23,7 +34,21 @@ func (p *Repository) Close() {
 }
 
 func (p *Repository) SaveFileMeta(ctx context.Context, file *models.FileMeta) error {
-	_, err := p.pool.Exec(ctx, SaveFileMetaQuery, file.Name, file.CreatedAt, file.UpdatedAt)
+	tags, attributes, err := encodeFileMetadata(file.Tags, file.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for %s: %w", file.Name, err)
+	}
+
+	_, err = p.db(ctx).Exec(ctx, SaveFileMetaQuery,
+		file.Name,
+		file.CreatedAt,
+		file.UpdatedAt,
+		tags,
+		attributes,
+		file.ETag,
+		file.Size,
+		file.MimeType,
+		file.OwnerID)
 	if err != nil {
 		return fmt.Errorf("failed to save file meta for %s: %w", file.Name, err)
 	}
@@ -32,7 +57,7 @@ func (p *Repository) SaveFileMeta(ctx context.Context, file *models.FileMeta) er
 
 func (p *Repository) IsFileExists(ctx context.Context, filename string) (bool, error) {
 	var exists bool
-	err := p.pool.QueryRow(ctx, IsFileExistsQuery, filename).Scan(&exists)
+	err := p.db(ctx).QueryRow(ctx, IsFileExistsQuery, filename).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check existence of file %s: %w", filename, err)
 	}
@@ -40,15 +65,23 @@ func (p *Repository) IsFileExists(ctx context.Context, filename string) (bool, e
 }
 
 func (p *Repository) UpdateFileMeta(ctx context.Context, file *models.FileMeta) error {
-	_, err := p.pool.Exec(ctx, UpdateFileMetaQuery, file.Name, file.CreatedAt, file.UpdatedAt)
+	_, err := p.db(ctx).Exec(ctx, UpdateFileMetaQuery, file.Name, file.CreatedAt, file.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update file meta for %s: %w", file.Name, err)
 	}
 	return nil
 }
 
-func (p *Repository) GetFilesMeta(ctx context.Context) ([]*models.FileMeta, error) {
-	rows, err := p.pool.Query(ctx, GetFilesMetaQuery)
+func (p *Repository) GetFilesMeta(ctx context.Context, filter *models.FileFilter) ([]*models.FileMeta, error) {
+	var tags, attributes []byte
+	if filter != nil {
+		var err error
+		if tags, attributes, err = encodeFileMetadata(filter.Tags, filter.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to encode files filter: %w", err)
+		}
+	}
+
+	rows, err := p.db(ctx).Query(ctx, GetFilesMetaQuery, tags, attributes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query files meta: %w", err)
 	}
@@ -57,7 +90,16 @@ func (p *Repository) GetFilesMeta(ctx context.Context) ([]*models.FileMeta, erro
 	var files []*models.FileMeta
 	for rows.Next() {
 		var file models.FileMeta
-		if err := rows.Scan(&file.Name, &file.CreatedAt, &file.UpdatedAt); err != nil {
+		if err := rows.Scan(
+			&file.Name,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+			&file.Tags,
+			&file.Attributes,
+			&file.ETag,
+			&file.Size,
+			&file.MimeType,
+			&file.OwnerID); err != nil {
 			return nil, fmt.Errorf("failed to scan file meta row: %w", err)
 		}
 		files = append(files, &file)
@@ -70,20 +112,114 @@ func (p *Repository) GetFilesMeta(ctx context.Context) ([]*models.FileMeta, erro
 	return files, nil
 }
 
+func (p *Repository) GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error) {
+	var file models.FileMeta
+	err := p.db(ctx).QueryRow(ctx, GetFileMetaQuery, filename).Scan(
+		&file.Name,
+		&file.CreatedAt,
+		&file.UpdatedAt,
+		&file.Tags,
+		&file.Attributes,
+		&file.ETag,
+		&file.Size,
+		&file.MimeType,
+		&file.OwnerID)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get file meta for %s: %w", filename, err)
+	}
+	return &file, nil
+}
+
+// Замена тегов и атрибутов файла
+func (p *Repository) UpdateFileMetadata(ctx context.Context, file *models.FileMeta) error {
+	tags, attributes, err := encodeFileMetadata(file.Tags, file.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for %s: %w", file.Name, err)
+	}
+	// Пустые значения сохраняем как пустые коллекции, а не NULL
+	if tags == nil {
+		tags = []byte("[]")
+	}
+	if attributes == nil {
+		attributes = []byte("{}")
+	}
+
+	err = p.db(ctx).QueryRow(ctx, UpdateFileMetadataQuery, file.Name, tags, attributes, file.UpdatedAt, file.ETag).Scan(
+		&file.Name,
+		&file.CreatedAt,
+		&file.UpdatedAt,
+		&file.Tags,
+		&file.Attributes,
+		&file.ETag,
+		&file.Size,
+		&file.MimeType,
+		&file.OwnerID)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrFileNotFound
+		}
+		return fmt.Errorf("failed to update metadata for %s: %w", file.Name, err)
+	}
+	return nil
+}
+
 func (p *Repository) DeleteFileMeta(ctx context.Context, filename string) error {
 
-	_, err := p.pool.Exec(ctx, DeleteFileMetaQuery, filename)
+	_, err := p.db(ctx).Exec(ctx, DeleteFileMetaQuery, filename)
 	if err != nil {
 		return fmt.Errorf("failed to delete file meta for %s: %w", filename, err)
 	}
 	return nil
 }
 
+// Переименование файла. Связанные записи (избранное, комментарии, блокировки)
+// переносятся каскадно
+func (p *Repository) RenameFileMeta(ctx context.Context, from, to string, updatedAt time.Time) error {
+	tag, err := p.db(ctx).Exec(ctx, RenameFileMetaQuery, from, to, updatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return models.ErrFileExists
+		}
+		return fmt.Errorf("failed to rename file meta %s to %s: %w", from, to, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrFileNotFound
+	}
+	return nil
+}
+
+// encodeFileMetadata сериализует теги и атрибуты в JSON.
+// Пустые значения возвращаются как nil, что в запросах означает NULL
+func encodeFileMetadata(tags []string, attributes map[string]string) ([]byte, []byte, error) {
+	var encodedTags, encodedAttributes []byte
+	var err error
+
+	if len(tags) > 0 {
+		if encodedTags, err = json.Marshal(tags); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(attributes) > 0 {
+		if encodedAttributes, err = json.Marshal(attributes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return encodedTags, encodedAttributes, nil
+}
+
 // Методы для работы с пользователями
 func (p *Repository) CreateUser(ctx context.Context, user *models.User) error {
-	err := p.pool.QueryRow(ctx, CreateUserQuery,
+	err := p.db(ctx).QueryRow(ctx, CreateUserQuery,
 		user.Email,
 		user.Password,
+		user.Role,
+		user.EmailVerified,
 		user.CreatedAt,
 		user.UpdatedAt).Scan(&user.ID)
 
@@ -94,31 +230,87 @@ func (p *Repository) CreateUser(ctx context.Context, user *models.User) error {
 }
 
 func (p *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
-	err := p.pool.QueryRow(ctx, GetUserByEmailQuery, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt)
-
+	user, err := scanUser(p.db(ctx).QueryRow(ctx, GetUserByEmailQuery, email))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = models.ErrUserNotFound
+		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (p *Repository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	user, err := scanUser(p.db(ctx).QueryRow(ctx, GetUserByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = models.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	return user, nil
+}
+
+// Страница списка пользователей в порядке регистрации
+func (p *Repository) GetUsers(ctx context.Context, limit, offset int) (*models.UserPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetUsersQuery, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.UserPage{}
+	for rows.Next() {
+		user, err := scanUser(rows, &page.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		page.Users = append(page.Users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// Назначение роли пользователю
+func (p *Repository) SetUserRole(ctx context.Context, userID uint, role string) error {
+	tag, err := p.db(ctx).Exec(ctx, SetUserRoleQuery, userID, role, time.Now())
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return models.ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to set role of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func scanUser(row pgx.Row, extra ...any) (*models.User, error) {
 	var user models.User
-	err := p.pool.QueryRow(ctx, GetUserByIDQuery, id).Scan(
+	dest := append([]any{
 		&user.ID,
 		&user.Email,
 		&user.Password,
+		&user.Role,
 		&user.CreatedAt,
-		&user.UpdatedAt)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user by id: %w", err)
+		&user.UpdatedAt,
+		&user.TokensValidAfter,
+		&user.TokenVersion,
+		&user.MFAEnabled,
+		&user.TOTPSecret,
+		&user.TOTPLastStep,
+		&user.RecoveryCodes,
+		&user.EmailVerified,
+		&user.VerificationSentAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
 	}
 	return &user, nil
 }
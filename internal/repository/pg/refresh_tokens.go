@@ -2,45 +2,142 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
-// Добавление refresh token в базу данных
-func (p *Repository) StoreRefreshToken(ctx context.Context, userID uint, token string, expiresAt time.Time) error {
-	_, err := p.pool.Exec(ctx, SaveRefreshTokenQuery,
-		userID,
-		token,
-		expiresAt)
+// Добавление refresh token новой сессии в базу данных
+func (p *Repository) StoreRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	err := p.db(ctx).QueryRow(ctx, SaveRefreshTokenQuery,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.DeviceName,
+		token.IP,
+		token.UserAgent,
+		token.CreatedAt).Scan(&token.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to store refresh token: %w", err)
 	}
+	token.LastUsedAt = token.CreatedAt
+	return nil
+}
+
+// Замена refresh token в существующей сессии. Хеш старого токена
+// сохраняется в семействе для обнаружения повторного использования
+func (p *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash string, token *models.RefreshToken) error {
+	rotated, err := scanRefreshToken(p.db(ctx).QueryRow(ctx, RotateRefreshTokenQuery,
+		oldTokenHash,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.IP,
+		token.UserAgent,
+		token.LastUsedAt))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	*token = *rotated
 	return nil
 }
 
 // Удаление refresh token при выходе из системы
-func (p *Repository) DeleteRefreshToken(ctx context.Context, token string) error {
-	_, err := p.pool.Exec(ctx, DeleteRefreshTokenQuery, token)
+func (p *Repository) DeleteRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := p.db(ctx).Exec(ctx, DeleteRefreshTokenQuery, tokenHash)
 	if err != nil {
 		return fmt.Errorf("failed to delete refresh token: %w", err)
 	}
 	return nil
 }
 
-// Получение refresh token из базы данных
-func (p *Repository) GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
-	var refreshToken models.RefreshToken
-	err := p.pool.QueryRow(ctx, GetRefreshTokenQuery, token).Scan(
-		&refreshToken.ID,
-		&refreshToken.UserID,
-		&refreshToken.Token,
-		&refreshToken.ExpiresAt)
-
+// Получение refresh token из базы данных по хешу
+func (p *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	refreshToken, err := scanRefreshToken(p.db(ctx).QueryRow(ctx, GetRefreshTokenQuery, tokenHash))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
-	return &refreshToken, nil
+	return refreshToken, nil
+}
+
+// Сессия, в которой токен с этим хешем уже был заменен
+func (p *Repository) GetRotatedRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	session, err := scanRefreshToken(p.db(ctx).QueryRow(ctx, GetRotatedRefreshTokenQuery, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get rotated refresh token: %w", err)
+	}
+	return session, nil
+}
+
+// Действующие сессии пользователя, последние использованные первыми
+func (p *Repository) GetUserSessions(ctx context.Context, userID uint, now time.Time) ([]*models.RefreshToken, error) {
+	rows, err := p.db(ctx).Query(ctx, GetUserSessionsQuery, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.RefreshToken
+	for rows.Next() {
+		session, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session row: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Завершение одной сессии пользователя
+func (p *Repository) DeleteUserSession(ctx context.Context, userID, sessionID uint) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteUserSessionQuery, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session %d: %w", sessionID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Завершение всех сессий пользователя, кроме exceptID (0 — завершить все)
+func (p *Repository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteUserSessionsQuery, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions of user %d: %w", userID, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Удаление сессий, срок действия которых истек
+func (p *Repository) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteExpiredRefreshTokensQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanRefreshToken(row pgx.Row) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	if err := row.Scan(&t.ID, &t.UserID, &t.TokenHash, &t.ExpiresAt, &t.DeviceName, &t.IP, &t.UserAgent, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
@@ -0,0 +1,194 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Установка срока хранения файла. allowShorten разрешает сокращать срок в режиме governance
+func (p *Repository) SetFileRetention(ctx context.Context, retention *models.Retention, allowShorten bool) (*models.Retention, error) {
+	saved, err := scanFileRetention(p.db(ctx).QueryRow(ctx, SetFileRetentionQuery,
+		retention.FileName,
+		retention.RetainUntil,
+		retention.Mode,
+		retention.SetBy,
+		retention.SetAt,
+		allowShorten))
+	if err == nil {
+		return saved, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set retention on %s: %w", retention.FileName, err)
+	}
+
+	// Ничего не изменено: файла нет или действующий срок запрещает изменение
+	exists, err := p.IsFileExists(ctx, retention.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrFileNotFound
+	}
+	return nil, models.ErrRetentionConflict
+}
+
+// Снятие срока хранения файла. allowGovernance разрешает снять действующий срок в режиме governance
+func (p *Repository) ClearFileRetention(ctx context.Context, filename string, now time.Time, allowGovernance bool) error {
+	tag, err := p.db(ctx).Exec(ctx, ClearFileRetentionQuery, filename, now, allowGovernance)
+	if err != nil {
+		return fmt.Errorf("failed to clear retention on %s: %w", filename, err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	_, err = scanFileRetention(p.db(ctx).QueryRow(ctx, GetFileRetentionQuery, filename, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrRetentionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get retention on %s: %w", filename, err)
+	}
+	return models.ErrRetentionConflict
+}
+
+// Установка срока хранения для префикса имен
+func (p *Repository) SetFolderRetention(ctx context.Context, retention *models.Retention, allowShorten bool) (*models.Retention, error) {
+	saved, err := scanFolderRetention(p.db(ctx).QueryRow(ctx, SetFolderRetentionQuery,
+		retention.Prefix,
+		retention.RetainUntil,
+		retention.Mode,
+		retention.SetBy,
+		retention.SetAt,
+		allowShorten))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrRetentionConflict
+		}
+		return nil, fmt.Errorf("failed to set retention on prefix %s: %w", retention.Prefix, err)
+	}
+	return saved, nil
+}
+
+func (p *Repository) ClearFolderRetention(ctx context.Context, prefix string, now time.Time, allowGovernance bool) error {
+	tag, err := p.db(ctx).Exec(ctx, ClearFolderRetentionQuery, prefix, now, allowGovernance)
+	if err != nil {
+		return fmt.Errorf("failed to clear retention on prefix %s: %w", prefix, err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	_, err = scanFolderRetention(p.db(ctx).QueryRow(ctx, GetFolderRetentionQuery, prefix, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrRetentionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get retention on prefix %s: %w", prefix, err)
+	}
+	return models.ErrRetentionConflict
+}
+
+// Действующие сроки хранения префиксов
+func (p *Repository) GetFolderRetentions(ctx context.Context, now time.Time) ([]*models.Retention, error) {
+	return p.queryFolderRetentions(ctx, GetFolderRetentionsQuery, now)
+}
+
+func (p *Repository) SetLegalHold(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error) {
+	saved, err := scanLegalHold(p.db(ctx).QueryRow(ctx, SetLegalHoldQuery, hold.FileName, hold.Reason, hold.SetBy, hold.SetAt))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to set legal hold on %s: %w", hold.FileName, err)
+	}
+	return saved, nil
+}
+
+func (p *Repository) ClearLegalHold(ctx context.Context, filename string) error {
+	tag, err := p.db(ctx).Exec(ctx, ClearLegalHoldQuery, filename)
+	if err != nil {
+		return fmt.Errorf("failed to clear legal hold on %s: %w", filename, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrLegalHoldNotFound
+	}
+	return nil
+}
+
+// Действующие защиты файла: собственный срок, сроки префиксов и юридическое удержание
+func (p *Repository) GetFileProtection(ctx context.Context, filename string, now time.Time) (*models.FileProtection, error) {
+	protection := &models.FileProtection{}
+
+	retention, err := scanFileRetention(p.db(ctx).QueryRow(ctx, GetFileRetentionQuery, filename, now))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get retention on %s: %w", filename, err)
+	}
+	protection.Retention = retention
+
+	protection.FolderRetentions, err = p.queryFolderRetentions(ctx, GetFolderRetentionsForFileQuery, filename, now)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := scanLegalHold(p.db(ctx).QueryRow(ctx, GetLegalHoldQuery, filename))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get legal hold on %s: %w", filename, err)
+	}
+	protection.LegalHold = hold
+
+	return protection, nil
+}
+
+func (p *Repository) queryFolderRetentions(ctx context.Context, query string, args ...any) ([]*models.Retention, error) {
+	rows, err := p.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query folder retentions: %w", err)
+	}
+	defer rows.Close()
+
+	var retentions []*models.Retention
+	for rows.Next() {
+		retention, err := scanFolderRetention(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan folder retention row: %w", err)
+		}
+		retentions = append(retentions, retention)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return retentions, nil
+}
+
+func scanFileRetention(row pgx.Row) (*models.Retention, error) {
+	var r models.Retention
+	if err := row.Scan(&r.FileName, &r.RetainUntil, &r.Mode, &r.SetBy, &r.SetAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func scanFolderRetention(row pgx.Row) (*models.Retention, error) {
+	var r models.Retention
+	if err := row.Scan(&r.Prefix, &r.RetainUntil, &r.Mode, &r.SetBy, &r.SetAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func scanLegalHold(row pgx.Row) (*models.LegalHold, error) {
+	var h models.LegalHold
+	if err := row.Scan(&h.FileName, &h.Reason, &h.SetBy, &h.SetAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
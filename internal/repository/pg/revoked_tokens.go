@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Отзыв всех выпущенных токенов пользователя: новое поколение токенов и момент отзыва
+func (p *Repository) RevokeUserTokens(ctx context.Context, userID uint, revokedAt time.Time) error {
+	tag, err := p.db(ctx).Exec(ctx, RevokeUserTokensQuery, userID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens of user %d: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to revoke tokens: user %d not found", userID)
+	}
+	return nil
+}
+
+// Добавление access token в список отозванных до истечения его срока действия
+func (p *Repository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := p.db(ctx).Exec(ctx, RevokeAccessTokenQuery, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	if err := p.db(ctx).QueryRow(ctx, IsAccessTokenRevokedQuery, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check access token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Удаление записей об отозванных токенах, срок действия которых истек
+func (p *Repository) DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteExpiredRevokedTokensQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Все роли, встроенные первыми
+func (p *Repository) GetRoles(ctx context.Context) ([]*models.Role, error) {
+	rows, err := p.db(ctx).Query(ctx, GetRolesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions, &role.Builtin, &role.CreatedAt, &role.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role row: %w", err)
+		}
+		roles = append(roles, &role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return roles, nil
+}
+
+// Создание пользовательской роли
+func (p *Repository) CreateRole(ctx context.Context, role *models.Role) error {
+	tag, err := p.db(ctx).Exec(ctx, CreateRoleQuery, role.Name, role.Description, role.Permissions, role.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create role %s: %w", role.Name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrRoleExists
+	}
+	role.UpdatedAt = role.CreatedAt
+	return nil
+}
+
+// Изменение пользовательской роли. Встроенные роли не изменяются
+func (p *Repository) UpdateRole(ctx context.Context, role *models.Role) error {
+	err := p.db(ctx).QueryRow(ctx, UpdateRoleQuery, role.Name, role.Description, role.Permissions, role.UpdatedAt).Scan(&role.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to update role %s: %w", role.Name, err)
+	}
+	return nil
+}
+
+// Удаление пользовательской роли, не назначенной ни одному пользователю
+func (p *Repository) DeleteRole(ctx context.Context, name string) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteRoleQuery, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return models.ErrRoleInUse
+		}
+		return fmt.Errorf("failed to delete role %s: %w", name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrRoleNotFound
+	}
+	return nil
+}
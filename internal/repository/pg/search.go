@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"tages/internal/models"
+)
+
+// ts_headline не экранирует текст, поэтому найденные слова выделяются управляющими
+// символами, а в <mark> превращаются после экранирования HTML
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+
+	nameHeadlineOptions    = `HighlightAll=true, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	contentHeadlineOptions = `MaxFragments=2, MaxWords=20, MinWords=5, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+)
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// highlightHTML экранирует фрагмент и заменяет маркеры ts_headline на <mark>
+func highlightHTML(headline string) string {
+	return highlightReplacer.Replace(html.EscapeString(headline))
+}
+
+// Полнотекстовый поиск по именам, тегам и извлеченному тексту файлов
+func (p *Repository) SearchFiles(ctx context.Context, query string, limit, offset int) (*models.SearchPage, error) {
+	rows, err := p.db(ctx).Query(ctx, SearchFilesQuery, query, limit, offset, nameHeadlineOptions, contentHeadlineOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search files: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.SearchPage{}
+	for rows.Next() {
+		result := &models.SearchResult{File: &models.FileMeta{}}
+		if err := rows.Scan(
+			&result.File.Name,
+			&result.File.CreatedAt,
+			&result.File.UpdatedAt,
+			&result.File.Tags,
+			&result.File.Attributes,
+			&result.Rank,
+			&result.NameHighlight,
+			&result.ContentHighlight,
+			&page.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan search result row: %w", err)
+		}
+		result.NameHighlight = highlightHTML(result.NameHighlight)
+		result.ContentHighlight = highlightHTML(result.ContentHighlight)
+		page.Results = append(page.Results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// Файлы, текст которых еще не извлечен или устарел после перезаписи
+func (p *Repository) GetFilesPendingIndex(ctx context.Context, limit int) ([]*models.FileMeta, error) {
+	rows, err := p.db(ctx).Query(ctx, GetFilesPendingIndexQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files pending index: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.FileMeta
+	for rows.Next() {
+		var file models.FileMeta
+		if err := rows.Scan(&file.Name, &file.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending file row: %w", err)
+		}
+		files = append(files, &file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return files, nil
+}
+
+// Сохранение извлеченного текста. indexedAt — версия файла, из которой извлечен текст
+func (p *Repository) SaveFileContent(ctx context.Context, filename, content string, indexedAt time.Time) error {
+	_, err := p.db(ctx).Exec(ctx, SaveFileContentQuery, filename, content, indexedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save content for %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package pg
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{headline: "отчет \x02квартал\x03.pdf", want: "отчет <mark>квартал</mark>.pdf"},
+		{headline: "<img src=x onerror=alert(1)> \x02mark\x03", want: "&lt;img src=x onerror=alert(1)&gt; <mark>mark</mark>"},
+		{headline: "<mark>не подсветка</mark> & \"кавычки\"", want: "&lt;mark&gt;не подсветка&lt;/mark&gt; &amp; &#34;кавычки&#34;"},
+	}
+	for _, tt := range tests {
+		if got := highlightHTML(tt.headline); got != tt.want {
+			t.Errorf("highlightHTML(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Сохраненные системные настройки по ключам
+func (p *Repository) GetSettings(ctx context.Context) (map[string]string, error) {
+	rows, err := p.db(ctx).Query(ctx, GetSettingsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan setting row: %w", err)
+		}
+		settings[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return settings, nil
+}
+
+// Сохранение системных настроек в одной транзакции
+func (p *Repository) SaveSettings(ctx context.Context, settings map[string]string, updatedBy uint, at time.Time) error {
+	tx, err := p.db(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for key, value := range settings {
+		if _, err := tx.Exec(ctx, SaveSettingQuery, key, value, at, updatedBy); err != nil {
+			return fmt.Errorf("failed to save setting %s: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit settings: %w", err)
+	}
+	return nil
+}
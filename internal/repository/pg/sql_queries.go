@@ -2,21 +2,43 @@ package pg
 
 const (
 	SaveFileMetaQuery = `
-		INSERT INTO file_meta(name, created_at, updated_at) 
-		VALUES ($1, $2, $3)
+		INSERT INTO file_meta(name, created_at, updated_at, tags, attributes, etag, size, mime_type, owner_id) 
+		VALUES ($1, $2, $3, COALESCE($4::jsonb, '[]'::jsonb), COALESCE($5::jsonb, '{}'::jsonb), $6, $7, $8,
+			NULLIF($9::integer, 0))
 		ON CONFLICT (name) DO UPDATE 
-		SET updated_at = $3
+		SET updated_at = $3,
+			tags = COALESCE($4::jsonb, file_meta.tags),
+			attributes = COALESCE($5::jsonb, file_meta.attributes),
+			etag = $6,
+			size = $7,
+			mime_type = $8,
+			owner_id = COALESCE(file_meta.owner_id, EXCLUDED.owner_id)
 	`
 	IsFileExistsQuery = `
 		SELECT EXISTS(SELECT 1 FROM file_meta WHERE name = $1)
 	`
 
 	GetFilesMetaQuery = `
-		SELECT name, created_at, updated_at 
+		SELECT name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0)
 		FROM file_meta 
+		WHERE ($1::jsonb IS NULL OR tags @> $1::jsonb)
+			AND ($2::jsonb IS NULL OR attributes @> $2::jsonb)
 		ORDER BY updated_at DESC
 	`
 
+	GetFileMetaQuery = `
+		SELECT name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0)
+		FROM file_meta 
+		WHERE name = $1
+	`
+
+	UpdateFileMetadataQuery = `
+		UPDATE file_meta
+		SET tags = $2::jsonb, attributes = $3::jsonb, updated_at = $4, etag = $5
+		WHERE name = $1
+		RETURNING name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0)
+	`
+
 	UpdateFileMetaQuery = `
 		UPDATE file_meta
 		SET created_at = $2, updated_at = $3
@@ -27,40 +49,919 @@ const (
 		DELETE FROM file_meta WHERE name = $1
 	`
 
+	RenameFileMetaQuery = `
+		UPDATE file_meta SET name = $2, updated_at = $3 WHERE name = $1
+	`
+
+	// Запросы для полнотекстового поиска
+	SearchFilesQuery = `
+		SELECT f.name, f.created_at, f.updated_at, f.tags, f.attributes,
+			ts_rank(f.search_vector, q) AS rank,
+			ts_headline('simple', f.name, q, $4),
+			ts_headline('simple', coalesce(f.content_text, ''), q, $5),
+			count(*) OVER() AS total
+		FROM file_meta f, websearch_to_tsquery('simple', $1) q
+		WHERE f.search_vector @@ q
+		ORDER BY rank DESC, f.updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	GetFilesPendingIndexQuery = `
+		SELECT name, updated_at
+		FROM file_meta
+		WHERE content_indexed_at IS NULL OR content_indexed_at < updated_at
+		ORDER BY updated_at
+		LIMIT $1
+	`
+
+	SaveFileContentQuery = `
+		UPDATE file_meta
+		SET content_text = $2, content_indexed_at = $3
+		WHERE name = $1
+	`
+
 	// Запросы для пользователей
 	CreateUserQuery = `
-		INSERT INTO users(email, password, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users(email, password, role, email_verified, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
 	GetUserByEmailQuery = `
-		SELECT id, email, password, created_at, updated_at 
+		SELECT id, email, password, role, created_at, updated_at, tokens_valid_after, token_version,
+			mfa_enabled, totp_secret, totp_last_step, mfa_recovery_codes,
+			email_verified, verification_sent_at
 		FROM users 
 		WHERE email = $1
 	`
 
 	GetUserByIDQuery = `
-		SELECT id, email, password, created_at, updated_at 
+		SELECT id, email, password, role, created_at, updated_at, tokens_valid_after, token_version,
+			mfa_enabled, totp_secret, totp_last_step, mfa_recovery_codes,
+			email_verified, verification_sent_at
 		FROM users 
 		WHERE id = $1
 	`
 
-	// Запросы для refresh токенов
+	GetUsersQuery = `
+		SELECT id, email, password, role, created_at, updated_at, tokens_valid_after, token_version,
+			mfa_enabled, totp_secret, totp_last_step, mfa_recovery_codes,
+			email_verified, verification_sent_at, count(*) OVER()
+		FROM users
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`
+
+	SetUserRoleQuery = `
+		UPDATE users SET role = $2, updated_at = $3 WHERE id = $1
+	`
+
+	// Подтверждение email
+	MarkEmailVerifiedQuery = `
+		UPDATE users SET email_verified = TRUE, updated_at = $2 WHERE id = $1
+	`
+
+	// Письмо отправляется, только если предыдущее было не позже $3
+	MarkVerificationSentQuery = `
+		UPDATE users SET verification_sent_at = $2
+		WHERE id = $1 AND NOT email_verified
+			AND (verification_sent_at IS NULL OR verification_sent_at <= $3)
+	`
+
+	// Двухфакторная аутентификация
+	SetTOTPSecretQuery = `
+		UPDATE users SET totp_secret = $2, updated_at = $3 WHERE id = $1 AND NOT mfa_enabled
+	`
+
+	EnableMFAQuery = `
+		UPDATE users
+		SET mfa_enabled = TRUE, totp_last_step = $2, mfa_recovery_codes = $3, updated_at = $4
+		WHERE id = $1 AND totp_secret <> '' AND NOT mfa_enabled
+	`
+
+	DisableMFAQuery = `
+		UPDATE users
+		SET mfa_enabled = FALSE, totp_secret = '', totp_last_step = 0, mfa_recovery_codes = '{}', updated_at = $2
+		WHERE id = $1
+	`
+
+	SetRecoveryCodesQuery = `
+		UPDATE users SET mfa_recovery_codes = $2, updated_at = $3 WHERE id = $1 AND mfa_enabled
+	`
+
+	UseRecoveryCodeQuery = `
+		UPDATE users SET mfa_recovery_codes = array_remove(mfa_recovery_codes, $2)
+		WHERE id = $1 AND $2 = ANY(mfa_recovery_codes)
+	`
+
+	UseTOTPStepQuery = `
+		UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2
+	`
+
+	RevokeUserTokensQuery = `
+		UPDATE users SET tokens_valid_after = $2, token_version = token_version + 1 WHERE id = $1
+	`
+
+	// Список отозванных access токенов
+	RevokeAccessTokenQuery = `
+		INSERT INTO revoked_tokens(jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	IsAccessTokenRevokedQuery = `
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	`
+
+	DeleteExpiredRevokedTokensQuery = `
+		DELETE FROM revoked_tokens WHERE expires_at <= $1
+	`
+
+	// Запросы для refresh токенов (сессий пользователей)
 	SaveRefreshTokenQuery = `
-		INSERT INTO refresh_tokens(user_id, token, expires_at) 
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id) DO UPDATE 
-		SET token = $2, expires_at = $3
+		INSERT INTO refresh_tokens(user_id, token_hash, expires_at, device_name, ip, user_agent, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id
 	`
 
 	GetRefreshTokenQuery = `
-		SELECT id, user_id, token, expires_at 
+		SELECT id, user_id, token_hash, expires_at, device_name, ip, user_agent, created_at, last_used_at
 		FROM refresh_tokens 
-		WHERE token = $1 AND expires_at > NOW()
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	// Замена токена в той же сессии. Старый токен должен быть действующим,
+	// поэтому из двух одновременных обновлений успешно только одно.
+	// Хеш старого токена запоминается для обнаружения повторного использования
+	RotateRefreshTokenQuery = `
+		WITH rotated AS (
+			UPDATE refresh_tokens
+			SET token_hash = $2, expires_at = $3, ip = $4, user_agent = $5, last_used_at = $6
+			WHERE token_hash = $1 AND expires_at > $6
+			RETURNING id, user_id, token_hash, expires_at, device_name, ip, user_agent, created_at, last_used_at
+		), remembered AS (
+			INSERT INTO rotated_refresh_tokens(token_hash, session_id, rotated_at)
+			SELECT $1, id, $6 FROM rotated
+		)
+		SELECT id, user_id, token_hash, expires_at, device_name, ip, user_agent, created_at, last_used_at
+		FROM rotated
+	`
+
+	// Сессия, в которой токен с этим хешем уже был заменен
+	GetRotatedRefreshTokenQuery = `
+		SELECT r.id, r.user_id, r.token_hash, r.expires_at, r.device_name, r.ip, r.user_agent, r.created_at, r.last_used_at
+		FROM rotated_refresh_tokens rt
+		JOIN refresh_tokens r ON r.id = rt.session_id
+		WHERE rt.token_hash = $1
 	`
 
 	DeleteRefreshTokenQuery = `
-		DELETE FROM refresh_tokens WHERE token = $1
+		DELETE FROM refresh_tokens WHERE token_hash = $1
+	`
+
+	GetUserSessionsQuery = `
+		SELECT id, user_id, token_hash, expires_at, device_name, ip, user_agent, created_at, last_used_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY last_used_at DESC, id DESC
+	`
+
+	DeleteUserSessionQuery = `
+		DELETE FROM refresh_tokens WHERE user_id = $1 AND id = $2
+	`
+
+	// $2 — сессия, которую нужно оставить (0 — удалить все)
+	DeleteUserSessionsQuery = `
+		DELETE FROM refresh_tokens WHERE user_id = $1 AND id <> $2
+	`
+
+	DeleteExpiredRefreshTokensQuery = `
+		DELETE FROM refresh_tokens WHERE expires_at <= $1
+	`
+
+	// Запросы для сброса пароля
+	SetPasswordQuery = `
+		UPDATE users SET password = $2, updated_at = $3 WHERE id = $1
+	`
+
+	CreatePasswordResetTokenQuery = `
+		INSERT INTO password_reset_tokens(token_hash, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	CountPasswordResetTokensQuery = `
+		SELECT count(*) FROM password_reset_tokens WHERE user_id = $1 AND created_at > $2
+	`
+
+	// Токен удаляется при использовании, поэтому принимается только один раз
+	ConsumePasswordResetTokenQuery = `
+		DELETE FROM password_reset_tokens
+		WHERE token_hash = $1 AND expires_at > $2
+		RETURNING user_id, token_hash, expires_at, created_at
+	`
+
+	DeletePasswordResetTokensQuery = `
+		DELETE FROM password_reset_tokens WHERE user_id = $1
+	`
+
+	DeleteExpiredPasswordResetTokensQuery = `
+		DELETE FROM password_reset_tokens WHERE expires_at <= $1
+	`
+
+	// Запросы для защиты от подбора паролей. Счетчик обнуляется, если с последней
+	// неудачи или окончания блокировки прошло больше времени, чем $3
+	RecordLoginFailureQuery = `
+		INSERT INTO login_attempts(subject, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE
+				WHEN GREATEST(login_attempts.last_failure_at, login_attempts.locked_until) < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures
+	`
+
+	LockLoginAttemptsQuery = `
+		UPDATE login_attempts SET locked_until = GREATEST(locked_until, $2) WHERE subject = $1
+	`
+
+	LoginLockedUntilQuery = `
+		SELECT max(locked_until) FROM login_attempts WHERE subject = ANY($1) AND locked_until > $2
+	`
+
+	DeleteLoginAttemptsQuery = `
+		DELETE FROM login_attempts WHERE subject = $1
+	`
+
+	DeleteExpiredLoginAttemptsQuery = `
+		DELETE FROM login_attempts WHERE GREATEST(last_failure_at, locked_until) < $1
+	`
+
+	// Запросы для ролей
+	GetRolesQuery = `
+		SELECT name, description, permissions, builtin, created_at, updated_at
+		FROM roles
+		ORDER BY builtin DESC, name
+	`
+
+	CreateRoleQuery = `
+		INSERT INTO roles(name, description, permissions, builtin, created_at, updated_at)
+		VALUES ($1, $2, $3, FALSE, $4, $4)
+		ON CONFLICT (name) DO NOTHING
+	`
+
+	// Встроенные роли не изменяются
+	UpdateRoleQuery = `
+		UPDATE roles SET description = $2, permissions = $3, updated_at = $4
+		WHERE name = $1 AND NOT builtin
+		RETURNING created_at
+	`
+
+	DeleteRoleQuery = `
+		DELETE FROM roles WHERE name = $1 AND NOT builtin
+	`
+
+	// Запросы для системных настроек
+	GetSettingsQuery = `
+		SELECT key, value FROM settings
+	`
+
+	SaveSettingQuery = `
+		INSERT INTO settings(key, value, updated_at, updated_by)
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at, updated_by = EXCLUDED.updated_by
+	`
+
+	// Запросы для персональных токенов доступа
+	CountAPITokensQuery = `
+		SELECT count(*) FROM api_tokens WHERE user_id = $1
+	`
+
+	CreateAPITokenQuery = `
+		INSERT INTO api_tokens(user_id, name, token_hash, prefix, scopes, allowed_ips, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	apiTokensSelect = `
+		SELECT id, user_id, name, token_hash, prefix, scopes, allowed_ips, expires_at, created_at, last_used_at, last_used_ip
+		FROM api_tokens
+	`
+
+	GetAPITokensQuery = apiTokensSelect + `
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	GetAPITokenByHashQuery = apiTokensSelect + `
+		WHERE token_hash = $1
+	`
+
+	DeleteAPITokenQuery = `
+		DELETE FROM api_tokens WHERE user_id = $1 AND id = $2
+	`
+
+	// Время использования обновляется не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+	TouchAPITokenQuery = `
+		UPDATE api_tokens SET last_used_at = $2, last_used_ip = $3
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute' OR last_used_ip <> $3)
+	`
+
+	// Запросы для журнала аудита
+	SaveAuditEventQuery = `
+		INSERT INTO audit_events(occurred_at, action, outcome, actor_id, actor_email,
+			ip, user_agent, request_id, target, details)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''),
+			NULLIF($8, ''), NULLIF($9, ''), $10)
+		RETURNING id
+	`
+
+	auditEventsSelect = `
+		SELECT id, occurred_at, action, outcome, COALESCE(actor_id, 0), COALESCE(actor_email, ''),
+			COALESCE(ip, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''),
+			COALESCE(target, ''), details
+		FROM audit_events
+		WHERE ($1::int IS NULL OR actor_id = $1)
+			AND ($2::text IS NULL OR action = $2)
+			AND ($3::text IS NULL OR target = $3)
+			AND ($4::text IS NULL OR outcome = $4)
+			AND ($5::timestamp IS NULL OR occurred_at >= $5)
+			AND ($6::timestamp IS NULL OR occurred_at < $6)
+	`
+
+	GetAuditEventsQuery = auditEventsSelect + `
+		ORDER BY id DESC
+		LIMIT $7 OFFSET $8
+	`
+
+	ExportAuditEventsQuery = auditEventsSelect + `
+		ORDER BY id
+	`
+
+	DeleteAuditEventsBeforeQuery = `
+		DELETE FROM audit_events WHERE occurred_at < $1
+	`
+
+	// Запросы для ленты изменений файлов
+	SaveFileEventQuery = `
+		INSERT INTO file_events(occurred_at, type, file_name, actor_id)
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+		RETURNING id
+	`
+
+	GetFileEventsSinceQuery = `
+		SELECT id, occurred_at, type, file_name, COALESCE(actor_id, 0)
+		FROM file_events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	DeleteFileEventsBeforeQuery = `
+		DELETE FROM file_events WHERE occurred_at < $1
+	`
+
+	// Блокировка снимается при завершении транзакции
+	AcquireFileMutexQuery = `
+		SELECT pg_advisory_xact_lock($1, hashtext($2))
+	`
+
+	NotifyQuery = `
+		SELECT pg_notify($1, $2)
+	`
+
+	// Запросы для вебхуков
+	CreateWebhookQuery = `
+		INSERT INTO webhooks(user_id, url, secret, events, system, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	webhooksSelect = `
+		SELECT id, user_id, url, secret, events, system, active, created_at
+		FROM webhooks
+	`
+
+	GetWebhooksQuery = webhooksSelect + `
+		WHERE ($1::int IS NULL OR user_id = $1)
+		ORDER BY id
+	`
+
+	GetWebhookQuery = webhooksSelect + `
+		WHERE id = $1
+	`
+
+	DeleteWebhookQuery = `
+		DELETE FROM webhooks WHERE id = $1
+	`
+
+	EnqueueWebhookDeliveriesQuery = `
+		INSERT INTO webhook_deliveries(webhook_id, event_type, payload, next_attempt_at, created_at)
+		SELECT id, $1, $2, $4, $4
+		FROM webhooks
+		WHERE active
+			AND (events = '[]'::jsonb OR events @> jsonb_build_array($1::text))
+			AND (system OR NOT $3)
+	`
+
+	ClaimWebhookDeliveriesQuery = `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = $2
+			FROM due
+			WHERE d.id = due.id
+			RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, d.created_at
+		)
+		SELECT c.id, c.webhook_id, c.event_type, c.payload, c.attempts, c.created_at, w.url, w.secret
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+	`
+
+	UpdateWebhookDeliveryQuery = `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+			response_status = NULLIF($6, 0), last_error = NULLIF($7, '')
+		WHERE id = $1
+	`
+
+	GetWebhookDeliveriesQuery = `
+		SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+			last_attempt_at, COALESCE(response_status, 0), COALESCE(last_error, ''), created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	// Запросы для избранных и недавних файлов
+	StarFileQuery = `
+		INSERT INTO file_stars (user_id, file_name, starred_at)
+		SELECT $1, name, $3 FROM file_meta WHERE name = $2
+		ON CONFLICT (user_id, file_name) DO NOTHING
+	`
+
+	UnstarFileQuery = `
+		DELETE FROM file_stars WHERE user_id = $1 AND file_name = $2
+	`
+
+	GetStarredFilesQuery = `
+		SELECT f.name, f.created_at, f.updated_at, f.tags, f.attributes, s.starred_at,
+			count(*) OVER()
+		FROM file_stars s
+		JOIN file_meta f ON f.name = s.file_name
+		WHERE s.user_id = $1
+		ORDER BY s.starred_at DESC, f.name
+		LIMIT $2 OFFSET $3
+	`
+
+	SaveRecentFileQuery = `
+		INSERT INTO file_recents (user_id, file_name, action, accessed_at)
+		SELECT $1, name, $3, $4 FROM file_meta WHERE name = $2
+		ON CONFLICT (user_id, file_name) DO UPDATE
+		SET action = EXCLUDED.action, accessed_at = EXCLUDED.accessed_at
+	`
+
+	TrimRecentFilesQuery = `
+		DELETE FROM file_recents
+		WHERE user_id = $1 AND file_name NOT IN (
+			SELECT file_name FROM file_recents
+			WHERE user_id = $1
+			ORDER BY accessed_at DESC
+			LIMIT $2
+		)
+	`
+
+	GetRecentFilesQuery = `
+		SELECT f.name, f.created_at, f.updated_at, f.tags, f.attributes, r.action, r.accessed_at,
+			count(*) OVER()
+		FROM file_recents r
+		JOIN file_meta f ON f.name = r.file_name
+		WHERE r.user_id = $1
+		ORDER BY r.accessed_at DESC, f.name
+		LIMIT $2 OFFSET $3
+	`
+
+	// Запросы для комментариев к файлам
+	CreateCommentQuery = `
+		INSERT INTO file_comments (file_name, parent_id, author_id, body, created_at, updated_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $5)
+		RETURNING id
+	`
+
+	SaveCommentMentionsQuery = `
+		INSERT INTO file_comment_mentions (comment_id, user_id)
+		SELECT $1, id FROM users WHERE lower(email) = ANY($2::text[])
+		ON CONFLICT DO NOTHING
+	`
+
+	DeleteCommentMentionsQuery = `
+		DELETE FROM file_comment_mentions WHERE comment_id = $1
+	`
+
+	commentColumns = `
+		c.id, c.file_name, COALESCE(c.parent_id, 0), c.author_id, u.email, c.body,
+		COALESCE((SELECT array_agg(mu.email ORDER BY mu.email)
+			FROM file_comment_mentions m
+			JOIN users mu ON mu.id = m.user_id
+			WHERE m.comment_id = c.id), '{}'),
+		c.resolved, COALESCE(c.resolved_by, 0), c.resolved_at, c.created_at, c.updated_at
+	`
+
+	GetCommentQuery = `
+		SELECT ` + commentColumns + `
+		FROM file_comments c
+		JOIN users u ON u.id = c.author_id
+		WHERE c.id = $1
+	`
+
+	GetFileCommentsQuery = `
+		SELECT ` + commentColumns + `
+		FROM file_comments c
+		JOIN users u ON u.id = c.author_id
+		WHERE c.file_name = $1
+		ORDER BY c.created_at, c.id
+	`
+
+	GetMentionedCommentsQuery = `
+		SELECT ` + commentColumns + `, count(*) OVER()
+		FROM file_comment_mentions fm
+		JOIN file_comments c ON c.id = fm.comment_id
+		JOIN users u ON u.id = c.author_id
+		WHERE fm.user_id = $1
+		ORDER BY c.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	UpdateCommentBodyQuery = `
+		UPDATE file_comments SET body = $2, updated_at = $3 WHERE id = $1
+	`
+
+	SetCommentResolvedQuery = `
+		UPDATE file_comments
+		SET resolved = $2,
+			resolved_by = CASE WHEN $2 THEN $3::integer END,
+			resolved_at = CASE WHEN $2 THEN $4::timestamp END
+		WHERE id = $1
+	`
+
+	DeleteCommentQuery = `
+		DELETE FROM file_comments WHERE id = $1
+	`
+
+	// Запросы для блокировок файлов
+	AcquireLockQuery = `
+		INSERT INTO file_locks (file_name, token, owner_id, acquired_at, expires_at)
+		SELECT name, $2, $3, $4, $5 FROM file_meta WHERE name = $1
+		ON CONFLICT (file_name) DO UPDATE
+		SET token = EXCLUDED.token,
+			owner_id = EXCLUDED.owner_id,
+			acquired_at = EXCLUDED.acquired_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE file_locks.expires_at <= EXCLUDED.acquired_at
+			OR file_locks.owner_id = EXCLUDED.owner_id
+		RETURNING file_name, token, owner_id, acquired_at, expires_at
+	`
+
+	RefreshLockQuery = `
+		UPDATE file_locks SET expires_at = $3
+		WHERE file_name = $1 AND token = $2 AND expires_at > $4
+		RETURNING file_name, token, owner_id, acquired_at, expires_at
+	`
+
+	ReleaseLockQuery = `
+		DELETE FROM file_locks WHERE file_name = $1 AND token = $2 AND expires_at > $3
+	`
+
+	BreakLockQuery = `
+		DELETE FROM file_locks WHERE file_name = $1 AND expires_at > $2
+		RETURNING file_name, owner_id, acquired_at, expires_at
+	`
+
+	GetLockQuery = `
+		SELECT file_name, token, owner_id, acquired_at, expires_at
+		FROM file_locks
+		WHERE file_name = $1 AND expires_at > $2
+	`
+
+	GetActiveLocksQuery = `
+		SELECT file_name, owner_id, acquired_at, expires_at
+		FROM file_locks
+		WHERE expires_at > $1
+	`
+
+	// Запросы для сроков хранения и юридических удержаний.
+	// Условие замены срока: прежний срок истек; или режим governance и срок не сокращается
+	// (сокращать может администратор, $6); или новый режим compliance и срок не сокращается
+	SetFileRetentionQuery = `
+		INSERT INTO file_retention (file_name, retain_until, mode, set_by, set_at)
+		SELECT name, $2, $3, $4, $5 FROM file_meta WHERE name = $1
+		ON CONFLICT (file_name) DO UPDATE
+		SET retain_until = EXCLUDED.retain_until,
+			mode = EXCLUDED.mode,
+			set_by = EXCLUDED.set_by,
+			set_at = EXCLUDED.set_at
+		WHERE file_retention.retain_until <= EXCLUDED.set_at
+			OR (file_retention.mode = 'governance'
+				AND ($6::boolean OR EXCLUDED.retain_until >= file_retention.retain_until))
+			OR (EXCLUDED.mode = 'compliance' AND EXCLUDED.retain_until >= file_retention.retain_until)
+		RETURNING file_name, retain_until, mode, COALESCE(set_by, 0), set_at
+	`
+
+	ClearFileRetentionQuery = `
+		DELETE FROM file_retention
+		WHERE file_name = $1 AND (retain_until <= $2 OR (mode = 'governance' AND $3::boolean))
+	`
+
+	GetFileRetentionQuery = `
+		SELECT file_name, retain_until, mode, COALESCE(set_by, 0), set_at
+		FROM file_retention
+		WHERE file_name = $1 AND retain_until > $2
+	`
+
+	SetFolderRetentionQuery = `
+		INSERT INTO folder_retention (prefix, retain_until, mode, set_by, set_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (prefix) DO UPDATE
+		SET retain_until = EXCLUDED.retain_until,
+			mode = EXCLUDED.mode,
+			set_by = EXCLUDED.set_by,
+			set_at = EXCLUDED.set_at
+		WHERE folder_retention.retain_until <= EXCLUDED.set_at
+			OR (folder_retention.mode = 'governance'
+				AND ($6::boolean OR EXCLUDED.retain_until >= folder_retention.retain_until))
+			OR (EXCLUDED.mode = 'compliance' AND EXCLUDED.retain_until >= folder_retention.retain_until)
+		RETURNING prefix, retain_until, mode, COALESCE(set_by, 0), set_at
+	`
+
+	ClearFolderRetentionQuery = `
+		DELETE FROM folder_retention
+		WHERE prefix = $1 AND (retain_until <= $2 OR (mode = 'governance' AND $3::boolean))
+	`
+
+	GetFolderRetentionQuery = `
+		SELECT prefix, retain_until, mode, COALESCE(set_by, 0), set_at
+		FROM folder_retention
+		WHERE prefix = $1 AND retain_until > $2
+	`
+
+	GetFolderRetentionsQuery = `
+		SELECT prefix, retain_until, mode, COALESCE(set_by, 0), set_at
+		FROM folder_retention
+		WHERE retain_until > $1
+		ORDER BY prefix
+	`
+
+	GetFolderRetentionsForFileQuery = `
+		SELECT prefix, retain_until, mode, COALESCE(set_by, 0), set_at
+		FROM folder_retention
+		WHERE starts_with($1, prefix) AND retain_until > $2
+		ORDER BY prefix
+	`
+
+	SetLegalHoldQuery = `
+		INSERT INTO legal_holds (file_name, reason, set_by, set_at)
+		SELECT name, $2, $3, $4 FROM file_meta WHERE name = $1
+		ON CONFLICT (file_name) DO UPDATE
+		SET reason = EXCLUDED.reason, set_by = EXCLUDED.set_by, set_at = EXCLUDED.set_at
+		RETURNING file_name, reason, COALESCE(set_by, 0), set_at
+	`
+
+	ClearLegalHoldQuery = `
+		DELETE FROM legal_holds WHERE file_name = $1
+	`
+
+	GetLegalHoldQuery = `
+		SELECT file_name, reason, COALESCE(set_by, 0), set_at
+		FROM legal_holds
+		WHERE file_name = $1
+	`
+
+	// Запросы для правил жизненного цикла и корзины
+	TouchFileAccessQuery = `
+		UPDATE file_meta SET accessed_at = $2 WHERE name = $1 AND accessed_at < $2
+	`
+
+	CreateLifecycleRuleQuery = `
+		INSERT INTO lifecycle_rules (name, owner_id, prefix, tag, condition, after_days, action, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING id
+	`
+
+	UpdateLifecycleRuleQuery = `
+		UPDATE lifecycle_rules
+		SET name = $2, prefix = $3, tag = $4, condition = $5, after_days = $6, action = $7, enabled = $8, updated_at = $9
+		WHERE id = $1
+	`
+
+	DeleteLifecycleRuleQuery = `
+		DELETE FROM lifecycle_rules WHERE id = $1
+	`
+
+	GetLifecycleRuleQuery = `
+		SELECT id, name, COALESCE(owner_id, 0), prefix, tag, condition, after_days, action, enabled,
+			created_at, updated_at, last_run_at
+		FROM lifecycle_rules
+		WHERE id = $1
+	`
+
+	// $1 = 0 — правила всех пользователей
+	GetLifecycleRulesQuery = `
+		SELECT id, name, COALESCE(owner_id, 0), prefix, tag, condition, after_days, action, enabled,
+			created_at, updated_at, last_run_at
+		FROM lifecycle_rules
+		WHERE $1::integer = 0 OR owner_id = $1
+		ORDER BY id
+	`
+
+	GetEnabledLifecycleRulesQuery = `
+		SELECT id, name, COALESCE(owner_id, 0), prefix, tag, condition, after_days, action, enabled,
+			created_at, updated_at, last_run_at
+		FROM lifecycle_rules
+		WHERE enabled
+		ORDER BY id
+	`
+
+	// Правило забирает тот экземпляр, который первым отметит запуск
+	ClaimLifecycleRuleQuery = `
+		UPDATE lifecycle_rules SET last_run_at = $2
+		WHERE id = $1 AND enabled AND (last_run_at IS NULL OR last_run_at <= $3)
+	`
+
+	// Файлы выбираются по возрастанию имени после $5, чтобы пропущенные не попадали в следующую пачку.
+	// Тег проверяется через @>: индекс jsonb_path_ops не поддерживает оператор ?.
+	// $7 — владелец файлов, 0 — файлы всех пользователей
+	GetLifecycleCandidatesQuery = `
+		SELECT name, etag, updated_at, accessed_at, count(*) OVER()
+		FROM file_meta
+		WHERE starts_with(name, $1)
+			AND ($2 = '' OR tags @> jsonb_build_array($2::text))
+			AND (CASE WHEN $3 = 'idle' THEN accessed_at ELSE updated_at END) < $4
+			AND name > $5
+			AND ($7::integer = 0 OR owner_id = $7)
+		ORDER BY name
+		LIMIT $6
+	`
+
+	SaveLifecycleActionQuery = `
+		INSERT INTO lifecycle_actions (rule_id, file_name, action, outcome, error, applied_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	GetLifecycleActionsQuery = `
+		SELECT id, rule_id, file_name, action, outcome, error, applied_at, count(*) OVER()
+		FROM lifecycle_actions
+		WHERE rule_id = $1
+		ORDER BY applied_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	DeleteLifecycleActionsBeforeQuery = `
+		DELETE FROM lifecycle_actions WHERE applied_at < $1
+	`
+
+	SaveTrashedFileQuery = `
+		INSERT INTO file_trash (name, created_at, updated_at, tags, attributes, etag, size, mime_type, owner_id,
+			trashed_at, trashed_by, rule_id)
+		VALUES ($1, $2, $3, COALESCE($4::jsonb, '[]'::jsonb), COALESCE($5::jsonb, '{}'::jsonb), $6, $7, $8,
+			NULLIF($9::integer, 0), $10, NULLIF($11::integer, 0), NULLIF($12::bigint, 0))
+		RETURNING id
+	`
+
+	GetTrashedFileQuery = `
+		SELECT id, name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0), trashed_at,
+			COALESCE(trashed_by, 0), COALESCE(rule_id, 0)
+		FROM file_trash
+		WHERE id = $1
+	`
+
+	GetTrashedFilesQuery = `
+		SELECT id, name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0), trashed_at,
+			COALESCE(trashed_by, 0), COALESCE(rule_id, 0), count(*) OVER()
+		FROM file_trash
+		ORDER BY trashed_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	GetExpiredTrashQuery = `
+		SELECT id, name, created_at, updated_at, tags, attributes, etag, size, mime_type, COALESCE(owner_id, 0), trashed_at,
+			COALESCE(trashed_by, 0), COALESCE(rule_id, 0)
+		FROM file_trash
+		WHERE trashed_at < $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	DeleteTrashedFileQuery = `
+		DELETE FROM file_trash WHERE id = $1
+	`
+
+	// Запросы для статистики занятого места
+	GetUnmeasuredFilesQuery = `
+		SELECT name FROM file_meta
+		WHERE mime_type = '' AND name > $1
+		ORDER BY name
+		LIMIT $2
+	`
+
+	SetFileStatsQuery = `
+		UPDATE file_meta SET size = $2, mime_type = $3 WHERE name = $1 AND mime_type = ''
+	`
+
+	// День занимается, если снимка еще нет или предыдущий запуск завис до $3
+	ClaimUsageSnapshotQuery = `
+		INSERT INTO usage_snapshot_runs (snapshot_date, started_at)
+		VALUES ($1, $2)
+		ON CONFLICT (snapshot_date) DO UPDATE
+		SET started_at = EXCLUDED.started_at
+		WHERE usage_snapshot_runs.completed_at IS NULL AND usage_snapshot_runs.started_at < $3
+		RETURNING snapshot_date
+	`
+
+	DeleteUsageSnapshotQuery = `
+		DELETE FROM usage_snapshots WHERE snapshot_date = $1
+	`
+
+	SaveUsageSnapshotQuery = `
+		INSERT INTO usage_snapshots (snapshot_date, owner_id, mime_type, bytes, files)
+		SELECT $1, COALESCE(owner_id, 0), mime_type, sum(size), count(*)
+		FROM file_meta
+		GROUP BY COALESCE(owner_id, 0), mime_type
+	`
+
+	CompleteUsageSnapshotQuery = `
+		INSERT INTO usage_snapshot_runs (snapshot_date, started_at, completed_at)
+		VALUES ($1, $2, $2)
+		ON CONFLICT (snapshot_date) DO UPDATE SET completed_at = EXCLUDED.completed_at
+	`
+
+	ReleaseUsageSnapshotQuery = `
+		DELETE FROM usage_snapshot_runs WHERE snapshot_date = $1 AND completed_at IS NULL
+	`
+
+	DeleteUsageSnapshotsBeforeQuery = `
+		WITH runs AS (
+			DELETE FROM usage_snapshot_runs WHERE snapshot_date < $1
+		)
+		DELETE FROM usage_snapshots WHERE snapshot_date < $1
+	`
+
+	// Дни без файлов попадают в ряд с нулями благодаря записи о запуске
+	GetUsageTotalHistoryQuery = `
+		SELECT r.snapshot_date, COALESCE(sum(s.bytes), 0)::bigint, COALESCE(sum(s.files), 0)::bigint
+		FROM usage_snapshot_runs r
+		LEFT JOIN usage_snapshots s ON s.snapshot_date = r.snapshot_date
+			AND ($3::integer IS NULL OR s.owner_id = $3)
+		WHERE r.completed_at IS NOT NULL AND r.snapshot_date BETWEEN $1 AND $2
+		GROUP BY r.snapshot_date
+		ORDER BY r.snapshot_date
+	`
+
+	GetUsageUserHistoryQuery = `
+		SELECT s.snapshot_date, s.owner_id, COALESCE(u.email, ''), sum(s.bytes)::bigint, sum(s.files)::bigint
+		FROM usage_snapshots s
+		LEFT JOIN users u ON u.id = s.owner_id
+		WHERE s.snapshot_date BETWEEN $1 AND $2
+		GROUP BY s.snapshot_date, s.owner_id, u.email
+		ORDER BY s.snapshot_date, sum(s.bytes) DESC
+	`
+
+	GetUsageTypeHistoryQuery = `
+		SELECT snapshot_date, mime_type, sum(bytes)::bigint, sum(files)::bigint
+		FROM usage_snapshots
+		WHERE snapshot_date BETWEEN $1 AND $2 AND ($3::integer IS NULL OR owner_id = $3)
+		GROUP BY snapshot_date, mime_type
+		ORDER BY snapshot_date, sum(bytes) DESC
+	`
+
+	GetLargestFilesQuery = `
+		SELECT f.name, f.size, f.mime_type, COALESCE(f.owner_id, 0), COALESCE(u.email, ''), f.updated_at
+		FROM file_meta f
+		LEFT JOIN users u ON u.id = f.owner_id
+		ORDER BY f.size DESC, f.name
+		LIMIT $1
+	`
+
+	GetUserUsageQuery = `
+		SELECT COALESCE(f.owner_id, 0), COALESCE(u.email, ''), sum(f.size)::bigint, count(*)
+		FROM file_meta f
+		LEFT JOIN users u ON u.id = f.owner_id
+		GROUP BY COALESCE(f.owner_id, 0), u.email
+		ORDER BY sum(f.size) DESC
+		LIMIT $1
+	`
+
+	GetTypeUsageQuery = `
+		SELECT mime_type, sum(size)::bigint, count(*)
+		FROM file_meta
+		GROUP BY mime_type
+		ORDER BY sum(size) DESC
 	`
 )
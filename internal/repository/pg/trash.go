@@ -0,0 +1,130 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Запись файла в корзину; заполняет идентификатор записи
+func (p *Repository) SaveTrashedFile(ctx context.Context, file *models.TrashedFile) error {
+	tags, attributes, err := encodeFileMetadata(file.Tags, file.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for %s: %w", file.Name, err)
+	}
+
+	err = p.db(ctx).QueryRow(ctx, SaveTrashedFileQuery,
+		file.Name,
+		file.CreatedAt,
+		file.UpdatedAt,
+		tags,
+		attributes,
+		file.ETag,
+		file.Size,
+		file.MimeType,
+		file.OwnerID,
+		file.TrashedAt,
+		file.TrashedBy,
+		file.RuleID).Scan(&file.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save trashed file %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func (p *Repository) GetTrashedFile(ctx context.Context, id int64) (*models.TrashedFile, error) {
+	file, err := scanTrashedFile(p.db(ctx).QueryRow(ctx, GetTrashedFileQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrTrashedFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get trashed file %d: %w", id, err)
+	}
+	return file, nil
+}
+
+func (p *Repository) GetTrashedFiles(ctx context.Context, limit, offset int) (*models.TrashPage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetTrashedFilesQuery, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trashed files: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.TrashPage{}
+	for rows.Next() {
+		file, err := scanTrashedFile(rows, &page.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trashed file row: %w", err)
+		}
+		page.Files = append(page.Files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// Файлы, удаленные в корзину раньше before, с идентификатором больше after
+func (p *Repository) GetExpiredTrash(ctx context.Context, before time.Time, after int64, limit int) ([]*models.TrashedFile, error) {
+	rows, err := p.db(ctx).Query(ctx, GetExpiredTrashQuery, before, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired trash: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.TrashedFile
+	for rows.Next() {
+		file, err := scanTrashedFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trashed file row: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return files, nil
+}
+
+func (p *Repository) DeleteTrashedFile(ctx context.Context, id int64) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteTrashedFileQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete trashed file %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrTrashedFileNotFound
+	}
+	return nil
+}
+
+func scanTrashedFile(row pgx.Row, extra ...any) (*models.TrashedFile, error) {
+	var f models.TrashedFile
+	dest := append([]any{
+		&f.ID,
+		&f.Name,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.Tags,
+		&f.Attributes,
+		&f.ETag,
+		&f.Size,
+		&f.MimeType,
+		&f.OwnerID,
+		&f.TrashedAt,
+		&f.TrashedBy,
+		&f.RuleID,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,206 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Файлы, размер и тип которых еще не определены, с именем больше after
+func (p *Repository) GetUnmeasuredFiles(ctx context.Context, after string, limit int) ([]string, error) {
+	rows, err := p.db(ctx).Query(ctx, GetUnmeasuredFilesQuery, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unmeasured files: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan file name: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return names, nil
+}
+
+// Сохранение размера и типа; файл, перезаписанный за это время, не изменяется
+func (p *Repository) SetFileStats(ctx context.Context, filename string, size int64, mimeType string) error {
+	if _, err := p.db(ctx).Exec(ctx, SetFileStatsQuery, filename, size, mimeType); err != nil {
+		return fmt.Errorf("failed to set stats of %s: %w", filename, err)
+	}
+	return nil
+}
+
+// Занимает день для снимка. false — снимок за день уже сделан или делается другим экземпляром
+func (p *Repository) ClaimUsageSnapshot(ctx context.Context, day, now, staleBefore time.Time) (bool, error) {
+	var claimed time.Time
+	err := p.db(ctx).QueryRow(ctx, ClaimUsageSnapshotQuery, day, now, staleBefore).Scan(&claimed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim usage snapshot: %w", err)
+	}
+	return true, nil
+}
+
+// Снимок занятого места за день; повторный снимок за тот же день заменяет прежний
+func (p *Repository) SaveUsageSnapshot(ctx context.Context, day, completedAt time.Time) error {
+	tx, err := p.db(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, DeleteUsageSnapshotQuery, day); err != nil {
+		return fmt.Errorf("failed to delete previous usage snapshot: %w", err)
+	}
+	if _, err := tx.Exec(ctx, SaveUsageSnapshotQuery, day); err != nil {
+		return fmt.Errorf("failed to save usage snapshot: %w", err)
+	}
+	if _, err := tx.Exec(ctx, CompleteUsageSnapshotQuery, day, completedAt); err != nil {
+		return fmt.Errorf("failed to complete usage snapshot: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit usage snapshot: %w", err)
+	}
+	return nil
+}
+
+// Освобождает день после неудачного снимка, чтобы его повторили
+func (p *Repository) ReleaseUsageSnapshot(ctx context.Context, day time.Time) error {
+	if _, err := p.db(ctx).Exec(ctx, ReleaseUsageSnapshotQuery, day); err != nil {
+		return fmt.Errorf("failed to release usage snapshot: %w", err)
+	}
+	return nil
+}
+
+func (p *Repository) DeleteUsageSnapshotsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, DeleteUsageSnapshotsBeforeQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete usage snapshots: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (p *Repository) GetUsageHistory(ctx context.Context, filter *models.UsageFilter) ([]*models.UsagePoint, error) {
+	var rows pgx.Rows
+	var err error
+	switch filter.GroupBy {
+	case models.UsageGroupUser:
+		rows, err = p.db(ctx).Query(ctx, GetUsageUserHistoryQuery, filter.From, filter.To)
+	case models.UsageGroupType:
+		rows, err = p.db(ctx).Query(ctx, GetUsageTypeHistoryQuery, filter.From, filter.To, filter.OwnerID)
+	default:
+		rows, err = p.db(ctx).Query(ctx, GetUsageTotalHistoryQuery, filter.From, filter.To, filter.OwnerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage history: %w", err)
+	}
+	defer rows.Close()
+
+	var points []*models.UsagePoint
+	for rows.Next() {
+		var point models.UsagePoint
+		switch filter.GroupBy {
+		case models.UsageGroupUser:
+			err = rows.Scan(&point.Date, &point.OwnerID, &point.OwnerEmail, &point.Bytes, &point.Files)
+		case models.UsageGroupType:
+			err = rows.Scan(&point.Date, &point.MimeType, &point.Bytes, &point.Files)
+		default:
+			err = rows.Scan(&point.Date, &point.Bytes, &point.Files)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage history row: %w", err)
+		}
+		points = append(points, &point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return points, nil
+}
+
+func (p *Repository) GetLargestFiles(ctx context.Context, limit int) ([]*models.LargestFile, error) {
+	rows, err := p.db(ctx).Query(ctx, GetLargestFilesQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query largest files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.LargestFile
+	for rows.Next() {
+		var f models.LargestFile
+		if err := rows.Scan(&f.Name, &f.Size, &f.MimeType, &f.OwnerID, &f.OwnerEmail, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan largest file row: %w", err)
+		}
+		files = append(files, &f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return files, nil
+}
+
+func (p *Repository) GetUserUsage(ctx context.Context, limit int) ([]*models.UserUsage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetUserUsageQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user usage: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.UserUsage
+	for rows.Next() {
+		var u models.UserUsage
+		if err := rows.Scan(&u.OwnerID, &u.OwnerEmail, &u.Bytes, &u.Files); err != nil {
+			return nil, fmt.Errorf("failed to scan user usage row: %w", err)
+		}
+		users = append(users, &u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return users, nil
+}
+
+func (p *Repository) GetTypeUsage(ctx context.Context) ([]*models.TypeUsage, error) {
+	rows, err := p.db(ctx).Query(ctx, GetTypeUsageQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query type usage: %w", err)
+	}
+	defer rows.Close()
+
+	var types []*models.TypeUsage
+	for rows.Next() {
+		var t models.TypeUsage
+		if err := rows.Scan(&t.MimeType, &t.Bytes, &t.Files); err != nil {
+			return nil, fmt.Errorf("failed to scan type usage row: %w", err)
+		}
+		types = append(types, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return types, nil
+}
@@ -0,0 +1,190 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tages/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Создание вебхука
+func (p *Repository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	err := p.db(ctx).QueryRow(ctx, CreateWebhookQuery,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		events,
+		webhook.System,
+		webhook.Active,
+		webhook.CreatedAt).Scan(&webhook.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// Список вебхуков пользователя; при userID == nil — всех пользователей
+func (p *Repository) GetWebhooks(ctx context.Context, userID *uint) ([]*models.Webhook, error) {
+	rows, err := p.db(ctx).Query(ctx, GetWebhooksQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (p *Repository) GetWebhook(ctx context.Context, id uint) (*models.Webhook, error) {
+	webhook, err := scanWebhook(p.db(ctx).QueryRow(ctx, GetWebhookQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (p *Repository) DeleteWebhook(ctx context.Context, id uint) error {
+	tag, err := p.db(ctx).Exec(ctx, DeleteWebhookQuery, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Постановка события в очередь доставки всем подписанным вебхукам.
+// systemOnly ограничивает получателей системными вебхуками
+func (p *Repository) EnqueueWebhookDeliveries(ctx context.Context, eventType string, payload []byte, systemOnly bool, now time.Time) (int64, error) {
+	tag, err := p.db(ctx).Exec(ctx, EnqueueWebhookDeliveriesQuery, eventType, payload, systemOnly, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries for %s: %w", eventType, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Захват доставок, которые пора отправить. До leaseUntil их не возьмет другой экземпляр
+func (p *Repository) ClaimWebhookDeliveries(ctx context.Context, limit int, now, leaseUntil time.Time) ([]*models.WebhookDelivery, error) {
+	rows, err := p.db(ctx).Query(ctx, ClaimWebhookDeliveriesQuery, limit, leaseUntil, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery := models.WebhookDelivery{Status: models.WebhookDeliveryPending}
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Сохранение результата попытки доставки
+func (p *Repository) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := p.db(ctx).Exec(ctx, UpdateWebhookDeliveryQuery,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseStatus,
+		delivery.LastError)
+
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// История доставок вебхука, новые первыми
+func (p *Repository) GetWebhookDeliveries(ctx context.Context, webhookID uint, limit, offset int) ([]*models.WebhookDelivery, error) {
+	rows, err := p.db(ctx).Query(ctx, GetWebhookDeliveriesQuery, webhookID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastAttemptAt,
+			&delivery.ResponseStatus,
+			&delivery.LastError,
+			&delivery.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.Events,
+		&webhook.System,
+		&webhook.Active,
+		&webhook.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+	}
+	return &webhook, nil
+}
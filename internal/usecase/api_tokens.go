@@ -0,0 +1,275 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidAPIToken      = errors.New("invalid api token")
+	ErrAPITokenForbidden    = errors.New("api token scope is not granted by user role")
+	ErrAPITokenLimit        = errors.New("too many api tokens")
+	ErrAPITokenUnauthorized = errors.New("api token is invalid or expired")
+	ErrAPITokenIPDenied     = errors.New("api token is not allowed from this address")
+)
+
+const (
+	maxAPITokenNameLength = 128
+	maxAPITokenAllowedIPs = 32
+	apiTokenPrefixLength  = 8
+)
+
+// Области действия, которые можно выдать токену
+var knownAPITokenScopes = map[string]bool{
+	models.ScopeFilesRead:   true,
+	models.ScopeFilesWrite:  true,
+	models.ScopeFilesDelete: true,
+	models.ScopeAdmin:       true,
+}
+
+type APITokenRepository interface {
+	CountAPITokens(ctx context.Context, userID uint) (int, error)
+	CreateAPIToken(ctx context.Context, token *models.APIToken) error
+	GetAPITokens(ctx context.Context, userID uint) ([]*models.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, id uint) error
+	TouchAPIToken(ctx context.Context, id uint, at time.Time, ip string) error
+}
+
+type APITokenConfig struct {
+	DefaultLifetime time.Duration
+	MaxLifetime     time.Duration
+	MaxPerUser      int
+}
+
+// APITokenUsecase персональные токены доступа к API для скриптов и интеграций.
+// Токен действует от имени владельца, но только в пределах выданных областей
+type APITokenUsecase struct {
+	r           APITokenRepository
+	permissions PermissionChecker
+	cfg         APITokenConfig
+	auditor     Auditor
+}
+
+func NewAPITokenUsecase(r APITokenRepository, permissions PermissionChecker, cfg APITokenConfig) *APITokenUsecase {
+	return &APITokenUsecase{
+		r:           r,
+		permissions: permissions,
+		cfg:         cfg,
+		auditor:     nopAuditor{},
+	}
+}
+
+// SetAuditor подключает журнал аудита выпуска и отзыва токенов
+func (u *APITokenUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// Create выпускает токен. Возвращается запись токена и сам токен, который
+// больше нигде не хранится. lifetime 0 — срок действия по умолчанию
+func (u *APITokenUsecase) Create(ctx context.Context, userID uint, name string, scopes, allowedIPs []string, lifetime time.Duration) (_ *models.APIToken, _ string, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionAPITokenCreate, name, map[string]string{
+			"scopes": strings.Join(scopes, ","),
+		}, err)
+	}()
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		return nil, "", fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidAPIToken, maxAPITokenNameLength)
+	}
+
+	scopes, err = u.normalizeScopes(ctx, userID, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	allowedIPs, err = normalizeAllowedIPs(allowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if lifetime < 0 || lifetime > u.cfg.MaxLifetime {
+		return nil, "", fmt.Errorf("%w: lifetime must not exceed %d days", ErrInvalidAPIToken, int(u.cfg.MaxLifetime.Hours()/24))
+	}
+	if lifetime == 0 {
+		lifetime = u.cfg.DefaultLifetime
+	}
+
+	count, err := u.r.CountAPITokens(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= u.cfg.MaxPerUser {
+		return nil, "", fmt.Errorf("%w: limit is %d", ErrAPITokenLimit, u.cfg.MaxPerUser)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	raw := models.APITokenPrefix + secret
+
+	now := time.Now()
+	token := &models.APIToken{
+		UserID:     userID,
+		Name:       name,
+		TokenHash:  hashRefreshToken(raw),
+		Prefix:     raw[:len(models.APITokenPrefix)+apiTokenPrefixLength],
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  now.Add(lifetime),
+		CreatedAt:  now,
+	}
+	if err := u.r.CreateAPIToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+
+	log.Printf("INFO: User %d created api token %d (%s) with scopes %v", userID, token.ID, token.Prefix, scopes)
+	return token, raw, nil
+}
+
+// List возвращает токены пользователя без секретов
+func (u *APITokenUsecase) List(ctx context.Context, userID uint) ([]*models.APIToken, error) {
+	tokens, err := u.r.GetAPITokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Revoke отзывает токен пользователя
+func (u *APITokenUsecase) Revoke(ctx context.Context, userID, id uint) (err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionAPITokenRevoke, strconv.FormatUint(uint64(id), 10), nil, err)
+	}()
+
+	if err := u.r.DeleteAPIToken(ctx, userID, id); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d revoked api token %d", userID, id)
+	return nil
+}
+
+// Authenticate проверяет предъявленный токен: срок действия и адрес клиента.
+// Области действия проверяет вызывающий
+func (u *APITokenUsecase) Authenticate(ctx context.Context, raw, ip string) (*models.APIToken, error) {
+	if !IsAPIToken(raw) {
+		return nil, ErrAPITokenUnauthorized
+	}
+
+	token, err := u.r.GetAPITokenByHash(ctx, hashRefreshToken(raw))
+	if err != nil {
+		if errors.Is(err, models.ErrAPITokenNotFound) {
+			return nil, ErrAPITokenUnauthorized
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrAPITokenUnauthorized
+	}
+	if !ipAllowed(token.AllowedIPs, ip) {
+		log.Printf("WARN: Api token %d of user %d used from disallowed address %s", token.ID, token.UserID, ip)
+		return nil, ErrAPITokenIPDenied
+	}
+
+	// Отметка об использовании не должна мешать запросу
+	if err := u.r.TouchAPIToken(ctx, token.ID, now, ip); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+	return token, nil
+}
+
+// IsAPIToken отличает персональный токен от JWT по префиксу
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, models.APITokenPrefix)
+}
+
+// Проверка и удаление повторов в областях действия. Выдать можно
+// только то, что разрешено роли владельца
+func (u *APITokenUsecase) normalizeScopes(ctx context.Context, userID uint, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIToken)
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !knownAPITokenScopes[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIToken, scope)
+		}
+		if seen[scope] {
+			continue
+		}
+
+		allowed, err := u.permissions.HasPermission(ctx, userID, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permissions: %w", err)
+		}
+		if !allowed {
+			return nil, fmt.Errorf("%w: %q", ErrAPITokenForbidden, scope)
+		}
+
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	return normalized, nil
+}
+
+// Разрешенные адреса: отдельные IP или подсети в нотации CIDR
+func normalizeAllowedIPs(allowedIPs []string) ([]string, error) {
+	if len(allowedIPs) > maxAPITokenAllowedIPs {
+		return nil, fmt.Errorf("%w: at most %d allowed addresses", ErrInvalidAPIToken, maxAPITokenAllowedIPs)
+	}
+
+	normalized := make([]string, 0, len(allowedIPs))
+	for _, entry := range allowedIPs {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			normalized = append(normalized, network.String())
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: invalid address %q", ErrInvalidAPIToken, entry)
+		}
+		normalized = append(normalized, ip.String())
+	}
+	return normalized, nil
+}
+
+// Пустой список разрешает любой адрес
+func ipAllowed(allowedIPs []string, addr string) bool {
+	if len(allowedIPs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowedIPs {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import "testing"
+
+func TestIPAllowed(t *testing.T) {
+	allowed := []string{"10.0.0.0/8", "192.168.1.5", "2001:db8::/32"}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "10.1.2.3", want: true},
+		{addr: "192.168.1.5", want: true},
+		{addr: "::ffff:192.168.1.5", want: true},
+		{addr: "2001:db8::1", want: true},
+		{addr: "192.168.1.6", want: false},
+		{addr: "11.0.0.1", want: false},
+		{addr: "", want: false},
+		{addr: "10.1.2.3, 1.2.3.4", want: false},
+	}
+	for _, tt := range tests {
+		if got := ipAllowed(allowed, tt.addr); got != tt.want {
+			t.Errorf("ipAllowed(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if !ipAllowed(nil, "1.2.3.4") {
+		t.Error("empty allowlist must allow any address")
+	}
+}
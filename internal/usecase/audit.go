@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"tages/internal/models"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+type AuditRepository interface {
+	SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEvent, error)
+	ExportAuditEvents(ctx context.Context, filter *models.AuditFilter, fn func(*models.AuditEvent) error) error
+	DeleteAuditEventsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Auditor фиксирует события в журнале аудита
+type Auditor interface {
+	Record(ctx context.Context, event *models.AuditEvent)
+}
+
+type AuditUsecase struct {
+	r         AuditRepository
+	retention time.Duration
+}
+
+// retention — срок хранения событий, 0 — хранить бессрочно
+func NewAuditUsecase(r AuditRepository, retention time.Duration) *AuditUsecase {
+	return &AuditUsecase{
+		r:         r,
+		retention: retention,
+	}
+}
+
+// Record дополняет событие сведениями о запросе из контекста и сохраняет его.
+// Ошибка записи не прерывает основную операцию, а только логируется
+func (u *AuditUsecase) Record(ctx context.Context, event *models.AuditEvent) {
+	meta := RequestMetaFromContext(ctx)
+	if event.ActorID == 0 {
+		event.ActorID = meta.ActorID
+	}
+	event.IP = meta.IP
+	event.UserAgent = meta.UserAgent
+	event.RequestID = meta.RequestID
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.Outcome == "" {
+		event.Outcome = models.AuditOutcomeSuccess
+	}
+
+	if err := u.r.SaveAuditEvent(ctx, event); err != nil {
+		log.Printf("ERROR: Failed to record audit event %s for %q: %v", event.Action, event.Target, err)
+	}
+}
+
+// Выборка событий журнала
+func (u *AuditUsecase) List(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEvent, error) {
+	if filter.Limit < 1 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	events, err := u.r.GetAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// Потоковая выгрузка событий журнала
+func (u *AuditUsecase) Export(ctx context.Context, filter *models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	log.Printf("INFO: Exporting audit events")
+
+	if err := u.r.ExportAuditEvents(ctx, filter, fn); err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	return nil
+}
+
+// RunRetention периодически удаляет события старше срока хранения
+func (u *AuditUsecase) RunRetention(ctx context.Context, interval time.Duration) {
+	if u.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := u.r.DeleteAuditEventsBefore(ctx, time.Now().Add(-u.retention))
+		if err != nil {
+			log.Printf("ERROR: Failed to prune audit events: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Pruned %d audit events older than %s", deleted, u.retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Журнал, который ничего не записывает. Используется, пока аудит не подключен
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, *models.AuditEvent) {}
+
+// Запись результата операции над файлом
+func recordFileEvent(ctx context.Context, auditor Auditor, action, filename string, err error) {
+	recordEvent(ctx, auditor, action, filename, nil, err)
+}
+
+// Запись результата операции; текст ошибки добавляется к подробностям
+func recordEvent(ctx context.Context, auditor Auditor, action, target string, details map[string]string, err error) {
+	event := &models.AuditEvent{
+		Action:  action,
+		Outcome: models.AuditOutcomeSuccess,
+		Target:  target,
+		Details: details,
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		if event.Details == nil {
+			event.Details = map[string]string{}
+		}
+		event.Details["error"] = err.Error()
+	}
+	auditor.Record(ctx, event)
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidComment   = errors.New("invalid comment")
+	ErrCommentForbidden = errors.New("comment belongs to another user")
+)
+
+const (
+	maxCommentLength        = 10000
+	maxCommentMentions      = 50
+	defaultCommentsPageSize = 20
+	maxCommentsPageSize     = 100
+)
+
+// Упоминание пользователя по email: "@user@example.com"
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([\w.+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	GetComment(ctx context.Context, id int64) (*models.Comment, error)
+	GetFileComments(ctx context.Context, filename string) ([]*models.Comment, error)
+	GetMentionedComments(ctx context.Context, userID uint, limit, offset int) (*models.CommentPage, error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	SetCommentResolved(ctx context.Context, id int64, resolved bool, userID uint, at time.Time) error
+	DeleteComment(ctx context.Context, id int64) error
+}
+
+// AdminChecker проверяет права администратора
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID uint) (bool, error)
+}
+
+// CommentsUsecase обсуждения файлов. Комментарии доступны всем, кто может читать файл,
+// изменять комментарий может только автор, удалять — автор или администратор
+type CommentsUsecase struct {
+	r      CommentRepository
+	admins AdminChecker
+}
+
+func NewCommentsUsecase(r CommentRepository, admins AdminChecker) *CommentsUsecase {
+	return &CommentsUsecase{
+		r:      r,
+		admins: admins,
+	}
+}
+
+// Create добавляет комментарий к файлу. Если parentID больше нуля, комментарий
+// становится ответом в ветке родителя
+func (u *CommentsUsecase) Create(ctx context.Context, userID uint, filename string, parentID int64, body string) (*models.Comment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID > 0 {
+		parent, err := u.r.GetComment(ctx, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent comment %d: %w", parentID, err)
+		}
+		if parent.FileName != filename {
+			return nil, fmt.Errorf("%w: parent comment belongs to another file", ErrInvalidComment)
+		}
+	}
+
+	comment := &models.Comment{
+		FileName:  filename,
+		ParentID:  parentID,
+		AuthorID:  userID,
+		Body:      body,
+		Mentions:  parseMentions(body),
+		CreatedAt: time.Now(),
+	}
+	if err := u.r.CreateComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to create comment on %s: %w", filename, err)
+	}
+
+	log.Printf("INFO: User %d commented on file %s (comment %d)", userID, filename, comment.ID)
+	return u.r.GetComment(ctx, comment.ID)
+}
+
+// List возвращает ветки обсуждения файла: комментарии верхнего уровня с ответами
+func (u *CommentsUsecase) List(ctx context.Context, filename string) ([]*models.Comment, error) {
+	comments, err := u.r.GetFileComments(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments of %s: %w", filename, err)
+	}
+
+	byID := make(map[int64]*models.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	var threads []*models.Comment
+	for _, comment := range comments {
+		if parent, ok := byID[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+			continue
+		}
+		threads = append(threads, comment)
+	}
+	return threads, nil
+}
+
+// Mentions возвращает комментарии, в которых упомянут пользователь
+func (u *CommentsUsecase) Mentions(ctx context.Context, userID uint, page, pageSize int) (*models.CommentPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultCommentsPageSize
+	}
+	if pageSize > maxCommentsPageSize {
+		pageSize = maxCommentsPageSize
+	}
+
+	result, err := u.r.GetMentionedComments(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mentioned comments: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// Update изменяет текст комментария автора
+func (u *CommentsUsecase) Update(ctx context.Context, userID uint, id int64, body string) (*models.Comment, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := u.r.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, ErrCommentForbidden
+	}
+
+	comment.Body = body
+	comment.Mentions = parseMentions(body)
+	comment.UpdatedAt = time.Now()
+	if err := u.r.UpdateComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to update comment %d: %w", id, err)
+	}
+
+	return u.r.GetComment(ctx, id)
+}
+
+// Resolve помечает ветку обсуждения решенной или снимает отметку
+func (u *CommentsUsecase) Resolve(ctx context.Context, userID uint, id int64, resolved bool) (*models.Comment, error) {
+	comment, err := u.r.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != 0 {
+		return nil, fmt.Errorf("%w: only top-level comments can be resolved", ErrInvalidComment)
+	}
+
+	if err := u.r.SetCommentResolved(ctx, id, resolved, userID, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to resolve comment %d: %w", id, err)
+	}
+
+	log.Printf("INFO: User %d set comment %d resolved=%t", userID, id, resolved)
+	return u.r.GetComment(ctx, id)
+}
+
+// Delete удаляет комментарий вместе с ответами
+func (u *CommentsUsecase) Delete(ctx context.Context, userID uint, id int64) error {
+	comment, err := u.r.GetComment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID {
+		isAdmin, err := u.admins.IsAdmin(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to check admin rights: %w", err)
+		}
+		if !isAdmin {
+			return ErrCommentForbidden
+		}
+	}
+
+	if err := u.r.DeleteComment(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete comment %d: %w", id, err)
+	}
+
+	log.Printf("INFO: User %d deleted comment %d on file %s", userID, id, comment.FileName)
+	return nil
+}
+
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("%w: empty comment", ErrInvalidComment)
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("%w: comment is longer than %d characters", ErrInvalidComment, maxCommentLength)
+	}
+	return body, nil
+}
+
+// Email упомянутых пользователей без повторов
+func parseMentions(body string) []string {
+	seen := make(map[string]struct{})
+	mentions := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(match[1])
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		mentions = append(mentions, email)
+		if len(mentions) == maxCommentMentions {
+			break
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Ошибки подтверждения email
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrVerificationThrottled    = errors.New("verification email was sent recently")
+)
+
+// Доступ пользователей с неподтвержденным email
+const (
+	UnverifiedAccessFull     = "full"      // без ограничений
+	UnverifiedAccessReadOnly = "read_only" // только чтение файлов
+	UnverifiedAccessNone     = "none"      // только управление учетной записью
+)
+
+type EmailVerificationConfig struct {
+	TokenLifetime time.Duration
+	// Адрес подтверждения; токен передается в параметре token
+	URL              string
+	ResendInterval   time.Duration
+	UnverifiedAccess string
+}
+
+// SetEmailVerificationConfig задает параметры писем для подтверждения email
+// и ограничения для пользователей, не подтвердивших адрес
+func (u *UserUsecase) SetEmailVerificationConfig(cfg EmailVerificationConfig) {
+	u.verify = cfg
+}
+
+// VerifyEmail подтверждает email по токену из письма. Повторное
+// подтверждение не считается ошибкой
+func (u *UserUsecase) VerifyEmail(ctx context.Context, token string) (user *models.User, err error) {
+	var userID uint
+	var email string
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionEmailVerify, userID, email, err)
+	}()
+
+	claims, err := u.authManager.ParseVerificationToken(token)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	userID, email = claims.UserID, claims.Email
+
+	user, err = u.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Email != claims.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+	if user.EmailVerified {
+		return user, nil
+	}
+
+	if err := u.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	user.EmailVerified = true
+
+	log.Printf("INFO: User %d verified email %s", user.ID, user.Email)
+	return user, nil
+}
+
+// ResendVerificationEmail повторно отправляет письмо для подтверждения.
+// Если письмо отправлялось недавно, возвращается время до следующей попытки
+func (u *UserUsecase) ResendVerificationEmail(ctx context.Context, userID uint) (time.Duration, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified {
+		return 0, ErrEmailAlreadyVerified
+	}
+
+	sent, err := u.sendVerificationEmail(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+	if !sent {
+		retryAfter := u.verify.ResendInterval
+		if user.VerificationSentAt != nil {
+			retryAfter = time.Until(user.VerificationSentAt.Add(u.verify.ResendInterval))
+		}
+		return max(retryAfter, time.Second), ErrVerificationThrottled
+	}
+	return 0, nil
+}
+
+// EmailVerificationAllows проверяет, разрешено ли действие с разрешением
+// permission пользователю, который еще не подтвердил email
+func (u *UserUsecase) EmailVerificationAllows(ctx context.Context, userID uint, permission string) (bool, error) {
+	if u.verify.UnverifiedAccess == UnverifiedAccessFull {
+		return true, nil
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified {
+		return true, nil
+	}
+	return u.verify.UnverifiedAccess == UnverifiedAccessReadOnly && permission == models.PermissionFilesRead, nil
+}
+
+// Отправка письма со ссылкой для подтверждения. false — письмо уже
+// отправлялось в пределах интервала повторной отправки
+func (u *UserUsecase) sendVerificationEmail(ctx context.Context, user *models.User) (bool, error) {
+	now := time.Now()
+	marked, err := u.userRepo.MarkVerificationSent(ctx, user.ID, now, now.Add(-u.verify.ResendInterval))
+	if err != nil {
+		return false, err
+	}
+	if !marked {
+		return false, nil
+	}
+
+	token, err := u.authManager.GenerateVerificationToken(user, u.verify.TokenLifetime)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	link, err := withTokenParam(u.verify.URL, token)
+	if err != nil {
+		return false, fmt.Errorf("failed to build verification link: %w", err)
+	}
+
+	u.sendMail(ctx, &models.MailMessage{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Чтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d ч.\n"+
+			"Если вы не регистрировались, проигнорируйте это письмо.\n",
+			link, int(u.verify.TokenLifetime.Hours())),
+	})
+
+	log.Printf("INFO: Verification email sent to user %d", user.ID)
+	return true, nil
+}
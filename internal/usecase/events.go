@@ -0,0 +1,341 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"tages/internal/models"
+)
+
+const (
+	fileEventsChannel    = "file_events"
+	subscriberBufferSize = 64
+	maxReplayEvents      = 1000
+	// Сколько ждать событие с пропущенным ID, прежде чем считать пропуск дырой
+	eventGapTimeout = 3 * time.Second
+)
+
+type FileEventRepository interface {
+	SaveFileEvent(ctx context.Context, event *models.FileEvent) error
+	GetFileEventsSince(ctx context.Context, afterID int64, limit int) ([]*models.FileEvent, error)
+	DeleteFileEventsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PubSub рассылка уведомлений между экземплярами сервиса
+type PubSub interface {
+	Notify(ctx context.Context, channel, payload string) error
+	Listen(ctx context.Context, channel string, onListen func(), handler func(payload string))
+}
+
+// FileEventPublisher получает события изменения файлов
+type FileEventPublisher interface {
+	Publish(ctx context.Context, event *models.FileEvent)
+}
+
+// FileEventBroker сохраняет события изменения файлов и раздает их подписчикам.
+// Между экземплярами события рассылаются через PubSub, поэтому подписчик получает
+// изменения, сделанные на любом экземпляре
+type FileEventBroker struct {
+	r         FileEventRepository
+	pubsub    PubSub
+	retention time.Duration
+
+	mu     sync.Mutex
+	subs   map[chan *models.FileEvent]struct{}
+	lastID int64
+}
+
+func NewFileEventBroker(r FileEventRepository, pubsub PubSub, retention time.Duration) *FileEventBroker {
+	return &FileEventBroker{
+		r:         r,
+		pubsub:    pubsub,
+		retention: retention,
+		subs:      make(map[chan *models.FileEvent]struct{}),
+	}
+}
+
+// Publish сохраняет событие и уведомляет все экземпляры.
+// Ошибки не прерывают операцию над файлом и только логируются
+func (b *FileEventBroker) Publish(ctx context.Context, event *models.FileEvent) {
+	if event.ActorID == 0 {
+		event.ActorID = RequestMetaFromContext(ctx).ActorID
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if err := b.r.SaveFileEvent(ctx, event); err != nil {
+		log.Printf("ERROR: Failed to save file event: %v", err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode file event %d: %v", event.ID, err)
+		return
+	}
+	if err := b.pubsub.Notify(ctx, fileEventsChannel, string(payload)); err != nil {
+		log.Printf("ERROR: Failed to notify file event %d: %v", event.ID, err)
+	}
+}
+
+// Run слушает уведомления и удаляет устаревшие события до отмены контекста
+func (b *FileEventBroker) Run(ctx context.Context, pruneInterval time.Duration) {
+	go b.pubsub.Listen(ctx, fileEventsChannel, func() { b.catchUp(ctx) }, b.handleNotification)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.closeAll()
+			return
+		case <-ticker.C:
+			deleted, err := b.r.DeleteFileEventsBefore(ctx, time.Now().Add(-b.retention))
+			if err != nil {
+				log.Printf("ERROR: Failed to prune file events: %v", err)
+			} else if deleted > 0 {
+				log.Printf("INFO: Pruned %d file events", deleted)
+			}
+		}
+	}
+}
+
+// Subscribe подписывает на события. Если lastEventID больше нуля, сначала
+// отдаются сохраненные события после него, затем новые. Канал закрывается при
+// отмене контекста или если подписчик не успевает читать события — в этом
+// случае клиент должен переподключиться с последним полученным ID
+func (b *FileEventBroker) Subscribe(ctx context.Context, lastEventID int64) (<-chan *models.FileEvent, error) {
+	live := make(chan *models.FileEvent, subscriberBufferSize)
+	b.mu.Lock()
+	b.subs[live] = struct{}{}
+	b.mu.Unlock()
+
+	var history []*models.FileEvent
+	if lastEventID > 0 {
+		var err error
+		history, err = b.r.GetFileEventsSince(ctx, lastEventID, maxReplayEvents)
+		if err != nil {
+			b.unsubscribe(live)
+			return nil, fmt.Errorf("failed to load file events since %d: %w", lastEventID, err)
+		}
+	}
+
+	out := make(chan *models.FileEvent)
+	go func() {
+		defer close(out)
+		defer b.unsubscribe(live)
+
+		seq := newEventSequencer(lastEventID)
+		// Таймер заводится, когда появляются события после пропуска, и не
+		// переносится новыми событиями, иначе под нагрузкой пропуск ждал бы вечно
+		gapTimer := time.NewTimer(eventGapTimeout)
+		gapTimer.Stop()
+		defer gapTimer.Stop()
+		timerArmed := false
+
+		flush := func() bool {
+			for _, event := range seq.ready(time.Now().Add(-eventGapTimeout)) {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			if seq.waiting() && !timerArmed {
+				gapTimer.Reset(eventGapTimeout)
+				timerArmed = true
+			}
+			return true
+		}
+		// Пропущенное событие могло быть уже записано, а уведомление о нем
+		// задержаться или потеряться: досылаем из базы
+		refill := func() {
+			events, err := b.r.GetFileEventsSince(ctx, seq.last, maxReplayEvents)
+			if err != nil {
+				log.Printf("ERROR: Failed to load file events since %d: %v", seq.last, err)
+				return
+			}
+			for _, event := range events {
+				seq.add(event, time.Now())
+			}
+		}
+
+		for _, event := range history {
+			seq.add(event, time.Now())
+		}
+		if !flush() {
+			return
+		}
+		for {
+			select {
+			case event, ok := <-live:
+				if !ok {
+					return
+				}
+				wasWaiting := seq.waiting()
+				if seq.add(event, time.Now()) && !wasWaiting && event.ID != seq.last+1 {
+					refill()
+				}
+			case <-gapTimer.C:
+				timerArmed = false
+				refill()
+			case <-ctx.Done():
+				return
+			}
+			if !flush() {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// eventSequencer выдает события подписчику по возрастанию ID без пропусков.
+// ID назначается при вставке, а транзакции фиксируются в другом порядке, поэтому
+// событие с меньшим ID может прийти позже большего. События после пропуска
+// ждут, пока он заполнится; пропуск, не заполненный за eventGapTimeout, считается
+// дырой в последовательности (неудачная вставка) и пропускается
+type eventSequencer struct {
+	// Последнее выданное событие
+	last    int64
+	pending map[int64]pendingEvent
+}
+
+type pendingEvent struct {
+	event      *models.FileEvent
+	receivedAt time.Time
+}
+
+func newEventSequencer(last int64) *eventSequencer {
+	return &eventSequencer{last: last, pending: make(map[int64]pendingEvent)}
+}
+
+// add запоминает событие; false — событие уже выдано или ждет выдачи
+func (s *eventSequencer) add(event *models.FileEvent, now time.Time) bool {
+	if s.last == 0 {
+		// Новый подписчик: отсчет идет от первого полученного события
+		s.last = event.ID - 1
+	}
+	if event.ID <= s.last {
+		return false
+	}
+	if _, ok := s.pending[event.ID]; ok {
+		return false
+	}
+	s.pending[event.ID] = pendingEvent{event: event, receivedAt: now}
+	return true
+}
+
+// ready возвращает события, которые можно выдать, по порядку. Пропуск перед
+// событием, полученным не позже gapBefore, считается дырой
+func (s *eventSequencer) ready(gapBefore time.Time) []*models.FileEvent {
+	var events []*models.FileEvent
+	for len(s.pending) > 0 {
+		next, ok := s.pending[s.last+1]
+		if !ok {
+			first := slices.Min(slices.Collect(maps.Keys(s.pending)))
+			if next = s.pending[first]; next.receivedAt.After(gapBefore) {
+				break
+			}
+			log.Printf("WARN: File events %d-%d are missing, skipping", s.last+1, first-1)
+		}
+		delete(s.pending, next.event.ID)
+		s.last = next.event.ID
+		events = append(events, next.event)
+	}
+	return events
+}
+
+// waiting сообщает, есть ли события, которые ждут заполнения пропуска
+func (s *eventSequencer) waiting() bool {
+	return len(s.pending) > 0
+}
+
+func (b *FileEventBroker) handleNotification(payload string) {
+	var event models.FileEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Printf("ERROR: Failed to decode file event notification: %v", err)
+		return
+	}
+	b.dispatch(&event)
+}
+
+// После переподключения досылаем события, пропущенные за время обрыва
+func (b *FileEventBroker) catchUp(ctx context.Context) {
+	b.mu.Lock()
+	lastID := b.lastID
+	b.mu.Unlock()
+	if lastID == 0 {
+		return
+	}
+
+	events, err := b.r.GetFileEventsSince(ctx, lastID, maxReplayEvents)
+	if err != nil {
+		log.Printf("ERROR: Failed to catch up file events since %d: %v", lastID, err)
+		return
+	}
+	for _, event := range events {
+		b.dispatch(event)
+	}
+}
+
+func (b *FileEventBroker) dispatch(event *models.FileEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.ID > b.lastID {
+		b.lastID = event.ID
+	}
+	for sub := range b.subs {
+		select {
+		case sub <- event:
+		default:
+			log.Printf("WARN: File event subscriber is too slow, dropping subscription")
+			delete(b.subs, sub)
+			close(sub)
+		}
+	}
+}
+
+func (b *FileEventBroker) unsubscribe(sub chan *models.FileEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub)
+	}
+}
+
+func (b *FileEventBroker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub)
+	}
+}
+
+// Публикатор, который никуда не отправляет события. Используется, пока лента не подключена
+type nopEventPublisher struct{}
+
+func (nopEventPublisher) Publish(context.Context, *models.FileEvent) {}
+
+// MultiEventPublisher передает событие изменения файла нескольким получателям
+type MultiEventPublisher []FileEventPublisher
+
+func (m MultiEventPublisher) Publish(ctx context.Context, event *models.FileEvent) {
+	for _, publisher := range m {
+		publisher.Publish(ctx, event)
+	}
+}
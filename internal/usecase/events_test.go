@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+)
+
+// memoryEventRepository хранит события в памяти; уведомления не рассылаются
+type memoryEventRepository struct {
+	mu     sync.Mutex
+	events []*models.FileEvent
+}
+
+func (r *memoryEventRepository) SaveFileEvent(ctx context.Context, event *models.FileEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryEventRepository) GetFileEventsSince(ctx context.Context, afterID int64, limit int) ([]*models.FileEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*models.FileEvent
+	for _, event := range r.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	slices.SortFunc(events, func(a, b *models.FileEvent) int { return int(a.ID - b.ID) })
+	return events, nil
+}
+
+func (r *memoryEventRepository) DeleteFileEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func receiveEventIDs(t *testing.T, events <-chan *models.FileEvent, n int) []int64 {
+	t.Helper()
+	var ids []int64
+	for len(ids) < n {
+		select {
+		case event := <-events:
+			ids = append(ids, event.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want %d events", ids, n)
+		}
+	}
+	return ids
+}
+
+func TestSubscribeReordersLateEvents(t *testing.T) {
+	repo := &memoryEventRepository{}
+	broker := NewFileEventBroker(repo, nil, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := broker.Subscribe(ctx, 10)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// Транзакция события 11 зафиксирована позже события 12
+	broker.dispatch(&models.FileEvent{ID: 12})
+	repo.SaveFileEvent(ctx, &models.FileEvent{ID: 11})
+	broker.dispatch(&models.FileEvent{ID: 11})
+	broker.dispatch(&models.FileEvent{ID: 13})
+
+	if got := receiveEventIDs(t, events, 3); !slices.Equal(got, []int64{11, 12, 13}) {
+		t.Errorf("events = %v, want [11 12 13]", got)
+	}
+}
+
+func TestSubscribeRefillsMissedNotification(t *testing.T) {
+	repo := &memoryEventRepository{}
+	broker := NewFileEventBroker(repo, nil, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := broker.Subscribe(ctx, 10)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// Событие 11 записано, но уведомление о нем не пришло
+	repo.SaveFileEvent(ctx, &models.FileEvent{ID: 11})
+	repo.SaveFileEvent(ctx, &models.FileEvent{ID: 12})
+	broker.dispatch(&models.FileEvent{ID: 12})
+
+	if got := receiveEventIDs(t, events, 2); !slices.Equal(got, []int64{11, 12}) {
+		t.Errorf("events = %v, want [11 12]", got)
+	}
+}
+
+func TestEventSequencerSkipsHoles(t *testing.T) {
+	start := time.Now()
+	seq := newEventSequencer(10)
+
+	seq.add(&models.FileEvent{ID: 12}, start)
+	seq.add(&models.FileEvent{ID: 14}, start.Add(time.Second))
+	if seq.add(&models.FileEvent{ID: 12}, start) {
+		t.Error("duplicate event accepted")
+	}
+
+	if got := seq.ready(start.Add(-time.Second)); len(got) != 0 {
+		t.Fatalf("ready before timeout = %v, want none", got)
+	}
+	if !seq.waiting() {
+		t.Fatal("events after the gap are not waiting")
+	}
+
+	// Пропуск 11 истек, пропуск 13 еще нет
+	got := seq.ready(start)
+	if len(got) != 1 || got[0].ID != 12 {
+		t.Fatalf("ready = %v, want [12]", got)
+	}
+	seq.add(&models.FileEvent{ID: 13}, start.Add(2*time.Second))
+	got = seq.ready(start)
+	if len(got) != 2 || got[0].ID != 13 || got[1].ID != 14 {
+		t.Fatalf("ready = %v, want [13 14]", got)
+	}
+	if seq.waiting() {
+		t.Error("sequencer still waiting")
+	}
+	if seq.add(&models.FileEvent{ID: 11}, start) {
+		t.Error("event behind the skipped hole accepted")
+	}
+}
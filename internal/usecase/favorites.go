@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"tages/internal/models"
+)
+
+const (
+	defaultFavoritesPageSize = 20
+	maxFavoritesPageSize     = 100
+	// Сколько недавних файлов хранится для каждого пользователя
+	maxRecentFiles = 100
+)
+
+type FavoritesRepository interface {
+	StarFile(ctx context.Context, userID uint, filename string, starredAt time.Time) error
+	UnstarFile(ctx context.Context, userID uint, filename string) error
+	GetStarredFiles(ctx context.Context, userID uint, limit, offset int) (*models.StarredPage, error)
+	SaveRecentFile(ctx context.Context, userID uint, filename, action string, accessedAt time.Time, keep int) error
+	GetRecentFiles(ctx context.Context, userID uint, limit, offset int) (*models.RecentPage, error)
+}
+
+// RecentTracker получает отметки о работе пользователя с файлами
+type RecentTracker interface {
+	TrackAccess(ctx context.Context, filename, action string)
+}
+
+// FavoritesUsecase избранные и недавние файлы пользователей.
+// Записи удаляются вместе с файлом на уровне базы данных
+type FavoritesUsecase struct {
+	r FavoritesRepository
+}
+
+func NewFavoritesUsecase(r FavoritesRepository) *FavoritesUsecase {
+	return &FavoritesUsecase{
+		r: r,
+	}
+}
+
+func (u *FavoritesUsecase) Star(ctx context.Context, userID uint, filename string) error {
+	if err := u.r.StarFile(ctx, userID, filename, time.Now()); err != nil {
+		return fmt.Errorf("failed to star file %s: %w", filename, err)
+	}
+	log.Printf("INFO: User %d starred file %s", userID, filename)
+	return nil
+}
+
+func (u *FavoritesUsecase) Unstar(ctx context.Context, userID uint, filename string) error {
+	if err := u.r.UnstarFile(ctx, userID, filename); err != nil {
+		return fmt.Errorf("failed to unstar file %s: %w", filename, err)
+	}
+	log.Printf("INFO: User %d unstarred file %s", userID, filename)
+	return nil
+}
+
+// Избранные файлы пользователя, новые первыми. Страницы нумеруются с единицы
+func (u *FavoritesUsecase) ListStarred(ctx context.Context, userID uint, page, pageSize int) (*models.StarredPage, error) {
+	page, pageSize = normalizeFavoritesPage(page, pageSize)
+
+	result, err := u.r.GetStarredFiles(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list starred files: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// Недавние файлы пользователя, последние первыми. Страницы нумеруются с единицы
+func (u *FavoritesUsecase) ListRecent(ctx context.Context, userID uint, page, pageSize int) (*models.RecentPage, error) {
+	page, pageSize = normalizeFavoritesPage(page, pageSize)
+
+	result, err := u.r.GetRecentFiles(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent files: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// TrackAccess отмечает файл как недавний для текущего пользователя.
+// Ошибки не прерывают операцию над файлом и только логируются
+func (u *FavoritesUsecase) TrackAccess(ctx context.Context, filename, action string) {
+	userID := RequestMetaFromContext(ctx).ActorID
+	if userID == 0 {
+		return
+	}
+	if err := u.r.SaveRecentFile(ctx, userID, filename, action, time.Now(), maxRecentFiles); err != nil {
+		log.Printf("ERROR: Failed to track recent file %s for user %d: %v", filename, userID, err)
+	}
+}
+
+func normalizeFavoritesPage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultFavoritesPageSize
+	}
+	if pageSize > maxFavoritesPageSize {
+		pageSize = maxFavoritesPageSize
+	}
+	return page, pageSize
+}
+
+type nopRecentTracker struct{}
+
+func (nopRecentTracker) TrackAccess(context.Context, string, string) {}
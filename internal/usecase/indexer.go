@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"tages/internal/extract"
+	"tages/internal/models"
+)
+
+type IndexRepository interface {
+	GetFilesPendingIndex(ctx context.Context, limit int) ([]*models.FileMeta, error)
+	SaveFileContent(ctx context.Context, filename, content string, indexedAt time.Time) error
+}
+
+// IndexerConfig параметры фонового извлечения текста
+type IndexerConfig struct {
+	Interval         time.Duration
+	BatchSize        int
+	MaxFileSize      int64
+	MaxContentLength int
+}
+
+// ContentIndexer в фоне извлекает текст из загруженных файлов для полнотекстового поиска.
+// Файлы выбираются из БД, поэтому после перезапуска необработанные загрузки не теряются
+type ContentIndexer struct {
+	storage FileStorage
+	r       IndexRepository
+	cfg     IndexerConfig
+	wake    chan struct{}
+}
+
+func NewContentIndexer(storage FileStorage, r IndexRepository, cfg IndexerConfig) *ContentIndexer {
+	return &ContentIndexer{
+		storage: storage,
+		r:       r,
+		cfg:     cfg,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает о новой загрузке, чтобы не ждать следующего интервала
+func (i *ContentIndexer) Notify() {
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run обрабатывает очередь до отмены контекста
+func (i *ContentIndexer) Run(ctx context.Context) {
+	log.Printf("INFO: Content indexer started")
+	ticker := time.NewTicker(i.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		i.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Printf("INFO: Content indexer stopped")
+			return
+		case <-ticker.C:
+		case <-i.wake:
+		}
+	}
+}
+
+func (i *ContentIndexer) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		files, err := i.r.GetFilesPendingIndex(ctx, i.cfg.BatchSize)
+		if err != nil {
+			log.Printf("ERROR: Failed to get files pending index: %v", err)
+			return
+		}
+		if len(files) == 0 {
+			return
+		}
+
+		for _, file := range files {
+			content := i.extract(file.Name)
+			// Даже при ошибке отмечаем версию обработанной, иначе файл будет выбираться бесконечно
+			if err := i.r.SaveFileContent(ctx, file.Name, content, file.UpdatedAt); err != nil {
+				log.Printf("ERROR: Failed to save extracted content for %s: %v", file.Name, err)
+				return
+			}
+		}
+
+		if len(files) < i.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (i *ContentIndexer) extract(filename string) string {
+	if !extract.Supported(filename) {
+		return ""
+	}
+
+	reader, err := i.storage.ReadStream(filename)
+	if err != nil {
+		log.Printf("WARN: Failed to open %s for indexing: %v", filename, err)
+		return ""
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, i.cfg.MaxFileSize+1))
+	if err != nil {
+		log.Printf("WARN: Failed to read %s for indexing: %v", filename, err)
+		return ""
+	}
+	if int64(len(data)) > i.cfg.MaxFileSize {
+		log.Printf("INFO: Skipping indexing of %s: file is larger than %d bytes", filename, i.cfg.MaxFileSize)
+		return ""
+	}
+
+	text, err := extract.Text(filename, data, i.cfg.MaxContentLength)
+	if err != nil {
+		if !errors.Is(err, extract.ErrUnsupported) {
+			log.Printf("WARN: %v", err)
+		}
+		return ""
+	}
+
+	// PostgreSQL не хранит нулевые байты в TEXT, а tsvector ограничен по размеру
+	text = strings.ReplaceAll(text, "\x00", " ")
+	if len(text) > i.cfg.MaxContentLength {
+		text = strings.ToValidUTF8(text[:i.cfg.MaxContentLength], "")
+	}
+
+	log.Printf("INFO: Extracted %d bytes of text from %s", len(text), filename)
+	return text
+}
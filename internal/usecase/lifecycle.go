@@ -0,0 +1,484 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidLifecycleRule   = errors.New("invalid lifecycle rule")
+	ErrLifecycleRuleForbidden = errors.New("insufficient rights for lifecycle rule")
+)
+
+const (
+	maxLifecycleRuleNameLength = 200
+	maxLifecycleAfterDays      = 100 * 365
+	defaultLifecycleDryRun     = 100
+	maxLifecycleDryRun         = 1000
+	defaultLifecyclePageSize   = 20
+	maxLifecyclePageSize       = 100
+)
+
+type LifecycleRepository interface {
+	TouchFileAccess(ctx context.Context, filename string, accessedAt time.Time) error
+	CreateLifecycleRule(ctx context.Context, rule *models.LifecycleRule) error
+	UpdateLifecycleRule(ctx context.Context, rule *models.LifecycleRule) error
+	DeleteLifecycleRule(ctx context.Context, id int64) error
+	GetLifecycleRule(ctx context.Context, id int64) (*models.LifecycleRule, error)
+	GetLifecycleRules(ctx context.Context, ownerID uint) ([]*models.LifecycleRule, error)
+	GetEnabledLifecycleRules(ctx context.Context) ([]*models.LifecycleRule, error)
+	ClaimLifecycleRule(ctx context.Context, id int64, now, notBefore time.Time) (bool, error)
+	// ownerID ограничивает отбор файлами владельца, 0 — файлы всех пользователей
+	GetLifecycleCandidates(ctx context.Context, rule *models.LifecycleRule, ownerID uint, before time.Time, after string, limit int) ([]*models.LifecycleCandidate, int, error)
+	SaveLifecycleActions(ctx context.Context, actions []*models.LifecycleAction) error
+	GetLifecycleActions(ctx context.Context, ruleID int64, limit, offset int) (*models.LifecycleActionPage, error)
+	DeleteLifecycleActionsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AccessRecorder отмечает обращения к файлу для правил «не использовался N дней»
+type AccessRecorder interface {
+	RecordAccess(ctx context.Context, filename string)
+}
+
+type LifecycleConfig struct {
+	// Период проверки правил
+	Interval time.Duration
+	// Сколько файлов обрабатывается за один запрос к базе
+	BatchSize int
+	// Срок хранения истории применения правил, 0 — хранить бессрочно
+	HistoryRetention time.Duration
+}
+
+// LifecycleUsecase правила жизненного цикла: периодически удаляет или переносит
+// в корзину устаревшие файлы. Правила создают пользователи с правом удаления
+// файлов. Правило администратора затрагивает файлы всех пользователей, правило
+// остальных — только файлы своего автора; изменять правило может автор или администратор
+type LifecycleUsecase struct {
+	r           LifecycleRepository
+	files       *Usecase
+	trash       *TrashUsecase
+	permissions PermissionChecker
+	auditor     Auditor
+	cfg         LifecycleConfig
+}
+
+func NewLifecycleUsecase(r LifecycleRepository, files *Usecase, trash *TrashUsecase, permissions PermissionChecker, cfg LifecycleConfig) *LifecycleUsecase {
+	return &LifecycleUsecase{
+		r:           r,
+		files:       files,
+		trash:       trash,
+		permissions: permissions,
+		auditor:     nopAuditor{},
+		cfg:         cfg,
+	}
+}
+
+// SetAuditor подключает журнал аудита изменений правил
+func (u *LifecycleUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// RecordAccess обновляет время последнего обращения к файлу.
+// Ошибки не прерывают операцию над файлом и только логируются
+func (u *LifecycleUsecase) RecordAccess(ctx context.Context, filename string) {
+	if err := u.r.TouchFileAccess(ctx, filename, time.Now()); err != nil {
+		log.Printf("ERROR: Failed to record access to %s: %v", filename, err)
+	}
+}
+
+func (u *LifecycleUsecase) CreateRule(ctx context.Context, userID uint, rule *models.LifecycleRule) (_ *models.LifecycleRule, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionLifecycleRuleCreate, lifecycleRuleTarget(rule), lifecycleRuleDetails(rule), err)
+	}()
+
+	if err := normalizeLifecycleRule(rule); err != nil {
+		return nil, err
+	}
+	if err := u.checkRulePermissions(ctx, userID, rule); err != nil {
+		return nil, err
+	}
+	rule.OwnerID = userID
+	rule.CreatedAt = time.Now()
+	rule.LastRunAt = nil
+
+	if err := u.r.CreateLifecycleRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d created lifecycle rule %d (%s)", userID, rule.ID, rule.Name)
+	return rule, nil
+}
+
+// UpdateRule заменяет условия и действие правила
+func (u *LifecycleUsecase) UpdateRule(ctx context.Context, userID uint, id int64, changes *models.LifecycleRule) (_ *models.LifecycleRule, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionLifecycleRuleUpdate, strconv.FormatInt(id, 10), lifecycleRuleDetails(changes), err)
+	}()
+
+	rule, err := u.Rule(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeLifecycleRule(changes); err != nil {
+		return nil, err
+	}
+	if err := u.checkRulePermissions(ctx, userID, changes); err != nil {
+		return nil, err
+	}
+
+	rule.Name = changes.Name
+	rule.Prefix = changes.Prefix
+	rule.Tag = changes.Tag
+	rule.Condition = changes.Condition
+	rule.AfterDays = changes.AfterDays
+	rule.Action = changes.Action
+	rule.Enabled = changes.Enabled
+	rule.UpdatedAt = time.Now()
+
+	if err := u.r.UpdateLifecycleRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d updated lifecycle rule %d", userID, id)
+	return rule, nil
+}
+
+func (u *LifecycleUsecase) DeleteRule(ctx context.Context, userID uint, id int64) (err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionLifecycleRuleDelete, strconv.FormatInt(id, 10), nil, err)
+	}()
+
+	if _, err := u.Rule(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := u.r.DeleteLifecycleRule(ctx, id); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d deleted lifecycle rule %d", userID, id)
+	return nil
+}
+
+// Rule возвращает правило, если оно принадлежит пользователю или пользователь — администратор
+func (u *LifecycleUsecase) Rule(ctx context.Context, userID uint, id int64) (*models.LifecycleRule, error) {
+	rule, err := u.r.GetLifecycleRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule.OwnerID == userID {
+		return rule, nil
+	}
+
+	isAdmin, err := u.permissions.HasPermission(ctx, userID, models.PermissionAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admin rights: %w", err)
+	}
+	if !isAdmin {
+		return nil, ErrLifecycleRuleForbidden
+	}
+	return rule, nil
+}
+
+// Rules возвращает правила пользователя; администратору — правила всех пользователей
+func (u *LifecycleUsecase) Rules(ctx context.Context, userID uint) ([]*models.LifecycleRule, error) {
+	isAdmin, err := u.permissions.HasPermission(ctx, userID, models.PermissionAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admin rights: %w", err)
+	}
+
+	ownerID := userID
+	if isAdmin {
+		ownerID = 0
+	}
+	rules, err := u.r.GetLifecycleRules(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifecycle rules: %w", err)
+	}
+	return rules, nil
+}
+
+// DryRun показывает, какие файлы затронет правило, ничего не изменяя.
+// Правило может быть еще не сохранено; файлы отбираются с правами его автора
+func (u *LifecycleUsecase) DryRun(ctx context.Context, rule *models.LifecycleRule, limit int) (*models.LifecycleDryRun, error) {
+	if err := normalizeLifecycleRule(rule); err != nil {
+		return nil, err
+	}
+	ownerID, err := u.ruleScope(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = defaultLifecycleDryRun
+	}
+	if limit > maxLifecycleDryRun {
+		limit = maxLifecycleDryRun
+	}
+
+	candidates, total, err := u.r.GetLifecycleCandidates(ctx, rule, ownerID, lifecycleCutoff(rule, time.Now()), "", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preview lifecycle rule: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		err := u.files.protect.CheckProtection(ctx, candidate.Name)
+		if errors.Is(err, models.ErrFileProtected) {
+			candidate.Protected = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if candidates == nil {
+		candidates = []*models.LifecycleCandidate{}
+	}
+	return &models.LifecycleDryRun{
+		Rule:  rule,
+		Files: candidates,
+		Total: total,
+	}, nil
+}
+
+// Actions возвращает историю применения правила, последние записи первыми
+func (u *LifecycleUsecase) Actions(ctx context.Context, userID uint, id int64, page, pageSize int) (*models.LifecycleActionPage, error) {
+	if _, err := u.Rule(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultLifecyclePageSize
+	}
+	if pageSize > maxLifecyclePageSize {
+		pageSize = maxLifecyclePageSize
+	}
+
+	result, err := u.r.GetLifecycleActions(ctx, id, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifecycle actions: %w", err)
+	}
+	if result.Actions == nil {
+		result.Actions = []*models.LifecycleAction{}
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// Run периодически применяет включенные правила, очищает корзину и историю.
+// Каждое правило за период обрабатывает только один экземпляр сервиса
+func (u *LifecycleUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		u.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *LifecycleUsecase) runOnce(ctx context.Context) {
+	rules, err := u.r.GetEnabledLifecycleRules(ctx)
+	if err != nil {
+		log.Printf("ERROR: Failed to load lifecycle rules: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		now := time.Now()
+		claimed, err := u.r.ClaimLifecycleRule(ctx, rule.ID, now, now.Add(-u.cfg.Interval/2))
+		if err != nil {
+			log.Printf("ERROR: Failed to claim lifecycle rule %d: %v", rule.ID, err)
+			continue
+		}
+		if claimed {
+			// Права автора могли отозвать после создания правила
+			ownerID, err := u.ruleScope(ctx, rule)
+			if err == nil {
+				err = u.checkRulePermissions(ctx, rule.OwnerID, rule)
+			}
+			if err != nil {
+				log.Printf("WARN: Skipping lifecycle rule %d: owner %d is no longer allowed to apply it: %v", rule.ID, rule.OwnerID, err)
+			} else {
+				u.applyRule(ctx, rule, ownerID, now)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	u.trash.PurgeExpired(ctx)
+
+	if u.cfg.HistoryRetention > 0 {
+		deleted, err := u.r.DeleteLifecycleActionsBefore(ctx, time.Now().Add(-u.cfg.HistoryRetention))
+		if err != nil {
+			log.Printf("ERROR: Failed to prune lifecycle history: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Pruned %d lifecycle history records", deleted)
+		}
+	}
+}
+
+// Применение правила пачками. Файлы перебираются по имени, поэтому
+// пропущенные (защищенные, заблокированные) не выбираются повторно
+func (u *LifecycleUsecase) applyRule(ctx context.Context, rule *models.LifecycleRule, ownerID uint, now time.Time) {
+	cutoff := lifecycleCutoff(rule, now)
+	var after string
+	var applied, skipped, failed int
+
+	for {
+		candidates, _, err := u.r.GetLifecycleCandidates(ctx, rule, ownerID, cutoff, after, u.cfg.BatchSize)
+		if err != nil {
+			log.Printf("ERROR: Failed to select files for lifecycle rule %d: %v", rule.ID, err)
+			break
+		}
+
+		actions := make([]*models.LifecycleAction, 0, len(candidates))
+		for _, candidate := range candidates {
+			after = candidate.Name
+			action := &models.LifecycleAction{
+				RuleID:   rule.ID,
+				FileName: candidate.Name,
+				Action:   rule.Action,
+				Outcome:  models.LifecycleOutcomeApplied,
+			}
+
+			err := u.apply(ctx, rule, candidate)
+			action.AppliedAt = time.Now()
+			switch {
+			case err == nil:
+				applied++
+			case errors.Is(err, models.ErrFileProtected),
+				errors.Is(err, models.ErrFileLocked),
+				errors.Is(err, models.ErrPreconditionFailed),
+				errors.Is(err, models.ErrFileNotFound):
+				action.Outcome = models.LifecycleOutcomeSkipped
+				action.Error = err.Error()
+				skipped++
+			default:
+				log.Printf("ERROR: Lifecycle rule %d failed on %s: %v", rule.ID, candidate.Name, err)
+				action.Outcome = models.LifecycleOutcomeFailed
+				action.Error = err.Error()
+				failed++
+			}
+			actions = append(actions, action)
+		}
+
+		if len(actions) > 0 {
+			if err := u.r.SaveLifecycleActions(ctx, actions); err != nil {
+				log.Printf("ERROR: Failed to save lifecycle history of rule %d: %v", rule.ID, err)
+			}
+		}
+
+		if len(candidates) < u.cfg.BatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if applied > 0 || skipped > 0 || failed > 0 {
+		log.Printf("INFO: Lifecycle rule %d (%s): %d applied, %d skipped, %d failed",
+			rule.ID, rule.Name, applied, skipped, failed)
+	}
+}
+
+// Файл изменяется, только если его версия не поменялась после отбора
+func (u *LifecycleUsecase) apply(ctx context.Context, rule *models.LifecycleRule, candidate *models.LifecycleCandidate) error {
+	cond := Preconditions{IfMatch: candidate.ETag}
+	if rule.Action == models.LifecycleActionTrash {
+		_, err := u.trash.Trash(ctx, candidate.Name, cond, rule.ID)
+		return err
+	}
+	return u.files.DeleteFile(ctx, candidate.Name, cond)
+}
+
+// Правило удаляет файлы, поэтому автору нужно право удаления
+func (u *LifecycleUsecase) checkRulePermissions(ctx context.Context, userID uint, rule *models.LifecycleRule) error {
+	allowed, err := u.permissions.HasPermission(ctx, userID, models.PermissionFilesDelete)
+	if err != nil {
+		return fmt.Errorf("failed to check permission %s: %w", models.PermissionFilesDelete, err)
+	}
+	if !allowed {
+		return ErrLifecycleRuleForbidden
+	}
+	return nil
+}
+
+// Владелец файлов, которые отбирает правило: правило администратора затрагивает
+// файлы всех пользователей (0), правило остальных — только файлы автора.
+// Права проверяются при каждом запуске, поэтому отзыв роли сразу сужает правило
+func (u *LifecycleUsecase) ruleScope(ctx context.Context, rule *models.LifecycleRule) (uint, error) {
+	// Автор удален: правило не должно расшириться до всех файлов
+	if rule.OwnerID == 0 {
+		return 0, ErrLifecycleRuleForbidden
+	}
+	isAdmin, err := u.permissions.HasPermission(ctx, rule.OwnerID, models.PermissionAdmin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check admin rights: %w", err)
+	}
+	if isAdmin {
+		return 0, nil
+	}
+	return rule.OwnerID, nil
+}
+
+// Файлы, измененные (или открытые) раньше этого момента, подпадают под правило
+func lifecycleCutoff(rule *models.LifecycleRule, now time.Time) time.Time {
+	return now.AddDate(0, 0, -rule.AfterDays)
+}
+
+func normalizeLifecycleRule(rule *models.LifecycleRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Tag = strings.TrimSpace(rule.Tag)
+
+	switch {
+	case rule.Name == "":
+		return fmt.Errorf("%w: empty name", ErrInvalidLifecycleRule)
+	case utf8.RuneCountInString(rule.Name) > maxLifecycleRuleNameLength:
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidLifecycleRule, maxLifecycleRuleNameLength)
+	case rule.Condition != models.LifecycleConditionAge && rule.Condition != models.LifecycleConditionIdle:
+		return fmt.Errorf("%w: unknown condition %q", ErrInvalidLifecycleRule, rule.Condition)
+	case rule.Action != models.LifecycleActionDelete && rule.Action != models.LifecycleActionTrash:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidLifecycleRule, rule.Action)
+	case rule.AfterDays < 1 || rule.AfterDays > maxLifecycleAfterDays:
+		return fmt.Errorf("%w: after_days must be between 1 and %d", ErrInvalidLifecycleRule, maxLifecycleAfterDays)
+	}
+	return nil
+}
+
+func lifecycleRuleTarget(rule *models.LifecycleRule) string {
+	if rule.ID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(rule.ID, 10)
+}
+
+func lifecycleRuleDetails(rule *models.LifecycleRule) map[string]string {
+	return map[string]string{
+		"name":       rule.Name,
+		"prefix":     rule.Prefix,
+		"tag":        rule.Tag,
+		"condition":  rule.Condition,
+		"after_days": strconv.Itoa(rule.AfterDays),
+		"action":     rule.Action,
+		"enabled":    strconv.FormatBool(rule.Enabled),
+	}
+}
+
+type nopAccessRecorder struct{}
+
+func (nopAccessRecorder) RecordAccess(context.Context, string) {}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+)
+
+// lifecycleTestRepository запоминает, для какого владельца отбирались файлы
+type lifecycleTestRepository struct {
+	LifecycleRepository
+	ownerID uint
+}
+
+func (r *lifecycleTestRepository) GetLifecycleCandidates(ctx context.Context, rule *models.LifecycleRule, ownerID uint, before time.Time, after string, limit int) ([]*models.LifecycleCandidate, int, error) {
+	r.ownerID = ownerID
+	return nil, 0, nil
+}
+
+func (r *lifecycleTestRepository) CreateLifecycleRule(ctx context.Context, rule *models.LifecycleRule) error {
+	rule.ID = 1
+	return nil
+}
+
+// staticPermissions права пользователей по ID
+type staticPermissions map[uint][]string
+
+func (p staticPermissions) HasPermission(ctx context.Context, userID uint, permission string) (bool, error) {
+	for _, granted := range p[userID] {
+		if granted == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestLifecycleRuleScope(t *testing.T) {
+	const (
+		admin  = uint(1)
+		member = uint(2)
+		reader = uint(3)
+	)
+	repo := &lifecycleTestRepository{}
+	files := New(&memoryStorage{files: map[string][]byte{}}, nil, MetadataLimits{})
+	u := NewLifecycleUsecase(repo, files, nil, staticPermissions{
+		admin:  {models.PermissionAdmin, models.PermissionFilesDelete},
+		member: {models.PermissionFilesDelete},
+	}, LifecycleConfig{})
+	ctx := context.Background()
+
+	newRule := func() *models.LifecycleRule {
+		return &models.LifecycleRule{
+			Name:      "scratch",
+			Tag:       "scratch",
+			Condition: models.LifecycleConditionAge,
+			AfterDays: 30,
+			Action:    models.LifecycleActionDelete,
+		}
+	}
+
+	// Правило только по тегу без префикса доступно пользователю с правом удаления
+	if _, err := u.CreateRule(ctx, member, newRule()); err != nil {
+		t.Fatalf("CreateRule by member: %v", err)
+	}
+	if _, err := u.CreateRule(ctx, reader, newRule()); !errors.Is(err, ErrLifecycleRuleForbidden) {
+		t.Errorf("CreateRule without files:delete: got %v, want ErrLifecycleRuleForbidden", err)
+	}
+
+	tests := []struct {
+		name    string
+		ownerID uint
+		want    uint
+		err     error
+	}{
+		{name: "member rule limited to own files", ownerID: member, want: member},
+		{name: "admin rule covers all files", ownerID: admin, want: 0},
+		{name: "rule of deleted user", ownerID: 0, err: ErrLifecycleRuleForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.ownerID = 42
+			rule := newRule()
+			rule.OwnerID = tt.ownerID
+			_, err := u.DryRun(ctx, rule, 10)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("DryRun: got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DryRun: %v", err)
+			}
+			if repo.ownerID != tt.want {
+				t.Errorf("candidates selected for owner %d, want %d", repo.ownerID, tt.want)
+			}
+		})
+	}
+}
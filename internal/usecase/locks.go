@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tages/internal/models"
+)
+
+type LockRepository interface {
+	AcquireLock(ctx context.Context, lock *models.FileLock) (*models.FileLock, error)
+	RefreshLock(ctx context.Context, filename, token string, expiresAt, now time.Time) (*models.FileLock, error)
+	ReleaseLock(ctx context.Context, filename, token string, now time.Time) error
+	BreakLock(ctx context.Context, filename string, now time.Time) (*models.FileLock, error)
+	GetLock(ctx context.Context, filename string, now time.Time) (*models.FileLock, error)
+	GetActiveLocks(ctx context.Context, now time.Time) ([]*models.FileLock, error)
+}
+
+// FileLocker проверяет блокировки перед изменением файлов
+type FileLocker interface {
+	// CheckLock возвращает ErrFileLocked, если файл заблокирован и token не совпадает с токеном блокировки
+	CheckLock(ctx context.Context, filename, token string) error
+	// ActiveLocks возвращает активные блокировки по именам файлов, без токенов
+	ActiveLocks(ctx context.Context) (map[string]*models.FileLock, error)
+}
+
+type LockConfig struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// LockUsecase явные блокировки файлов (check-out/check-in)
+type LockUsecase struct {
+	r       LockRepository
+	cfg     LockConfig
+	auditor Auditor
+}
+
+func NewLockUsecase(r LockRepository, cfg LockConfig) *LockUsecase {
+	return &LockUsecase{
+		r:       r,
+		cfg:     cfg,
+		auditor: nopAuditor{},
+	}
+}
+
+// SetAuditor подключает журнал аудита принудительных снятий блокировок
+func (u *LockUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// Lock блокирует файл и возвращает блокировку с токеном. Владелец может
+// заблокировать файл повторно, получив новый токен. Если файл заблокирован
+// другим пользователем, возвращается текущая блокировка без токена и ErrFileLocked
+func (u *LockUsecase) Lock(ctx context.Context, userID uint, filename string, ttl time.Duration) (*models.FileLock, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	now := time.Now()
+	lock, err := u.r.AcquireLock(ctx, &models.FileLock{
+		FileName:   filename,
+		Token:      token,
+		OwnerID:    userID,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(u.ttl(ttl)),
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrFileLocked) {
+			return u.currentLock(ctx, filename), err
+		}
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d locked file %s until %s", userID, filename, lock.ExpiresAt.Format(time.RFC3339))
+	return lock, nil
+}
+
+// Refresh продлевает блокировку по токену
+func (u *LockUsecase) Refresh(ctx context.Context, filename, token string, ttl time.Duration) (*models.FileLock, error) {
+	now := time.Now()
+	lock, err := u.r.RefreshLock(ctx, filename, token, now.Add(u.ttl(ttl)), now)
+	if err != nil {
+		return nil, u.lockError(ctx, filename, err)
+	}
+	return lock, nil
+}
+
+// Unlock снимает блокировку по токену
+func (u *LockUsecase) Unlock(ctx context.Context, filename, token string) error {
+	if err := u.r.ReleaseLock(ctx, filename, token, time.Now()); err != nil {
+		return u.lockError(ctx, filename, err)
+	}
+
+	log.Printf("INFO: File %s unlocked", filename)
+	return nil
+}
+
+// Break принудительно снимает блокировку. Доступно администраторам
+func (u *LockUsecase) Break(ctx context.Context, filename string) (_ *models.FileLock, err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionFileLockBreak, filename, err) }()
+
+	lock, err := u.r.BreakLock(ctx, filename, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: Lock of user %d on file %s was broken", lock.OwnerID, filename)
+	return lock, nil
+}
+
+func (u *LockUsecase) CheckLock(ctx context.Context, filename, token string) error {
+	lock, err := u.r.GetLock(ctx, filename, time.Now())
+	if err != nil {
+		if errors.Is(err, models.ErrLockNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to check lock on %s: %w", filename, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(lock.Token), []byte(token)) != 1 {
+		return models.ErrFileLocked
+	}
+	return nil
+}
+
+func (u *LockUsecase) ActiveLocks(ctx context.Context) (map[string]*models.FileLock, error) {
+	locks, err := u.r.GetActiveLocks(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*models.FileLock, len(locks))
+	for _, lock := range locks {
+		byName[lock.FileName] = lock
+	}
+	return byName, nil
+}
+
+func (u *LockUsecase) ttl(requested time.Duration) time.Duration {
+	if requested <= 0 {
+		return u.cfg.DefaultTTL
+	}
+	if requested > u.cfg.MaxTTL {
+		return u.cfg.MaxTTL
+	}
+	return requested
+}
+
+// Если токен не подошел, но файл заблокирован, сообщаем о чужой блокировке
+func (u *LockUsecase) lockError(ctx context.Context, filename string, err error) error {
+	if !errors.Is(err, models.ErrLockNotFound) {
+		return err
+	}
+	if u.currentLock(ctx, filename) != nil {
+		return models.ErrFileLocked
+	}
+	return err
+}
+
+// Текущая активная блокировка файла без токена
+func (u *LockUsecase) currentLock(ctx context.Context, filename string) *models.FileLock {
+	lock, err := u.r.GetLock(ctx, filename, time.Now())
+	if err != nil {
+		if !errors.Is(err, models.ErrLockNotFound) {
+			log.Printf("ERROR: Failed to get lock on %s: %v", filename, err)
+		}
+		return nil
+	}
+	lock.Token = ""
+	return lock
+}
+
+// Заглушка, пока блокировки не подключены
+type nopFileLocker struct{}
+
+func (nopFileLocker) CheckLock(context.Context, string, string) error { return nil }
+
+func (nopFileLocker) ActiveLocks(context.Context) (map[string]*models.FileLock, error) {
+	return nil, nil
+}
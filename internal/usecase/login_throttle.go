@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"tages/internal/models"
+)
+
+// ErrTooManyAttempts учетная запись или IP временно заблокированы после неудачных попыток
+var ErrTooManyAttempts = errors.New("too many failed attempts")
+
+// LockoutError блокировка после неудачных попыток. Сравнивается с ErrTooManyAttempts
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// Префиксы счетчиков неудачных попыток
+const (
+	accountAttemptsPrefix = "account:"
+	ipAttemptsPrefix      = "ip:"
+)
+
+type LoginThrottleConfig struct {
+	// Неудачных попыток до блокировки учетной записи и IP адреса
+	AccountMaxFailures int
+	IPMaxFailures      int
+	// Первая блокировка; каждая следующая неудача удваивает ее до MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Счетчик обнуляется, если столько времени не было неудач и блокировок
+	ResetAfter time.Duration
+}
+
+// SetLoginThrottleConfig задает пороги блокировки при подборе паролей
+func (u *UserUsecase) SetLoginThrottleConfig(cfg LoginThrottleConfig) {
+	u.throttle = cfg
+}
+
+// AccountLockout возвращает время окончания блокировки входа в учетную запись.
+// Нулевое время — учетная запись не заблокирована
+func (u *UserUsecase) AccountLockout(ctx context.Context, userID uint) (time.Time, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return u.userRepo.LoginLockedUntil(ctx, []string{accountAttemptsKey(user.Email)}, time.Now())
+}
+
+// UnlockAccount снимает блокировку входа в учетную запись и обнуляет счетчик неудач
+func (u *UserUsecase) UnlockAccount(ctx context.Context, userID uint) (err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionAccountUnlock, strconv.FormatUint(uint64(userID), 10), nil, err)
+	}()
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := u.userRepo.DeleteLoginAttempts(ctx, accountAttemptsKey(user.Email)); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: Login lockout of user %d cleared", userID)
+	return nil
+}
+
+// Счетчики для запроса: учетная запись, если email известен, и IP клиента
+func attemptKeys(ctx context.Context, email string) []string {
+	var keys []string
+	if email != "" {
+		keys = append(keys, accountAttemptsKey(email))
+	}
+	if ip := RequestMetaFromContext(ctx).IP; ip != "" {
+		keys = append(keys, ipAttemptsPrefix+ip)
+	}
+	return keys
+}
+
+func accountAttemptsKey(email string) string {
+	return accountAttemptsPrefix + strings.ToLower(email)
+}
+
+// Проверка блокировки перед попыткой. Возвращает *LockoutError со временем до
+// окончания самой долгой блокировки
+func (u *UserUsecase) checkLockout(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	until, err := u.userRepo.LoginLockedUntil(ctx, keys, now)
+	if err != nil {
+		return err
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return &LockoutError{RetryAfter: max(until.Sub(now), time.Second)}
+}
+
+// Учет неудачной попытки. После порога ключ блокируется, каждая следующая
+// неудача удваивает блокировку. Ошибки хранилища только записываются в лог:
+// попытка уже отклонена
+func (u *UserUsecase) recordFailedAttempt(ctx context.Context, keys []string) {
+	now := time.Now()
+	for _, key := range keys {
+		failures, err := u.userRepo.RecordLoginFailure(ctx, key, now, now.Add(-u.throttle.ResetAfter))
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+
+		lockout := u.lockoutDuration(key, failures)
+		if lockout == 0 {
+			continue
+		}
+		if err := u.userRepo.LockLoginAttempts(ctx, key, now.Add(lockout)); err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+
+		log.Printf("WARN: %s locked for %s after %d failed attempts", key, lockout, failures)
+		recordEvent(ctx, u.auditor, models.AuditActionLockout, key, map[string]string{
+			"failures": strconv.Itoa(failures),
+			"duration": lockout.String(),
+		}, nil)
+	}
+}
+
+// Удачная попытка обнуляет счетчик учетной записи. Счетчик IP не обнуляется,
+// чтобы вход в свою учетную запись не снимал ограничение на подбор чужих
+func (u *UserUsecase) resetFailedAttempts(ctx context.Context, email string) {
+	if err := u.userRepo.DeleteLoginAttempts(ctx, accountAttemptsKey(email)); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+}
+
+// Длительность блокировки после failures неудач подряд; 0 — порог не достигнут
+func (u *UserUsecase) lockoutDuration(key string, failures int) time.Duration {
+	maxFailures := u.throttle.IPMaxFailures
+	if strings.HasPrefix(key, accountAttemptsPrefix) {
+		maxFailures = u.throttle.AccountMaxFailures
+	}
+	if failures < maxFailures {
+		return 0
+	}
+
+	lockout := u.throttle.BaseLockout
+	for i := maxFailures; i < failures && lockout < u.throttle.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, u.throttle.MaxLockout)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tages/internal/models"
+)
+
+var ErrInvalidMetadata = errors.New("invalid file metadata")
+
+// MetadataLimits ограничения на теги и атрибуты файла
+type MetadataLimits struct {
+	MaxTags        int
+	MaxAttributes  int
+	MaxKeyLength   int
+	MaxValueLength int
+}
+
+// Нормализация и проверка тегов и атрибутов перед сохранением
+func (l MetadataLimits) normalize(tags []string, attributes map[string]string) ([]string, map[string]string, error) {
+	seen := make(map[string]struct{}, len(tags))
+	normalizedTags := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		if l.MaxValueLength > 0 && len(tag) > l.MaxValueLength {
+			return nil, nil, fmt.Errorf("%w: tag %q is longer than %d bytes", ErrInvalidMetadata, tag, l.MaxValueLength)
+		}
+		seen[tag] = struct{}{}
+		normalizedTags = append(normalizedTags, tag)
+	}
+	if l.MaxTags > 0 && len(normalizedTags) > l.MaxTags {
+		return nil, nil, fmt.Errorf("%w: too many tags (%d > %d)", ErrInvalidMetadata, len(normalizedTags), l.MaxTags)
+	}
+
+	normalizedAttributes := make(map[string]string, len(attributes))
+	for key, value := range attributes {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, nil, fmt.Errorf("%w: empty attribute key", ErrInvalidMetadata)
+		}
+		if l.MaxKeyLength > 0 && len(key) > l.MaxKeyLength {
+			return nil, nil, fmt.Errorf("%w: attribute key %q is longer than %d bytes", ErrInvalidMetadata, key, l.MaxKeyLength)
+		}
+		if l.MaxValueLength > 0 && len(value) > l.MaxValueLength {
+			return nil, nil, fmt.Errorf("%w: value of attribute %q is longer than %d bytes", ErrInvalidMetadata, key, l.MaxValueLength)
+		}
+		normalizedAttributes[key] = value
+	}
+	if l.MaxAttributes > 0 && len(normalizedAttributes) > l.MaxAttributes {
+		return nil, nil, fmt.Errorf("%w: too many attributes (%d > %d)", ErrInvalidMetadata, len(normalizedAttributes), l.MaxAttributes)
+	}
+
+	return normalizedTags, normalizedAttributes, nil
+}
+
+// Замена тегов и атрибутов существующего файла
+func (u *Usecase) UpdateMetadata(ctx context.Context, filename string, tags []string, attributes map[string]string, cond Preconditions) (_ *models.FileMeta, err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionMetadataUpdate, filename, err) }()
+	log.Printf("INFO: Updating metadata for file: %s", filename)
+
+	tags, attributes, err = u.limits.normalize(tags, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &models.FileMeta{
+		Name:       filename,
+		Tags:       tags,
+		Attributes: attributes,
+		ETag:       newETag(),
+	}
+	err = u.r.WithFileMutex(ctx, []string{filename}, func(ctx context.Context) error {
+		current, err := u.currentMeta(ctx, filename)
+		if err != nil {
+			return err
+		}
+		if err := cond.check(current); err != nil {
+			return err
+		}
+
+		meta.UpdatedAt = time.Now()
+		if err := u.r.UpdateFileMetadata(ctx, meta); err != nil {
+			if errors.Is(err, models.ErrFileNotFound) {
+				return err
+			}
+			return fmt.Errorf("failed to update metadata for %s: %w", filename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Publish(ctx, &models.FileEvent{Type: models.FileEventUpdated, FileName: filename})
+
+	log.Printf("INFO: Successfully updated metadata for file: %s", filename)
+	return meta, nil
+}
@@ -0,0 +1,380 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tages/internal/auth"
+	"tages/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ошибки двухфакторной аутентификации
+var (
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+	ErrMFANotEnabled     = errors.New("mfa is not enabled")
+	ErrMFANotEnrolled    = errors.New("totp enrollment is not started")
+)
+
+const (
+	// Неверных кодов на один токен второго шага; затем нужно снова ввести пароль
+	maxMFAAttempts = 5
+	// Символов в коде восстановления, без учета дефиса
+	recoveryCodeLength = 10
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+type MFAConfig struct {
+	Issuer        string
+	RecoveryCodes int
+}
+
+// MFAStatus состояние двухфакторной аутентификации пользователя
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment секрет для приложения-аутентификатора. Показывается один раз
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// SetMFAConfig задает название сервиса для приложений-аутентификаторов
+// и количество кодов восстановления
+func (u *UserUsecase) SetMFAConfig(cfg MFAConfig) {
+	u.mfa = cfg
+}
+
+// LoginMFA второй шаг входа: обмен токена, выданного после проверки пароля,
+// и кода TOTP или кода восстановления на пару токенов
+func (u *UserUsecase) LoginMFA(ctx context.Context, mfaToken, code, deviceName string) (*models.User, *auth.TokenPair, error) {
+	claims, err := u.authManager.ParseMFAToken(mfaToken)
+	if err != nil {
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, 0, "", ErrInvalidMFAToken)
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	// Токен одноразовый; выход на всех устройствах отзывает и его
+	revoked, err := u.IsAccessTokenRevoked(ctx, claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check mfa token: %w", err)
+	}
+	if revoked {
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, claims.UserID, "", ErrInvalidMFAToken)
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.MFAEnabled {
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, user.ID, user.Email, ErrInvalidMFAToken)
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	// Неверные коды считаются неудачными попытками входа в учетную запись
+	keys := attemptKeys(ctx, user.Email)
+	if err := u.checkLockout(ctx, keys); err != nil {
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, user.ID, user.Email, err)
+		return nil, nil, err
+	}
+
+	if err := u.verifyMFACode(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			u.recordFailedAttempt(ctx, keys)
+		}
+		if errors.Is(err, ErrInvalidMFACode) && u.mfaFailures.add(claims.ID, claims.ExpiresAt.Time) >= maxMFAAttempts {
+			log.Printf("WARN: Too many invalid mfa codes for user %d, revoking mfa token", user.ID)
+			if revokeErr := u.revokeAccessToken(ctx, claims); revokeErr != nil {
+				log.Printf("ERROR: %v", revokeErr)
+			}
+		}
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, user.ID, user.Email, err)
+		return nil, nil, err
+	}
+	u.mfaFailures.reset(claims.ID)
+
+	if err := u.revokeAccessToken(ctx, claims); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := u.startSession(ctx, user, deviceName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u.resetFailedAttempts(ctx, user.Email)
+	u.recordAuthEvent(ctx, models.AuditActionLogin, user.ID, user.Email, nil)
+	log.Printf("INFO: User %s logged in successfully with mfa", user.Email)
+	return user, tokens, nil
+}
+
+// MFAStatus возвращает состояние двухфакторной аутентификации пользователя
+func (u *UserUsecase) MFAStatus(ctx context.Context, userID uint) (*MFAStatus, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &MFAStatus{
+		Enabled:           user.MFAEnabled,
+		RecoveryCodesLeft: len(user.RecoveryCodes),
+	}, nil
+}
+
+// EnrollTOTP создает секрет TOTP после проверки пароля. Двухфакторная
+// аутентификация включается только после подтверждения кодом из приложения
+func (u *UserUsecase) EnrollTOTP(ctx context.Context, userID uint, password string) (*TOTPEnrollment, error) {
+	user, err := u.reauthenticate(ctx, userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	if err := u.userRepo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	log.Printf("INFO: User %d started totp enrollment", userID)
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    auth.TOTPURI(u.mfa.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает двухфакторную аутентификацию по коду из приложения
+// и возвращает коды восстановления. Коды хранятся только в виде хешей
+func (u *UserUsecase) ConfirmTOTP(ctx context.Context, userID uint, code string) (_ []string, err error) {
+	var email string
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionMFAEnable, userID, email, err)
+	}()
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	email = user.Email
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := u.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.EnableMFA(ctx, userID, step, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	log.Printf("INFO: User %d enabled mfa", userID)
+	return codes, nil
+}
+
+// DisableMFA отключает двухфакторную аутентификацию. Нужны пароль
+// и код TOTP или код восстановления
+func (u *UserUsecase) DisableMFA(ctx context.Context, userID uint, password, code string) (err error) {
+	var email string
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionMFADisable, userID, email, err)
+	}()
+
+	user, err := u.reauthenticateMFA(ctx, userID, password, code)
+	if user != nil {
+		email = user.Email
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.DisableMFA(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+
+	log.Printf("INFO: User %d disabled mfa", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми.
+// Нужны пароль и код TOTP или один из старых кодов
+func (u *UserUsecase) RegenerateRecoveryCodes(ctx context.Context, userID uint, password, code string) (_ []string, err error) {
+	var email string
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionRecoveryCodesRegenerate, userID, email, err)
+	}()
+
+	user, err := u.reauthenticateMFA(ctx, userID, password, code)
+	if user != nil {
+		email = user.Email
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := u.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.SetRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+
+	log.Printf("INFO: User %d regenerated recovery codes", userID)
+	return codes, nil
+}
+
+// Повторная проверка пароля перед изменением настроек безопасности
+func (u *UserUsecase) reauthenticate(ctx context.Context, userID uint, password string) (*models.User, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return user, ErrInvalidPassword
+	}
+	return user, nil
+}
+
+// Повторная проверка пароля и второго фактора
+func (u *UserUsecase) reauthenticateMFA(ctx context.Context, userID uint, password, code string) (*models.User, error) {
+	user, err := u.reauthenticate(ctx, userID, password)
+	if err != nil {
+		return user, err
+	}
+	if !user.MFAEnabled {
+		return user, ErrMFANotEnabled
+	}
+	if err := u.verifyMFACode(ctx, user, code); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// Проверка кода TOTP или кода восстановления. Каждый код принимается один раз
+func (u *UserUsecase) verifyMFACode(ctx context.Context, user *models.User, code string) error {
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		used, err := u.userRepo.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			log.Printf("WARN: Replayed totp code for user %d", user.ID)
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return ErrInvalidMFACode
+	}
+	used, err := u.userRepo.UseRecoveryCode(ctx, user.ID, hashRefreshToken(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	u.recordAuthEvent(ctx, models.AuditActionRecoveryCodeUsed, user.ID, user.Email, nil)
+	log.Printf("INFO: User %d used a recovery code, %d left", user.ID, len(user.RecoveryCodes)-1)
+	return nil
+}
+
+// Коды восстановления в виде xxxxx-xxxxx и их хеши для хранения.
+// Код случайный, поэтому, как и для refresh token, соль не нужна
+func (u *UserUsecase) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, u.mfa.RecoveryCodes)
+	hashes := make([]string, 0, u.mfa.RecoveryCodes)
+	for i := 0; i < u.mfa.RecoveryCodes; i++ {
+		b := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(b)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashRefreshToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// Код восстановления без учета регистра, дефисов и пробелов
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// mfaFailures счетчик неверных кодов по jti токена второго шага.
+// Записи удаляются после истечения срока действия токена
+type mfaFailures struct {
+	mu      sync.Mutex
+	entries map[string]*mfaFailure
+}
+
+type mfaFailure struct {
+	count     int
+	expiresAt time.Time
+}
+
+func newMFAFailures() *mfaFailures {
+	return &mfaFailures{entries: make(map[string]*mfaFailure)}
+}
+
+// Учет неверного кода; возвращает число неудачных попыток по токену
+func (f *mfaFailures) add(jti string, expiresAt time.Time) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range f.entries {
+		if !entry.expiresAt.After(now) {
+			delete(f.entries, key)
+		}
+	}
+
+	entry, ok := f.entries[jti]
+	if !ok {
+		entry = &mfaFailure{expiresAt: expiresAt}
+		f.entries[jti] = entry
+	}
+	entry.count++
+	return entry.count
+}
+
+func (f *mfaFailures) reset(jti string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entries, jti)
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"tages/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+const (
+	// Не больше стольких писем со сбросом пароля на пользователя за окно
+	maxPasswordResetRequests = 3
+	passwordResetWindow      = time.Hour
+	// Время на отправку письма после ответа клиенту
+	mailSendTimeout = time.Minute
+)
+
+// Mailer отправляет письма пользователям
+type Mailer interface {
+	Send(ctx context.Context, msg *models.MailMessage) error
+}
+
+// Почта не настроена: письмо не отправляется
+type nopMailer struct{}
+
+func (nopMailer) Send(_ context.Context, msg *models.MailMessage) error {
+	log.Printf("WARN: Mail is not configured, message %q to %s was not sent", msg.Subject, msg.To)
+	return nil
+}
+
+type PasswordConfig struct {
+	ResetTokenLifetime time.Duration
+	// Страница сброса пароля; токен передается в параметре token
+	ResetURL string
+}
+
+// SetMailer подключает отправку писем
+func (u *UserUsecase) SetMailer(mailer Mailer) {
+	u.mailer = mailer
+}
+
+// SetPasswordConfig задает срок действия токенов сброса пароля и адрес страницы сброса
+func (u *UserUsecase) SetPasswordConfig(cfg PasswordConfig) {
+	u.password = cfg
+}
+
+// RequestPasswordReset выпускает токен сброса пароля и отправляет ссылку на почту.
+// Результат не зависит от того, зарегистрирован ли email, чтобы по ответу
+// нельзя было проверить наличие пользователя
+func (u *UserUsecase) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	var userID uint
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionPasswordResetRequest, userID, email, err)
+	}()
+
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("INFO: Password reset requested for unknown email %s", email)
+		return nil
+	}
+	userID = user.ID
+
+	now := time.Now()
+	count, err := u.userRepo.CountPasswordResetTokens(ctx, user.ID, now.Add(-passwordResetWindow))
+	if err != nil {
+		return err
+	}
+	if count >= maxPasswordResetRequests {
+		log.Printf("WARN: Too many password reset requests for user %d", user.ID)
+		return nil
+	}
+
+	raw, err := generateSecret()
+	if err != nil {
+		return fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	token := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashRefreshToken(raw),
+		ExpiresAt: now.Add(u.password.ResetTokenLifetime),
+		CreatedAt: now,
+	}
+	if err := u.userRepo.CreatePasswordResetToken(ctx, token); err != nil {
+		return err
+	}
+
+	link, err := withTokenParam(u.password.ResetURL, raw)
+	if err != nil {
+		return fmt.Errorf("failed to build password reset link: %w", err)
+	}
+	u.sendMail(ctx, &models.MailMessage{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Для вашей учетной записи запрошен сброс пароля.\n\n"+
+			"Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d мин. и может быть использована один раз.\n"+
+			"Если вы не запрашивали сброс пароля, проигнорируйте это письмо.\n",
+			link, int(u.password.ResetTokenLifetime.Minutes())),
+	})
+
+	log.Printf("INFO: Password reset token issued for user %d", user.ID)
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма. Токен одноразовый;
+// все сессии пользователя завершаются
+func (u *UserUsecase) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	var userID uint
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionPasswordReset, userID, "", err)
+	}()
+
+	resetToken, err := u.userRepo.ConsumePasswordResetToken(ctx, hashRefreshToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, models.ErrPasswordResetTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	userID = resetToken.UserID
+
+	if err := u.replacePassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d reset password", userID)
+	return nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все сессии
+// пользователя, включая текущую, завершаются
+func (u *UserUsecase) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) (err error) {
+	var email string
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionPasswordChange, userID, email, err)
+	}()
+
+	user, err := u.reauthenticate(ctx, userID, currentPassword)
+	if user != nil {
+		email = user.Email
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := u.replacePassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d changed password", userID)
+	return nil
+}
+
+// Сохранение нового пароля, отзыв токенов сброса, завершение сессий
+// и уведомление пользователя
+func (u *UserUsecase) replacePassword(ctx context.Context, userID uint, newPassword string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := u.userRepo.SetPassword(ctx, userID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := u.userRepo.DeletePasswordResetTokens(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := u.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	u.sendMail(ctx, &models.MailMessage{
+		To:      user.Email,
+		Subject: "Пароль изменен",
+		Body: "Пароль вашей учетной записи изменен, все сеансы завершены.\n" +
+			"Если это сделали не вы, восстановите доступ через сброс пароля и обратитесь к администратору.\n",
+	})
+	return nil
+}
+
+// Отправка письма в фоне: время ответа не должно зависеть от почтового сервера
+func (u *UserUsecase) sendMail(ctx context.Context, msg *models.MailMessage) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailSendTimeout)
+	go func() {
+		defer cancel()
+		if err := u.mailer.Send(ctx, msg); err != nil {
+			log.Printf("ERROR: Failed to send mail %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// Ссылка с токеном в параметре token
+func withTokenParam(rawURL, token string) (string, error) {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
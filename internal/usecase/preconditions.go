@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"tages/internal/models"
+)
+
+// Preconditions условия, которые проверяются перед изменением файла
+type Preconditions struct {
+	// Токен блокировки; обязателен, если файл заблокирован
+	LockToken string
+	// Значения заголовков If-Match и If-None-Match: "*" или список ETag через запятую
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// check сравнивает условия с текущей версией файла (nil, если файла нет)
+func (p Preconditions) check(current *models.FileMeta) error {
+	if p.IfMatch != "" {
+		if current == nil {
+			return fmt.Errorf("%w: file does not exist", models.ErrPreconditionFailed)
+		}
+		if !matchETag(p.IfMatch, current.ETag, false) {
+			return fmt.Errorf("%w: file has changed", models.ErrPreconditionFailed)
+		}
+	}
+	if p.IfNoneMatch != "" && current != nil && matchETag(p.IfNoneMatch, current.ETag, true) {
+		return fmt.Errorf("%w: file already exists", models.ErrPreconditionFailed)
+	}
+	return nil
+}
+
+// matchETag проверяет, есть ли etag в списке из заголовка. If-Match требует
+// сильного сравнения (RFC 9110, 13.1.1): слабые теги W/ не совпадают никогда.
+// Для If-None-Match используется слабое сравнение, префикс W/ игнорируется
+func matchETag(list, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		candidate = strings.Trim(candidate, `"`)
+		if candidate != "" && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func newETag() string {
+	b := make([]byte, 16)
+	// Ошибка crypto/rand возможна только при неисправном источнике энтропии
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Текущие метаданные файла или nil, если файла нет
+func (u *Usecase) currentMeta(ctx context.Context, filename string) (*models.FileMeta, error) {
+	meta, err := u.r.GetFileMeta(ctx, filename)
+	if err != nil {
+		if errors.Is(err, models.ErrFileNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get file meta for %s: %w", filename, err)
+	}
+	return meta, nil
+}
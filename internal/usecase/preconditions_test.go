@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"tages/internal/models"
+)
+
+func TestPreconditionsCheck(t *testing.T) {
+	file := &models.FileMeta{Name: "a.txt", ETag: "abc"}
+
+	tests := []struct {
+		name    string
+		cond    Preconditions
+		current *models.FileMeta
+		ok      bool
+	}{
+		{name: "no conditions", cond: Preconditions{}, current: file, ok: true},
+		{name: "if-match strong", cond: Preconditions{IfMatch: `"abc"`}, current: file, ok: true},
+		{name: "if-match in list", cond: Preconditions{IfMatch: `"x", "abc"`}, current: file, ok: true},
+		{name: "if-match star", cond: Preconditions{IfMatch: "*"}, current: file, ok: true},
+		{name: "if-match changed", cond: Preconditions{IfMatch: `"x"`}, current: file, ok: false},
+		{name: "if-match weak never matches", cond: Preconditions{IfMatch: `W/"abc"`}, current: file, ok: false},
+		{name: "if-match missing file", cond: Preconditions{IfMatch: "*"}, current: nil, ok: false},
+		{name: "if-none-match star exists", cond: Preconditions{IfNoneMatch: "*"}, current: file, ok: false},
+		{name: "if-none-match star missing", cond: Preconditions{IfNoneMatch: "*"}, current: nil, ok: true},
+		{name: "if-none-match weak", cond: Preconditions{IfNoneMatch: `W/"abc"`}, current: file, ok: false},
+		{name: "if-none-match other", cond: Preconditions{IfNoneMatch: `"x"`}, current: file, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cond.check(tt.current)
+			if tt.ok && err != nil {
+				t.Errorf("check: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, models.ErrPreconditionFailed) {
+				t.Errorf("check: got %v, want ErrPreconditionFailed", err)
+			}
+		})
+	}
+}
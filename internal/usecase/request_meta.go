@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+
+	"tages/internal/models"
+)
+
+type requestMetaKey struct{}
+
+// WithRequestMeta добавляет в контекст сведения о запросе (IP, User-Agent, ID запроса)
+func WithRequestMeta(ctx context.Context, meta models.RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// WithActor добавляет в контекст ID пользователя, выполняющего запрос
+func WithActor(ctx context.Context, userID uint) context.Context {
+	meta := RequestMetaFromContext(ctx)
+	meta.ActorID = userID
+	return WithRequestMeta(ctx, meta)
+}
+
+// RequestMetaFromContext возвращает сведения о запросе или пустую структуру
+func RequestMetaFromContext(ctx context.Context) models.RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(models.RequestMeta)
+	return meta
+}
@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidRetention   = errors.New("invalid retention")
+	ErrRetentionForbidden = errors.New("insufficient rights for retention")
+)
+
+type RetentionRepository interface {
+	SetFileRetention(ctx context.Context, retention *models.Retention, allowShorten bool) (*models.Retention, error)
+	ClearFileRetention(ctx context.Context, filename string, now time.Time, allowGovernance bool) error
+	SetFolderRetention(ctx context.Context, retention *models.Retention, allowShorten bool) (*models.Retention, error)
+	ClearFolderRetention(ctx context.Context, prefix string, now time.Time, allowGovernance bool) error
+	GetFolderRetentions(ctx context.Context, now time.Time) ([]*models.Retention, error)
+	SetLegalHold(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error)
+	ClearLegalHold(ctx context.Context, filename string) error
+	GetFileProtection(ctx context.Context, filename string, now time.Time) (*models.FileProtection, error)
+	GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error)
+}
+
+// ProtectionChecker запрещает изменять и удалять защищенные файлы
+type ProtectionChecker interface {
+	// CheckProtection возвращает ErrFileProtected, если действует срок хранения или удержание
+	CheckProtection(ctx context.Context, filename string) error
+}
+
+// RetentionUsecase сроки хранения (WORM) и юридические удержания файлов.
+// Срок в режиме governance на файл задает владелец файла или администратор,
+// сократить или снять его может только администратор. Срок в режиме compliance
+// задает только администратор, и его можно только продлить. Срок не может
+// превышать maxPeriod от текущего момента
+type RetentionUsecase struct {
+	r         RetentionRepository
+	admins    AdminChecker
+	auditor   Auditor
+	maxPeriod time.Duration
+}
+
+func NewRetentionUsecase(r RetentionRepository, admins AdminChecker, maxPeriod time.Duration) *RetentionUsecase {
+	return &RetentionUsecase{
+		r:         r,
+		admins:    admins,
+		auditor:   nopAuditor{},
+		maxPeriod: maxPeriod,
+	}
+}
+
+// SetAuditor подключает журнал аудита изменений защит
+func (u *RetentionUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+func (u *RetentionUsecase) CheckProtection(ctx context.Context, filename string) error {
+	protection, err := u.r.GetFileProtection(ctx, filename, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to check protection of %s: %w", filename, err)
+	}
+	if protection.Protected() {
+		return models.ErrFileProtected
+	}
+	return nil
+}
+
+// Protection возвращает действующие защиты файла
+func (u *RetentionUsecase) Protection(ctx context.Context, filename string) (*models.FileProtection, error) {
+	protection, err := u.r.GetFileProtection(ctx, filename, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get protection of %s: %w", filename, err)
+	}
+	return protection, nil
+}
+
+func (u *RetentionUsecase) SetFileRetention(ctx context.Context, userID uint, filename string, until time.Time, mode string) (_ *models.Retention, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionRetentionSet, filename, retentionDetails(until, mode), err)
+	}()
+
+	now := time.Now()
+	if err := u.validateRetention(until, mode, now); err != nil {
+		return nil, err
+	}
+	isAdmin, err := u.checkFileRights(ctx, userID, filename)
+	if err != nil {
+		return nil, err
+	}
+	if mode == models.RetentionModeCompliance && !isAdmin {
+		return nil, ErrRetentionForbidden
+	}
+
+	retention, err := u.r.SetFileRetention(ctx, &models.Retention{
+		FileName:    filename,
+		RetainUntil: until,
+		Mode:        mode,
+		SetBy:       userID,
+		SetAt:       now,
+	}, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d set %s retention on %s until %s", userID, mode, filename, until.Format(time.RFC3339))
+	return retention, nil
+}
+
+func (u *RetentionUsecase) ClearFileRetention(ctx context.Context, userID uint, filename string) (err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionRetentionClear, filename, nil, err) }()
+
+	isAdmin, err := u.checkFileRights(ctx, userID, filename)
+	if err != nil {
+		return err
+	}
+	if err := u.r.ClearFileRetention(ctx, filename, time.Now(), isAdmin); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d cleared retention on %s", userID, filename)
+	return nil
+}
+
+// SetFolderRetention задает срок хранения для всех файлов, имя которых начинается с prefix
+func (u *RetentionUsecase) SetFolderRetention(ctx context.Context, userID uint, prefix string, until time.Time, mode string) (_ *models.Retention, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionFolderRetentionSet, prefix, retentionDetails(until, mode), err)
+	}()
+
+	now := time.Now()
+	if strings.TrimSpace(prefix) == "" {
+		return nil, fmt.Errorf("%w: empty prefix", ErrInvalidRetention)
+	}
+	if err := u.validateRetention(until, mode, now); err != nil {
+		return nil, err
+	}
+	isAdmin, err := u.admins.IsAdmin(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admin rights: %w", err)
+	}
+
+	retention, err := u.r.SetFolderRetention(ctx, &models.Retention{
+		Prefix:      prefix,
+		RetainUntil: until,
+		Mode:        mode,
+		SetBy:       userID,
+		SetAt:       now,
+	}, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d set %s retention on prefix %q until %s", userID, mode, prefix, until.Format(time.RFC3339))
+	return retention, nil
+}
+
+func (u *RetentionUsecase) ClearFolderRetention(ctx context.Context, userID uint, prefix string) (err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionFolderRetentionClear, prefix, nil, err) }()
+
+	isAdmin, err := u.admins.IsAdmin(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check admin rights: %w", err)
+	}
+	if err := u.r.ClearFolderRetention(ctx, prefix, time.Now(), isAdmin); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d cleared retention on prefix %q", userID, prefix)
+	return nil
+}
+
+func (u *RetentionUsecase) FolderRetentions(ctx context.Context) ([]*models.Retention, error) {
+	retentions, err := u.r.GetFolderRetentions(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder retentions: %w", err)
+	}
+	return retentions, nil
+}
+
+// SetLegalHold ставит файл на юридическое удержание. Доступно администраторам
+func (u *RetentionUsecase) SetLegalHold(ctx context.Context, userID uint, filename, reason string) (_ *models.LegalHold, err error) {
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionLegalHoldSet, filename, map[string]string{"reason": reason}, err)
+	}()
+
+	hold, err := u.r.SetLegalHold(ctx, &models.LegalHold{
+		FileName: filename,
+		Reason:   reason,
+		SetBy:    userID,
+		SetAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: User %d placed legal hold on %s", userID, filename)
+	return hold, nil
+}
+
+// ClearLegalHold снимает юридическое удержание. Доступно администраторам
+func (u *RetentionUsecase) ClearLegalHold(ctx context.Context, userID uint, filename string) (err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionLegalHoldClear, filename, nil, err) }()
+
+	if err := u.r.ClearLegalHold(ctx, filename); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d released legal hold on %s", userID, filename)
+	return nil
+}
+
+// Срок на файл меняет его владелец или администратор. Возвращает, администратор ли пользователь
+func (u *RetentionUsecase) checkFileRights(ctx context.Context, userID uint, filename string) (bool, error) {
+	isAdmin, err := u.admins.IsAdmin(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin rights: %w", err)
+	}
+	if isAdmin {
+		return true, nil
+	}
+
+	file, err := u.r.GetFileMeta(ctx, filename)
+	if err != nil {
+		return false, err
+	}
+	if file.OwnerID != userID {
+		return false, ErrRetentionForbidden
+	}
+	return false, nil
+}
+
+func (u *RetentionUsecase) validateRetention(until time.Time, mode string, now time.Time) error {
+	if mode != models.RetentionModeGovernance && mode != models.RetentionModeCompliance {
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidRetention, mode)
+	}
+	if !until.After(now) {
+		return fmt.Errorf("%w: retain_until must be in the future", ErrInvalidRetention)
+	}
+	if u.maxPeriod > 0 && until.After(now.Add(u.maxPeriod)) {
+		return fmt.Errorf("%w: retain_until must be before %s", ErrInvalidRetention, now.Add(u.maxPeriod).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func retentionDetails(until time.Time, mode string) map[string]string {
+	return map[string]string{
+		"mode":         mode,
+		"retain_until": until.UTC().Format(time.RFC3339),
+	}
+}
+
+// Заглушка, пока защита файлов не подключена
+type nopProtectionChecker struct{}
+
+func (nopProtectionChecker) CheckProtection(context.Context, string) error { return nil }
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+)
+
+// retentionTestRepository хранит метаданные файлов и принимает любой срок хранения
+type retentionTestRepository struct {
+	RetentionRepository
+	files map[string]*models.FileMeta
+}
+
+func (r *retentionTestRepository) GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error) {
+	file, ok := r.files[filename]
+	if !ok {
+		return nil, models.ErrFileNotFound
+	}
+	return file, nil
+}
+
+func (r *retentionTestRepository) SetFileRetention(ctx context.Context, retention *models.Retention, allowShorten bool) (*models.Retention, error) {
+	return retention, nil
+}
+
+func (r *retentionTestRepository) ClearFileRetention(ctx context.Context, filename string, now time.Time, allowGovernance bool) error {
+	return nil
+}
+
+type staticAdminChecker map[uint]bool
+
+func (a staticAdminChecker) IsAdmin(ctx context.Context, userID uint) (bool, error) {
+	return a[userID], nil
+}
+
+func TestSetFileRetentionRights(t *testing.T) {
+	const (
+		admin = uint(1)
+		owner = uint(2)
+		other = uint(3)
+	)
+	repo := &retentionTestRepository{files: map[string]*models.FileMeta{
+		"owned.txt":  {Name: "owned.txt", OwnerID: owner},
+		"legacy.txt": {Name: "legacy.txt"},
+	}}
+	u := NewRetentionUsecase(repo, staticAdminChecker{admin: true}, 365*24*time.Hour)
+	soon := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		filename string
+		until    time.Time
+		mode     string
+		err      error
+	}{
+		{name: "owner governance", userID: owner, filename: "owned.txt", until: soon, mode: models.RetentionModeGovernance},
+		{name: "owner compliance", userID: owner, filename: "owned.txt", until: soon, mode: models.RetentionModeCompliance, err: ErrRetentionForbidden},
+		{name: "other user governance", userID: other, filename: "owned.txt", until: soon, mode: models.RetentionModeGovernance, err: ErrRetentionForbidden},
+		{name: "file without owner", userID: other, filename: "legacy.txt", until: soon, mode: models.RetentionModeGovernance, err: ErrRetentionForbidden},
+		{name: "missing file", userID: owner, filename: "missing.txt", until: soon, mode: models.RetentionModeGovernance, err: models.ErrFileNotFound},
+		{name: "admin compliance", userID: admin, filename: "owned.txt", until: soon, mode: models.RetentionModeCompliance},
+		{name: "past max period", userID: admin, filename: "owned.txt", until: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), mode: models.RetentionModeCompliance, err: ErrInvalidRetention},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.SetFileRetention(context.Background(), tt.userID, tt.filename, tt.until, tt.mode)
+			if tt.err == nil && err != nil {
+				t.Fatalf("SetFileRetention: %v", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("SetFileRetention: got %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if err := u.ClearFileRetention(context.Background(), other, "owned.txt"); !errors.Is(err, ErrRetentionForbidden) {
+		t.Errorf("ClearFileRetention by another user: got %v, want ErrRetentionForbidden", err)
+	}
+}
@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrBuiltinRole = errors.New("builtin role cannot be changed")
+)
+
+const maxRoleDescriptionLength = 256
+
+// Имя роли: строчные латинские буквы, цифры, "-" и "_"
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+type RoleRepository interface {
+	GetRoles(ctx context.Context) ([]*models.Role, error)
+	CreateRole(ctx context.Context, role *models.Role) error
+	UpdateRole(ctx context.Context, role *models.Role) error
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// RoleProvider источник ролей для проверки разрешений
+type RoleProvider interface {
+	// Role возвращает роль по имени или models.ErrRoleNotFound
+	Role(ctx context.Context, name string) (*models.Role, error)
+}
+
+// PermissionChecker проверяет разрешения пользователя по его роли
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID uint, permission string) (bool, error)
+}
+
+// Только встроенные роли, пока роли не хранятся в базе
+type builtinRoles struct{}
+
+func (builtinRoles) Role(ctx context.Context, name string) (*models.Role, error) {
+	for _, role := range models.BuiltinRoles() {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, models.ErrRoleNotFound
+}
+
+// RoleUsecase роли пользователей, хранящиеся в базе. Роли проверяются на
+// каждый запрос, поэтому держатся в памяти и периодически перечитываются,
+// чтобы подхватить изменения, сделанные на других экземплярах
+type RoleUsecase struct {
+	r       RoleRepository
+	auditor Auditor
+
+	mu    sync.RWMutex
+	roles map[string]*models.Role
+}
+
+func NewRoleUsecase(r RoleRepository) *RoleUsecase {
+	return &RoleUsecase{
+		r:       r,
+		auditor: nopAuditor{},
+	}
+}
+
+// SetAuditor подключает журнал аудита изменений ролей
+func (u *RoleUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// Run перечитывает роли с периодом interval до отмены контекста
+func (u *RoleUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.reload(ctx); err != nil {
+				log.Printf("ERROR: Failed to reload roles: %v", err)
+			}
+		}
+	}
+}
+
+// Role возвращает роль по имени. Неизвестная роль могла появиться
+// на другом экземпляре, поэтому перед отказом роли перечитываются
+func (u *RoleUsecase) Role(ctx context.Context, name string) (*models.Role, error) {
+	u.mu.RLock()
+	role, ok := u.roles[name]
+	u.mu.RUnlock()
+	if ok {
+		return role, nil
+	}
+
+	if err := u.reload(ctx); err != nil {
+		return nil, err
+	}
+
+	u.mu.RLock()
+	role, ok = u.roles[name]
+	u.mu.RUnlock()
+	if !ok {
+		return nil, models.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// List возвращает все роли
+func (u *RoleUsecase) List(ctx context.Context) ([]*models.Role, error) {
+	roles, err := u.r.GetRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Create добавляет пользовательскую роль
+func (u *RoleUsecase) Create(ctx context.Context, name, description string, permissions []string) (_ *models.Role, err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionRoleCreate, name, nil, err) }()
+
+	if !roleNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 2-64 lowercase letters, digits, '-' or '_'", ErrInvalidRole)
+	}
+	role, err := newRole(name, description, permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.CreatedAt = time.Now()
+
+	if err := u.r.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	u.refresh(ctx)
+
+	log.Printf("INFO: Created role %s with permissions %v", name, role.Permissions)
+	return role, nil
+}
+
+// Update изменяет описание и разрешения пользовательской роли
+func (u *RoleUsecase) Update(ctx context.Context, name, description string, permissions []string) (_ *models.Role, err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionRoleUpdate, name, nil, err) }()
+
+	if isBuiltinRole(name) {
+		return nil, ErrBuiltinRole
+	}
+	role, err := newRole(name, description, permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.UpdatedAt = time.Now()
+
+	if err := u.r.UpdateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	u.refresh(ctx)
+
+	log.Printf("INFO: Updated role %s, permissions %v", name, role.Permissions)
+	return role, nil
+}
+
+// Delete удаляет пользовательскую роль, если она никому не назначена
+func (u *RoleUsecase) Delete(ctx context.Context, name string) (err error) {
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionRoleDelete, name, nil, err) }()
+
+	if isBuiltinRole(name) {
+		return ErrBuiltinRole
+	}
+	if err := u.r.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	u.refresh(ctx)
+
+	log.Printf("INFO: Deleted role %s", name)
+	return nil
+}
+
+func (u *RoleUsecase) reload(ctx context.Context) error {
+	roles, err := u.r.GetRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+
+	byName := make(map[string]*models.Role, len(roles))
+	for _, role := range roles {
+		byName[role.Name] = role
+	}
+
+	u.mu.Lock()
+	u.roles = byName
+	u.mu.Unlock()
+	return nil
+}
+
+// Перечитывание ролей после изменения. Ошибка не отменяет изменение:
+// роли подхватятся при следующем периодическом обновлении
+func (u *RoleUsecase) refresh(ctx context.Context) {
+	if err := u.reload(ctx); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+}
+
+// Проверка полей роли и удаление повторов в разрешениях
+func newRole(name, description string, permissions []string) (*models.Role, error) {
+	if utf8.RuneCountInString(description) > maxRoleDescriptionLength {
+		return nil, fmt.Errorf("%w: description must not exceed %d characters", ErrInvalidRole, maxRoleDescriptionLength)
+	}
+
+	known := make(map[string]bool, len(models.Permissions))
+	for _, permission := range models.Permissions {
+		known[permission] = true
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	role := &models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: make([]string, 0, len(permissions)),
+	}
+	for _, permission := range permissions {
+		if !known[permission] {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidRole, permission)
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		role.Permissions = append(role.Permissions, permission)
+	}
+	return role, nil
+}
+
+func isBuiltinRole(name string) bool {
+	for _, role := range models.BuiltinRoles() {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"tages/internal/models"
+)
+
+var ErrEmptySearchQuery = errors.New("empty search query")
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
+type SearchRepository interface {
+	SearchFiles(ctx context.Context, query string, limit, offset int) (*models.SearchPage, error)
+}
+
+type SearchUsecase struct {
+	r SearchRepository
+}
+
+func NewSearchUsecase(r SearchRepository) *SearchUsecase {
+	return &SearchUsecase{
+		r: r,
+	}
+}
+
+// Поиск файлов. Страницы нумеруются с единицы
+func (u *SearchUsecase) Search(ctx context.Context, query string, page, pageSize int) (*models.SearchPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultSearchPageSize
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+
+	log.Printf("INFO: Searching files: %q (page %d)", query, page)
+
+	result, err := u.r.SearchFiles(ctx, query, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search files: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+
+	log.Printf("INFO: Search %q matched %d files", query, result.Total)
+	return result, nil
+}
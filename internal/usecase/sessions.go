@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Действующие сессии пользователя. currentToken — refresh token текущего
+// клиента; его сессия возвращается вторым значением (0, если не найдена)
+func (u *UserUsecase) Sessions(ctx context.Context, userID uint, currentToken string) ([]*models.RefreshToken, uint, error) {
+	sessions, err := u.userRepo.GetUserSessions(ctx, userID, time.Now())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	var currentID uint
+	if currentToken != "" {
+		currentHash := hashRefreshToken(currentToken)
+		for _, session := range sessions {
+			if session.TokenHash == currentHash {
+				currentID = session.ID
+			}
+		}
+	}
+	return sessions, currentID, nil
+}
+
+// ID сессии пользователя, которой принадлежит refresh token (0, если не найдена)
+func (u *UserUsecase) CurrentSessionID(ctx context.Context, userID uint, token string) uint {
+	if token == "" {
+		return 0
+	}
+	session, err := u.userRepo.GetRefreshToken(ctx, hashRefreshToken(token))
+	if err != nil || session.UserID != userID {
+		return 0
+	}
+	return session.ID
+}
+
+// Завершение одной сессии пользователя
+func (u *UserUsecase) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	err := u.userRepo.DeleteUserSession(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		err = fmt.Errorf("failed to revoke session: %w", err)
+	}
+	recordEvent(ctx, u.auditor, models.AuditActionSessionRevoke, strconv.FormatUint(uint64(sessionID), 10), nil, err)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d revoked session %d", userID, sessionID)
+	return nil
+}
+
+// Завершение всех сессий пользователя. Если keepToken принадлежит одной из
+// сессий пользователя, эта сессия остается активной
+func (u *UserUsecase) RevokeSessions(ctx context.Context, userID uint, keepToken string) (int64, error) {
+	exceptID := u.CurrentSessionID(ctx, userID, keepToken)
+	revoked, err := u.userRepo.DeleteUserSessions(ctx, userID, exceptID)
+	if err != nil {
+		err = fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	recordEvent(ctx, u.auditor, models.AuditActionSessionsRevoke, strconv.FormatUint(uint64(userID), 10), map[string]string{
+		"revoked":      strconv.FormatInt(revoked, 10),
+		"kept_current": strconv.FormatBool(exceptID != 0),
+	}, err)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("INFO: User %d revoked %d sessions", userID, revoked)
+	return revoked, nil
+}
+
+// RunSessionCleanup периодически удаляет истекшие сессии, токены сброса пароля
+// и записи об отозванных access токенах, срок действия которых закончился
+func (u *UserUsecase) RunSessionCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := u.userRepo.DeleteExpiredRefreshTokens(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to delete expired sessions: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Deleted %d expired sessions", deleted)
+		}
+
+		deleted, err = u.userRepo.DeleteExpiredRevokedTokens(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to delete expired revoked tokens: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Deleted %d expired revoked tokens", deleted)
+		}
+
+		deleted, err = u.userRepo.DeleteExpiredPasswordResetTokens(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to delete expired password reset tokens: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Deleted %d expired password reset tokens", deleted)
+		}
+
+		deleted, err = u.userRepo.DeleteExpiredLoginAttempts(ctx, time.Now().Add(-u.throttle.ResetAfter))
+		if err != nil {
+			log.Printf("ERROR: Failed to delete expired login attempts: %v", err)
+		} else if deleted > 0 {
+			log.Printf("INFO: Deleted %d expired login attempt counters", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+)
+
+var ErrInvalidSettings = errors.New("invalid settings")
+
+type SettingsRepository interface {
+	GetSettings(ctx context.Context) (map[string]string, error)
+	SaveSettings(ctx context.Context, settings map[string]string, updatedBy uint, at time.Time) error
+}
+
+// SettingsProvider источник системных настроек
+type SettingsProvider interface {
+	Settings(ctx context.Context) (*models.Settings, error)
+}
+
+// Настройки по умолчанию, пока настройки не хранятся в базе
+type defaultSettings struct{}
+
+func (defaultSettings) Settings(ctx context.Context) (*models.Settings, error) {
+	return models.DefaultSettings(), nil
+}
+
+// SettingsPatch изменение настроек: nil — оставить значение без изменений
+type SettingsPatch struct {
+	RegistrationEnabled *bool
+	DefaultRole         *string
+}
+
+// SettingsUsecase системные настройки, которые администратор меняет без перезапуска
+type SettingsUsecase struct {
+	r       SettingsRepository
+	roles   RoleProvider
+	auditor Auditor
+}
+
+func NewSettingsUsecase(r SettingsRepository, roles RoleProvider) *SettingsUsecase {
+	return &SettingsUsecase{
+		r:       r,
+		roles:   roles,
+		auditor: nopAuditor{},
+	}
+}
+
+// SetAuditor подключает журнал аудита изменений настроек
+func (u *SettingsUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// Settings возвращает действующие настройки: сохраненные значения поверх значений по умолчанию
+func (u *SettingsUsecase) Settings(ctx context.Context) (*models.Settings, error) {
+	stored, err := u.r.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := models.DefaultSettings()
+	if value, ok := stored[models.SettingRegistrationEnabled]; ok {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			settings.RegistrationEnabled = enabled
+		}
+	}
+	if value, ok := stored[models.SettingDefaultRole]; ok && value != "" {
+		settings.DefaultRole = value
+	}
+	return settings, nil
+}
+
+// Update изменяет настройки и возвращает действующие значения
+func (u *SettingsUsecase) Update(ctx context.Context, userID uint, patch SettingsPatch) (_ *models.Settings, err error) {
+	changes := make(map[string]string, 2)
+	defer func() { recordEvent(ctx, u.auditor, models.AuditActionSettingsUpdate, "", changes, err) }()
+
+	if patch.RegistrationEnabled != nil {
+		changes[models.SettingRegistrationEnabled] = strconv.FormatBool(*patch.RegistrationEnabled)
+	}
+	if patch.DefaultRole != nil {
+		if _, err := u.roles.Role(ctx, *patch.DefaultRole); err != nil {
+			if errors.Is(err, models.ErrRoleNotFound) {
+				return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidSettings, *patch.DefaultRole)
+			}
+			return nil, err
+		}
+		changes[models.SettingDefaultRole] = *patch.DefaultRole
+	}
+
+	if len(changes) > 0 {
+		if err := u.r.SaveSettings(ctx, changes, userID, time.Now()); err != nil {
+			return nil, err
+		}
+		log.Printf("INFO: User %d updated settings %v", userID, changes)
+	}
+
+	return u.Settings(ctx)
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"tages/internal/auth"
+	"tages/internal/models"
+)
+
+// IsAccessTokenRevoked проверяет, отозван ли access token: по списку отозванных
+// jti и по поколению токенов пользователя. Поколение сравнивается на равенство,
+// поэтому токен, выпущенный сразу после отзыва, даже в ту же секунду, действителен.
+// Вызывается на каждый аутентифицированный запрос: без секции cache в конфигурации
+// каждый запрос дополнительно читает пользователя и список отозванных токенов из базы
+func (u *UserUsecase) IsAccessTokenRevoked(ctx context.Context, claims *auth.TokenClaims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := u.userRepo.IsAccessTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	return claims.TokenVersion != user.TokenVersion, nil
+}
+
+// Выход на всех устройствах: завершение всех сессий и отзыв всех выпущенных access токенов
+func (u *UserUsecase) LogoutAll(ctx context.Context, userID uint) (err error) {
+	log.Printf("INFO: Logging out user %d everywhere", userID)
+	defer func() {
+		u.recordAuthEvent(ctx, models.AuditActionLogoutAll, userID, "", err)
+	}()
+
+	revoked, err := u.revokeAllSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d logged out everywhere, %d sessions revoked", userID, revoked)
+	return nil
+}
+
+// Завершение всех сессий пользователя и отзыв всех его access токенов
+func (u *UserUsecase) revokeAllSessions(ctx context.Context, userID uint) (int64, error) {
+	if err := u.userRepo.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	revoked, err := u.userRepo.DeleteUserSessions(ctx, userID, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// Добавление access token в список отозванных до истечения его срока действия
+func (u *UserUsecase) revokeAccessToken(ctx context.Context, claims *auth.TokenClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	if err := u.userRepo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	log.Printf("INFO: Access token %s of user %d revoked", claims.ID, claims.UserID)
+	return nil
+}
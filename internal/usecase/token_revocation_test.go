@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tages/internal/auth"
+	"tages/internal/config"
+	"tages/internal/models"
+)
+
+// revocationUserRepository хранит одного пользователя; остальные методы не используются
+type revocationUserRepository struct {
+	UserRepository
+	user *models.User
+}
+
+func (r *revocationUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	user := *r.user
+	return &user, nil
+}
+
+func (r *revocationUserRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return false, nil
+}
+
+func (r *revocationUserRepository) RevokeUserTokens(ctx context.Context, userID uint, revokedAt time.Time) error {
+	r.user.TokensValidAfter = &revokedAt
+	r.user.TokenVersion++
+	return nil
+}
+
+func (r *revocationUserRepository) DeleteUserSessions(ctx context.Context, userID, exceptID uint) (int64, error) {
+	return 0, nil
+}
+
+func TestAccessTokenRevocationSameSecond(t *testing.T) {
+	repo := &revocationUserRepository{user: &models.User{ID: 1, Role: models.RoleMember}}
+	u := &UserUsecase{userRepo: repo}
+	tokens, err := auth.NewTokenManager(&config.JWT{
+		AccessTokenSecret:      "access",
+		RefreshTokenSecret:     "refresh",
+		AccessTokenExpiration:  15,
+		RefreshTokenExpiration: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	tokens.SetRevocationChecker(u)
+	ctx := context.Background()
+
+	issue := func() string {
+		t.Helper()
+		user, _ := repo.GetUserByID(ctx, 1)
+		pair, err := tokens.GenerateTokenPair(user)
+		if err != nil {
+			t.Fatalf("GenerateTokenPair: %v", err)
+		}
+		return pair.AccessToken
+	}
+
+	// Вход, отзыв всех токенов и новый вход укладываются в одну секунду
+	before := issue()
+	if _, err := u.revokeAllSessions(ctx, 1); err != nil {
+		t.Fatalf("revokeAllSessions: %v", err)
+	}
+	after := issue()
+
+	if _, err := tokens.ParseAccessToken(ctx, before); !errors.Is(err, auth.ErrRevokedToken) {
+		t.Errorf("token issued before revocation: got %v, want ErrRevokedToken", err)
+	}
+	if _, err := tokens.ParseAccessToken(ctx, after); err != nil {
+		t.Errorf("token issued after revocation: %v", err)
+	}
+}
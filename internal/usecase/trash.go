@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+)
+
+const (
+	defaultTrashPageSize = 20
+	maxTrashPageSize     = 100
+	trashPurgeBatchSize  = 100
+)
+
+// Содержимое файлов в корзине хранится в служебном каталоге хранилища.
+// Имена файлов не содержат разделителя пути, поэтому через API он недоступен
+const trashDir = ".trash"
+
+type TrashRepository interface {
+	SaveTrashedFile(ctx context.Context, file *models.TrashedFile) error
+	GetTrashedFile(ctx context.Context, id int64) (*models.TrashedFile, error)
+	GetTrashedFiles(ctx context.Context, limit, offset int) (*models.TrashPage, error)
+	GetExpiredTrash(ctx context.Context, before time.Time, after int64, limit int) ([]*models.TrashedFile, error)
+	DeleteTrashedFile(ctx context.Context, id int64) error
+}
+
+// TrashUsecase корзина: удаленные файлы хранятся retention, затем очищаются.
+// Перенос в корзину проверяет те же условия, что и удаление файла
+type TrashUsecase struct {
+	files     *Usecase
+	r         TrashRepository
+	retention time.Duration
+}
+
+// retention — срок хранения файлов в корзине, 0 — хранить до ручной очистки
+func NewTrashUsecase(files *Usecase, r TrashRepository, retention time.Duration) *TrashUsecase {
+	return &TrashUsecase{
+		files:     files,
+		r:         r,
+		retention: retention,
+	}
+}
+
+// Trash переносит файл в корзину. ruleID — правило жизненного цикла, 0 — вручную
+func (u *TrashUsecase) Trash(ctx context.Context, filename string, cond Preconditions, ruleID int64) (_ *models.TrashedFile, err error) {
+	defer func() { recordFileEvent(ctx, u.files.auditor, models.AuditActionFileTrash, filename, err) }()
+	log.Printf("INFO: Moving file to trash: %s", filename)
+
+	var trashed *models.TrashedFile
+	err = u.files.r.WithFileMutex(ctx, []string{filename}, func(ctx context.Context) error {
+		current, err := u.files.currentMeta(ctx, filename)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return models.ErrFileNotFound
+		}
+		if err := cond.check(current); err != nil {
+			return err
+		}
+		if err := u.files.locks.CheckLock(ctx, filename, cond.LockToken); err != nil {
+			return err
+		}
+		if err := u.files.protect.CheckProtection(ctx, filename); err != nil {
+			return err
+		}
+
+		trashed = &models.TrashedFile{
+			Name:       filename,
+			CreatedAt:  current.CreatedAt,
+			UpdatedAt:  current.UpdatedAt,
+			Tags:       current.Tags,
+			Attributes: current.Attributes,
+			ETag:       current.ETag,
+			Size:       current.Size,
+			MimeType:   current.MimeType,
+			OwnerID:    current.OwnerID,
+			TrashedAt:  time.Now(),
+			TrashedBy:  RequestMetaFromContext(ctx).ActorID,
+			RuleID:     ruleID,
+		}
+		if err := u.r.SaveTrashedFile(ctx, trashed); err != nil {
+			return err
+		}
+
+		if err := u.files.storage.Rename(filename, trashStorageName(trashed.ID)); err != nil {
+			u.dropEntry(ctx, trashed.ID)
+			return fmt.Errorf("failed to move file %s to trash: %w", filename, err)
+		}
+
+		if err := u.files.r.DeleteFileMeta(ctx, filename); err != nil {
+			if restoreErr := u.files.storage.Rename(trashStorageName(trashed.ID), filename); restoreErr != nil {
+				log.Printf("ERROR: Failed to restore %s after failed trash: %v", filename, restoreErr)
+			} else {
+				u.dropEntry(ctx, trashed.ID)
+			}
+			return fmt.Errorf("failed to delete file metadata for %s: %w", filename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.files.events.Publish(ctx, &models.FileEvent{Type: models.FileEventDeleted, FileName: filename})
+
+	log.Printf("INFO: File %s moved to trash as %d", filename, trashed.ID)
+	return trashed, nil
+}
+
+// Restore возвращает файл из корзины под прежним именем
+func (u *TrashUsecase) Restore(ctx context.Context, id int64) (_ *models.FileMeta, err error) {
+	trashed, err := u.r.GetTrashedFile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	filename := trashed.Name
+	defer func() { recordFileEvent(ctx, u.files.auditor, models.AuditActionFileRestore, filename, err) }()
+	log.Printf("INFO: Restoring file %s from trash", filename)
+
+	var meta *models.FileMeta
+	err = u.files.r.WithFileMutex(ctx, []string{filename}, func(ctx context.Context) error {
+		current, err := u.files.currentMeta(ctx, filename)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			return models.ErrFileExists
+		}
+
+		if err := u.files.storage.Rename(trashStorageName(id), filename); err != nil {
+			return fmt.Errorf("failed to restore file %s from trash: %w", filename, err)
+		}
+
+		meta = &models.FileMeta{
+			Name:       filename,
+			CreatedAt:  trashed.CreatedAt,
+			UpdatedAt:  trashed.UpdatedAt,
+			Tags:       trashed.Tags,
+			Attributes: trashed.Attributes,
+			ETag:       trashed.ETag,
+			Size:       trashed.Size,
+			MimeType:   trashed.MimeType,
+			OwnerID:    trashed.OwnerID,
+		}
+		if err := u.files.r.SaveFileMeta(ctx, meta); err != nil {
+			if rollbackErr := u.files.storage.Rename(filename, trashStorageName(id)); rollbackErr != nil {
+				log.Printf("ERROR: Failed to return %s to trash after failed restore: %v", filename, rollbackErr)
+			}
+			return fmt.Errorf("failed to save file metadata for %s: %w", filename, err)
+		}
+
+		u.dropEntry(ctx, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.files.events.Publish(ctx, &models.FileEvent{Type: models.FileEventCreated, FileName: filename})
+
+	log.Printf("INFO: File %s restored from trash", filename)
+	return meta, nil
+}
+
+// Purge окончательно удаляет файл из корзины. Сроки хранения по префиксу
+// продолжают действовать и для файлов в корзине
+func (u *TrashUsecase) Purge(ctx context.Context, id int64) (err error) {
+	trashed, err := u.r.GetTrashedFile(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer func() { recordFileEvent(ctx, u.files.auditor, models.AuditActionFilePurge, trashed.Name, err) }()
+
+	return u.purge(ctx, trashed)
+}
+
+// List возвращает содержимое корзины, последние удаленные первыми
+func (u *TrashUsecase) List(ctx context.Context, page, pageSize int) (*models.TrashPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultTrashPageSize
+	}
+	if pageSize > maxTrashPageSize {
+		pageSize = maxTrashPageSize
+	}
+
+	result, err := u.r.GetTrashedFiles(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// PurgeExpired очищает файлы, пролежавшие в корзине дольше срока хранения
+func (u *TrashUsecase) PurgeExpired(ctx context.Context) {
+	if u.retention <= 0 {
+		return
+	}
+
+	before := time.Now().Add(-u.retention)
+	var after int64
+	var purged, skipped int
+	for {
+		files, err := u.r.GetExpiredTrash(ctx, before, after, trashPurgeBatchSize)
+		if err != nil {
+			log.Printf("ERROR: Failed to query expired trash: %v", err)
+			return
+		}
+
+		for _, trashed := range files {
+			after = trashed.ID
+			err := u.purge(ctx, trashed)
+			recordFileEvent(ctx, u.files.auditor, models.AuditActionFilePurge, trashed.Name, err)
+			switch {
+			case err == nil:
+				purged++
+			case errors.Is(err, models.ErrFileProtected):
+				skipped++
+			default:
+				log.Printf("ERROR: Failed to purge trashed file %d: %v", trashed.ID, err)
+			}
+		}
+
+		if len(files) < trashPurgeBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if purged > 0 || skipped > 0 {
+		log.Printf("INFO: Purged %d files from trash, %d protected files kept", purged, skipped)
+	}
+}
+
+func (u *TrashUsecase) purge(ctx context.Context, trashed *models.TrashedFile) error {
+	if err := u.files.protect.CheckProtection(ctx, trashed.Name); err != nil {
+		return err
+	}
+
+	if err := u.files.storage.Delete(trashStorageName(trashed.ID)); err != nil {
+		return fmt.Errorf("failed to delete trashed file %d from storage: %w", trashed.ID, err)
+	}
+	if err := u.r.DeleteTrashedFile(ctx, trashed.ID); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: Trashed file %s (%d) purged", trashed.Name, trashed.ID)
+	return nil
+}
+
+// Удаление записи корзины после отката или восстановления; ошибка только логируется
+func (u *TrashUsecase) dropEntry(ctx context.Context, id int64) {
+	if err := u.r.DeleteTrashedFile(ctx, id); err != nil {
+		log.Printf("ERROR: Failed to delete trash entry %d: %v", id, err)
+	}
+}
+
+func trashStorageName(id int64) string {
+	return trashDir + "/" + strconv.FormatInt(id, 10)
+}
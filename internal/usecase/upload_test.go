@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"tages/internal/models"
+	"tages/internal/repository/boltdb"
+)
+
+// memoryStorage хранит содержимое файлов в памяти
+type memoryStorage struct {
+	FileStorage
+	files map[string][]byte
+}
+
+func (s *memoryStorage) Save(filename string, data []byte) error {
+	s.files[filename] = data
+	return nil
+}
+
+func TestUploadOverwriteKeepsMetadata(t *testing.T) {
+	repo, err := boltdb.New(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("boltdb.New: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	u := New(&memoryStorage{files: map[string][]byte{}}, repo, MetadataLimits{
+		MaxTags: 8, MaxAttributes: 8, MaxKeyLength: 64, MaxValueLength: 256,
+	})
+	ctx := context.Background()
+
+	tags := []string{"report"}
+	attributes := map[string]string{"owner": "finance"}
+	if _, err := u.Upload(ctx, "report.txt", []byte("v1"), UploadOptions{Tags: tags, Attributes: attributes}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	// Перезапись без метаданных: ответ совпадает с тем, что осталось в базе
+	meta, err := u.Upload(ctx, "report.txt", []byte("v2"), UploadOptions{})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	stored, err := repo.GetFileMeta(ctx, "report.txt")
+	if err != nil {
+		t.Fatalf("GetFileMeta: %v", err)
+	}
+
+	for name, got := range map[string]*models.FileMeta{"response": meta, "stored": stored} {
+		if !slices.Equal(got.Tags, tags) {
+			t.Errorf("%s tags = %v, want %v", name, got.Tags, tags)
+		}
+		if !maps.Equal(got.Attributes, attributes) {
+			t.Errorf("%s attributes = %v, want %v", name, got.Attributes, attributes)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"tages/internal/models"
+)
+
+var ErrInvalidUsageFilter = errors.New("invalid usage filter")
+
+const (
+	defaultUsageHistoryDays = 30
+	maxUsageHistoryDays     = 3 * 366
+	defaultUsageTopLimit    = 50
+	maxUsageTopLimit        = 1000
+	// Через сколько незавершенный снимок считается брошенным и повторяется
+	usageSnapshotTimeout = time.Hour
+	// Сколько байт содержимого нужно для определения типа
+	mimeSniffLength = 512
+)
+
+type UsageRepository interface {
+	GetUnmeasuredFiles(ctx context.Context, after string, limit int) ([]string, error)
+	SetFileStats(ctx context.Context, filename string, size int64, mimeType string) error
+	ClaimUsageSnapshot(ctx context.Context, day, now, staleBefore time.Time) (bool, error)
+	SaveUsageSnapshot(ctx context.Context, day, completedAt time.Time) error
+	ReleaseUsageSnapshot(ctx context.Context, day time.Time) error
+	DeleteUsageSnapshotsBefore(ctx context.Context, before time.Time) (int64, error)
+	GetUsageHistory(ctx context.Context, filter *models.UsageFilter) ([]*models.UsagePoint, error)
+	GetLargestFiles(ctx context.Context, limit int) ([]*models.LargestFile, error)
+	GetUserUsage(ctx context.Context, limit int) ([]*models.UserUsage, error)
+	GetTypeUsage(ctx context.Context) ([]*models.TypeUsage, error)
+}
+
+type UsageConfig struct {
+	// Период проверки, сделан ли снимок за текущий день
+	CheckInterval time.Duration
+	// Срок хранения снимков, 0 — хранить бессрочно
+	Retention time.Duration
+	// Сколько файлов без размера обрабатывается за один запрос к базе
+	BatchSize int
+}
+
+// UsageUsecase статистика занятого места: ежедневные снимки по пользователям
+// и типам файлов и текущие крупнейшие файлы и пользователи
+type UsageUsecase struct {
+	r       UsageRepository
+	storage FileStorage
+	cfg     UsageConfig
+}
+
+func NewUsageUsecase(r UsageRepository, storage FileStorage, cfg UsageConfig) *UsageUsecase {
+	return &UsageUsecase{
+		r:       r,
+		storage: storage,
+		cfg:     cfg,
+	}
+}
+
+// Run раз в день снимает статистику. Перед снимком определяет размер и тип
+// файлов, загруженных до появления статистики
+func (u *UsageUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		u.measureFiles(ctx)
+		u.snapshotIfDue(ctx)
+
+		if u.cfg.Retention > 0 {
+			deleted, err := u.r.DeleteUsageSnapshotsBefore(ctx, usageDay(time.Now().Add(-u.cfg.Retention)))
+			if err != nil {
+				log.Printf("ERROR: Failed to prune usage snapshots: %v", err)
+			} else if deleted > 0 {
+				log.Printf("INFO: Pruned %d usage snapshot rows", deleted)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// TakeSnapshot снимает статистику за текущий день, заменяя снимок, если он уже есть
+func (u *UsageUsecase) TakeSnapshot(ctx context.Context) error {
+	now := time.Now()
+	if err := u.r.SaveUsageSnapshot(ctx, usageDay(now), now); err != nil {
+		return err
+	}
+	log.Printf("INFO: Usage snapshot for %s taken", usageDay(now).Format(time.DateOnly))
+	return nil
+}
+
+// History возвращает дневные снимки за период; по умолчанию — за последние 30 дней
+func (u *UsageUsecase) History(ctx context.Context, filter *models.UsageFilter) ([]*models.UsagePoint, error) {
+	switch filter.GroupBy {
+	case "":
+		filter.GroupBy = models.UsageGroupTotal
+	case models.UsageGroupTotal, models.UsageGroupUser, models.UsageGroupType:
+	default:
+		return nil, fmt.Errorf("%w: unknown group %q", ErrInvalidUsageFilter, filter.GroupBy)
+	}
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -defaultUsageHistoryDays)
+	}
+	filter.From, filter.To = usageDay(filter.From), usageDay(filter.To)
+	if filter.From.After(filter.To) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidUsageFilter)
+	}
+	if filter.To.Sub(filter.From) > maxUsageHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: period is longer than %d days", ErrInvalidUsageFilter, maxUsageHistoryDays)
+	}
+
+	points, err := u.r.GetUsageHistory(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage history: %w", err)
+	}
+	return points, nil
+}
+
+// LargestFiles возвращает самые большие файлы
+func (u *UsageUsecase) LargestFiles(ctx context.Context, limit int) ([]*models.LargestFile, error) {
+	files, err := u.r.GetLargestFiles(ctx, normalizeUsageLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get largest files: %w", err)
+	}
+	return files, nil
+}
+
+// HeaviestUsers возвращает пользователей, занимающих больше всего места
+func (u *UsageUsecase) HeaviestUsers(ctx context.Context, limit int) ([]*models.UserUsage, error) {
+	users, err := u.r.GetUserUsage(ctx, normalizeUsageLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user usage: %w", err)
+	}
+	return users, nil
+}
+
+// Types возвращает занятое место по MIME-типам
+func (u *UsageUsecase) Types(ctx context.Context) ([]*models.TypeUsage, error) {
+	types, err := u.r.GetTypeUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type usage: %w", err)
+	}
+	return types, nil
+}
+
+// Снимок за текущий день делает только один экземпляр сервиса
+func (u *UsageUsecase) snapshotIfDue(ctx context.Context) {
+	now := time.Now()
+	day := usageDay(now)
+
+	claimed, err := u.r.ClaimUsageSnapshot(ctx, day, now, now.Add(-usageSnapshotTimeout))
+	if err != nil {
+		log.Printf("ERROR: Failed to claim usage snapshot: %v", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err := u.r.SaveUsageSnapshot(ctx, day, time.Now()); err != nil {
+		log.Printf("ERROR: Failed to take usage snapshot: %v", err)
+		if err := u.r.ReleaseUsageSnapshot(ctx, day); err != nil {
+			log.Printf("ERROR: %v", err)
+		}
+		return
+	}
+	log.Printf("INFO: Usage snapshot for %s taken", day.Format(time.DateOnly))
+}
+
+// Определение размера и типа файлов, загруженных до появления статистики.
+// Метаданные обновляются в обход кэша: размер появится в ответах после истечения TTL
+func (u *UsageUsecase) measureFiles(ctx context.Context) {
+	var after string
+	var measured int
+	for {
+		names, err := u.r.GetUnmeasuredFiles(ctx, after, u.cfg.BatchSize)
+		if err != nil {
+			log.Printf("ERROR: Failed to query unmeasured files: %v", err)
+			return
+		}
+
+		for _, name := range names {
+			after = name
+			size, mimeType, err := u.measure(name)
+			if err != nil {
+				log.Printf("ERROR: Failed to measure file %s: %v", name, err)
+				continue
+			}
+			if err := u.r.SetFileStats(ctx, name, size, mimeType); err != nil {
+				log.Printf("ERROR: %v", err)
+				continue
+			}
+			measured++
+		}
+
+		if len(names) < u.cfg.BatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if measured > 0 {
+		log.Printf("INFO: Measured size and type of %d files", measured)
+	}
+}
+
+func (u *UsageUsecase) measure(filename string) (int64, string, error) {
+	reader, err := u.storage.ReadStream(filename)
+	if err != nil {
+		return 0, "", err
+	}
+	defer reader.Close()
+
+	head := make([]byte, mimeSniffLength)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, "", err
+	}
+	rest, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, "", err
+	}
+	return int64(n) + rest, detectMimeType(filename, head[:n]), nil
+}
+
+// MIME-тип по расширению имени, иначе по первым байтам содержимого.
+// Параметры типа (charset) отбрасываются, чтобы статистика не дробилась
+func detectMimeType(filename string, data []byte) string {
+	mimeType := mime.TypeByExtension(filepath.Ext(filename))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return mimeType
+}
+
+// Снимки привязаны к календарным дням UTC
+func usageDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func normalizeUsageLimit(limit int) int {
+	if limit < 1 {
+		return defaultUsageTopLimit
+	}
+	if limit > maxUsageTopLimit {
+		return maxUsageTopLimit
+	}
+	return limit
+}
@@ -2,103 +2,347 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"tages/internal/models"
 )
 
+var ErrInvalidFileName = errors.New("invalid file name")
+
 type FileStorage interface {
 	Save(filename string, data []byte) error
 	Read(filename string) ([]byte, error)
 	ReadStream(filename string) (models.FileReader, error)
 	Delete(filename string) error
+	Rename(from, to string) error
 }
 
 type Repository interface {
 	UpdateFileMeta(ctx context.Context, filname *models.FileMeta) error
 	IsFileExists(ctx context.Context, filename string) (bool, error)
 	SaveFileMeta(ctx context.Context, file *models.FileMeta) error
-	GetFilesMeta(ctx context.Context) ([]*models.FileMeta, error)
+	GetFilesMeta(ctx context.Context, filter *models.FileFilter) ([]*models.FileMeta, error)
+	GetFileMeta(ctx context.Context, filename string) (*models.FileMeta, error)
+	UpdateFileMetadata(ctx context.Context, file *models.FileMeta) error
 	DeleteFileMeta(ctx context.Context, filename string) error
+	RenameFileMeta(ctx context.Context, from, to string, updatedAt time.Time) error
+	// WithFileMutex сериализует изменения файлов между запросами и экземплярами:
+	// выполняет fn, пока захвачены мьютексы всех файлов. Изменения метаданных,
+	// сделанные с контекстом fn, фиксируются вместе с проверкой условий
+	WithFileMutex(ctx context.Context, filenames []string, fn func(ctx context.Context) error) error
 }
 
 type Usecase struct {
 	storage FileStorage
 	r       Repository
+	limits  MetadataLimits
+	indexer *ContentIndexer
+	auditor Auditor
+	events  FileEventPublisher
+	recents RecentTracker
+	locks   FileLocker
+	protect ProtectionChecker
+	access  AccessRecorder
+}
+
+// UploadOptions дополнительные параметры загрузки
+type UploadOptions struct {
+	Preconditions
+	// Теги и атрибуты файла. Если не заданы, у существующего файла сохраняются прежние
+	Tags       []string
+	Attributes map[string]string
 }
 
-func New(storage FileStorage, r Repository) *Usecase {
+func New(storage FileStorage, r Repository, limits MetadataLimits) *Usecase {
 	return &Usecase{
 		storage: storage,
 		r:       r,
+		limits:  limits,
+		auditor: nopAuditor{},
+		events:  nopEventPublisher{},
+		recents: nopRecentTracker{},
+		locks:   nopFileLocker{},
+		protect: nopProtectionChecker{},
+		access:  nopAccessRecorder{},
 	}
 }
-func (u *Usecase) Upload(ctx context.Context, filename string, data []byte) error {
+
+// SetAccessRecorder подключает учет обращений к файлам
+func (u *Usecase) SetAccessRecorder(access AccessRecorder) {
+	u.access = access
+}
+
+// SetProtectionChecker подключает проверку сроков хранения и юридических удержаний
+func (u *Usecase) SetProtectionChecker(protect ProtectionChecker) {
+	u.protect = protect
+}
+
+// SetFileLocker подключает проверку блокировок файлов
+func (u *Usecase) SetFileLocker(locks FileLocker) {
+	u.locks = locks
+}
+
+// SetRecentTracker подключает учет недавних файлов пользователя
+func (u *Usecase) SetRecentTracker(recents RecentTracker) {
+	u.recents = recents
+}
+
+// SetEventPublisher подключает публикацию событий изменения файлов
+func (u *Usecase) SetEventPublisher(events FileEventPublisher) {
+	u.events = events
+}
+
+// SetAuditor подключает журнал аудита операций с файлами
+func (u *Usecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// SetContentIndexer подключает фоновое извлечение текста для новых загрузок
+func (u *Usecase) SetContentIndexer(indexer *ContentIndexer) {
+	u.indexer = indexer
+}
+
+// Upload сохраняет файл и возвращает его метаданные с новой версией (ETag).
+// Проверка условий и запись выполняются под мьютексом файла, поэтому
+// параллельная запись не может вклиниться между ними
+func (u *Usecase) Upload(ctx context.Context, filename string, data []byte, opts UploadOptions) (_ *models.FileMeta, err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionFileUpload, filename, err) }()
 	log.Printf("INFO: Processing upload request for file: %s (%d bytes)", filename, len(data))
 
-	if err := u.storage.Save(filename, data); err != nil {
-		return fmt.Errorf("failed to upload file %s: %w", filename, err)
+	if !isValidFileName(filename) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFileName, filename)
 	}
 
-	exists, err := u.r.IsFileExists(ctx, filename)
+	tags, attributes, err := u.limits.normalize(opts.Tags, opts.Attributes)
 	if err != nil {
-		return fmt.Errorf("failed to check if file exists %s: %w", filename, err)
+		return nil, err
 	}
 
-	now := time.Now()
-	meta := &models.FileMeta{
-		Name:      filename,
-		UpdatedAt: now,
+	var current, meta *models.FileMeta
+	err = u.r.WithFileMutex(ctx, []string{filename}, func(ctx context.Context) error {
+		var err error
+		if current, err = u.currentMeta(ctx, filename); err != nil {
+			return err
+		}
+		if err := opts.check(current); err != nil {
+			return err
+		}
+
+		if err := u.locks.CheckLock(ctx, filename, opts.LockToken); err != nil {
+			return err
+		}
+		if current != nil {
+			if err := u.protect.CheckProtection(ctx, filename); err != nil {
+				return err
+			}
+		}
+
+		if err := u.storage.Save(filename, data); err != nil {
+			return fmt.Errorf("failed to upload file %s: %w", filename, err)
+		}
+
+		now := time.Now()
+		meta = &models.FileMeta{
+			Name:       filename,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Tags:       tags,
+			Attributes: attributes,
+			ETag:       newETag(),
+			Size:       int64(len(data)),
+			MimeType:   detectMimeType(filename, data),
+			OwnerID:    RequestMetaFromContext(ctx).ActorID,
+		}
+		if err := u.r.SaveFileMeta(ctx, meta); err != nil {
+			return fmt.Errorf("failed to save file metadata for %s: %w", filename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// При перезаписи без метаданных в базе остаются прежние теги, атрибуты, дата создания и владелец
+	if current != nil {
+		meta.CreatedAt = current.CreatedAt
+		if current.OwnerID != 0 {
+			meta.OwnerID = current.OwnerID
+		}
+		if len(tags) == 0 {
+			meta.Tags = current.Tags
+		}
+		if len(attributes) == 0 {
+			meta.Attributes = current.Attributes
+		}
 	}
-	if !exists {
-		meta.CreatedAt = now
+
+	if u.indexer != nil {
+		u.indexer.Notify()
 	}
 
-	if err := u.r.SaveFileMeta(ctx, meta); err != nil {
-		return fmt.Errorf("failed to save file metadata for %s: %w", filename, err)
+	eventType := models.FileEventUpdated
+	if current == nil {
+		eventType = models.FileEventCreated
 	}
+	u.events.Publish(ctx, &models.FileEvent{Type: eventType, FileName: filename})
+	u.recents.TrackAccess(ctx, filename, models.RecentActionUpload)
+	u.access.RecordAccess(ctx, filename)
 
 	log.Printf("INFO: Successfully uploaded file: %s", filename)
-	return nil
+	return meta, nil
+}
+
+// GetFile возвращает метаданные файла
+func (u *Usecase) GetFile(ctx context.Context, filename string) (*models.FileMeta, error) {
+	return u.r.GetFileMeta(ctx, filename)
 }
 
-func (u *Usecase) Download(filename string) (models.FileReader, error) {
+func (u *Usecase) Download(ctx context.Context, filename string) (_ models.FileReader, err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionFileDownload, filename, err) }()
 	log.Printf("INFO: Processing download request for file: %s", filename)
 	reader, err := u.storage.ReadStream(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file %s: %w", filename, err)
 	}
 
+	u.recents.TrackAccess(ctx, filename, models.RecentActionDownload)
+	u.access.RecordAccess(ctx, filename)
+
 	log.Printf("INFO: File stream opened for download: %s", filename)
 	return reader, nil
 }
 
-func (u *Usecase) ListFiles(ctx context.Context) ([]*models.FileMeta, error) {
+func (u *Usecase) ListFiles(ctx context.Context, filter *models.FileFilter) ([]*models.FileMeta, error) {
 	log.Printf("INFO: Retrieving file list")
 
-	files, err := u.r.GetFilesMeta(ctx)
+	files, err := u.r.GetFilesMeta(ctx, filter)
 	if err != nil {
 		log.Printf("ERROR: Failed to retrieve file list: %v", err)
 		return nil, fmt.Errorf("failed to retrieve file list: %w", err)
 	}
 
+	locks, err := u.locks.ActiveLocks(ctx)
+	if err != nil {
+		log.Printf("ERROR: Failed to retrieve file locks: %v", err)
+		return nil, fmt.Errorf("failed to retrieve file locks: %w", err)
+	}
+	for _, file := range files {
+		file.Lock = locks[file.Name]
+	}
+
 	log.Printf("INFO: Retrieved %d files", len(files))
 	return files, nil
 }
 
-func (u *Usecase) DeleteFile(ctx context.Context, filename string) error {
+func (u *Usecase) DeleteFile(ctx context.Context, filename string, cond Preconditions) (err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionFileDelete, filename, err) }()
 	log.Printf("INFO: Processing delete request for file: %s", filename)
 
-	if err := u.storage.Delete(filename); err != nil {
-		return fmt.Errorf("failed to delete file from storage %s: %w", filename, err)
-	}
+	err = u.r.WithFileMutex(ctx, []string{filename}, func(ctx context.Context) error {
+		current, err := u.currentMeta(ctx, filename)
+		if err != nil {
+			return err
+		}
+		if err := cond.check(current); err != nil {
+			return err
+		}
+
+		if err := u.locks.CheckLock(ctx, filename, cond.LockToken); err != nil {
+			return err
+		}
+		if err := u.protect.CheckProtection(ctx, filename); err != nil {
+			return err
+		}
 
-	if err := u.r.DeleteFileMeta(ctx, filename); err != nil {
-		return fmt.Errorf("failed to delete file metadata for %s: %w", filename, err)
+		if err := u.storage.Delete(filename); err != nil {
+			return fmt.Errorf("failed to delete file from storage %s: %w", filename, err)
+		}
+
+		if err := u.r.DeleteFileMeta(ctx, filename); err != nil {
+			return fmt.Errorf("failed to delete file metadata for %s: %w", filename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	u.events.Publish(ctx, &models.FileEvent{Type: models.FileEventDeleted, FileName: filename})
+
 	log.Printf("INFO: Successfully deleted file: %s", filename)
 	return nil
 }
+
+// Move переименовывает файл. Избранное, комментарии и блокировка переходят к новому имени.
+// If-Match проверяется для исходного файла, If-None-Match — для имени назначения
+func (u *Usecase) Move(ctx context.Context, from, to string, cond Preconditions) (err error) {
+	defer func() { recordFileEvent(ctx, u.auditor, models.AuditActionFileMove, from, err) }()
+	log.Printf("INFO: Processing move request for file: %s -> %s", from, to)
+
+	if !isValidFileName(to) {
+		return fmt.Errorf("%w: %q", ErrInvalidFileName, to)
+	}
+	if from == to {
+		return nil
+	}
+
+	err = u.r.WithFileMutex(ctx, []string{from, to}, func(ctx context.Context) error {
+		source, err := u.currentMeta(ctx, from)
+		if err != nil {
+			return err
+		}
+		target, err := u.currentMeta(ctx, to)
+		if err != nil {
+			return err
+		}
+		if err := (Preconditions{IfMatch: cond.IfMatch}).check(source); err != nil {
+			return err
+		}
+		if err := (Preconditions{IfNoneMatch: cond.IfNoneMatch}).check(target); err != nil {
+			return err
+		}
+
+		if err := u.locks.CheckLock(ctx, from, cond.LockToken); err != nil {
+			return err
+		}
+		if err := u.protect.CheckProtection(ctx, from); err != nil {
+			return err
+		}
+
+		// Сначала метаданные: переименование в базе атомарно проверяет, что имя свободно
+		if err := u.r.RenameFileMeta(ctx, from, to, time.Now()); err != nil {
+			if errors.Is(err, models.ErrFileNotFound) || errors.Is(err, models.ErrFileExists) {
+				return err
+			}
+			return fmt.Errorf("failed to rename file metadata %s: %w", from, err)
+		}
+
+		if err := u.storage.Rename(from, to); err != nil {
+			if rollbackErr := u.r.RenameFileMeta(ctx, to, from, time.Now()); rollbackErr != nil {
+				log.Printf("ERROR: Failed to restore metadata of %s after failed move: %v", from, rollbackErr)
+			}
+			return fmt.Errorf("failed to move file %s: %w", from, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	u.events.Publish(ctx, &models.FileEvent{Type: models.FileEventDeleted, FileName: from})
+	u.events.Publish(ctx, &models.FileEvent{Type: models.FileEventCreated, FileName: to})
+
+	log.Printf("INFO: Successfully moved file %s to %s", from, to)
+	return nil
+}
+
+// Имя файла без каталогов; файлы хранятся в одной директории.
+// Имя служебного каталога корзины занято
+func isValidFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && name != trashDir &&
+		filepath.Base(name) == name && !strings.ContainsRune(name, '\\')
+}
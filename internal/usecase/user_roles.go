@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tages/internal/models"
+)
+
+var ErrSelfRoleChange = errors.New("users cannot change their own role")
+
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 200
+)
+
+// HasPermission проверяет, дает ли роль пользователя разрешение
+func (u *UserUsecase) HasPermission(ctx context.Context, userID uint, permission string) (bool, error) {
+	role, err := u.UserRole(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return u.RoleHasPermission(ctx, role, permission)
+}
+
+// RoleHasPermission проверяет, входит ли разрешение в роль. Неизвестная роль не дает разрешений
+func (u *UserUsecase) RoleHasPermission(ctx context.Context, roleName, permission string) (bool, error) {
+	role, err := u.roles.Role(ctx, roleName)
+	if err != nil {
+		if errors.Is(err, models.ErrRoleNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
+	return role.HasPermission(permission), nil
+}
+
+// UserRole текущая роль пользователя. Нужна для токенов без роли в claims
+func (u *UserUsecase) UserRole(ctx context.Context, userID uint) (string, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	return user.Role, nil
+}
+
+// Permissions разрешения роли. Неизвестная роль не дает разрешений
+func (u *UserUsecase) Permissions(ctx context.Context, roleName string) ([]string, error) {
+	role, err := u.roles.Role(ctx, roleName)
+	if err != nil {
+		if errors.Is(err, models.ErrRoleNotFound) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
+	return role.Permissions, nil
+}
+
+// GetUser возвращает пользователя по ID
+func (u *UserUsecase) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	return u.userRepo.GetUserByID(ctx, userID)
+}
+
+// ListUsers возвращает страницу списка пользователей
+func (u *UserUsecase) ListUsers(ctx context.Context, page, pageSize int) (*models.UserPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultUsersPageSize
+	}
+	if pageSize > maxUsersPageSize {
+		pageSize = maxUsersPageSize
+	}
+
+	result, err := u.userRepo.GetUsers(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	return result, nil
+}
+
+// SetUserRole назначает пользователю роль. Роль передается в токенах, поэтому
+// выданные пользователю access токены отзываются, а новые он получит при обновлении.
+// Собственную роль менять нельзя, чтобы администратор не лишил себя прав по ошибке
+func (u *UserUsecase) SetUserRole(ctx context.Context, actorID, userID uint, role string) (err error) {
+	details := map[string]string{"role": role}
+	defer func() {
+		recordEvent(ctx, u.auditor, models.AuditActionUserRoleChange, strconv.FormatUint(uint64(userID), 10), details, err)
+	}()
+
+	if actorID == userID {
+		return ErrSelfRoleChange
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	details["previous_role"] = user.Role
+	if user.Role == role {
+		return nil
+	}
+
+	if err := u.assignRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: User %d changed role of user %d from %s to %s", actorID, userID, user.Role, role)
+	return nil
+}
+
+// EnsureAdmins назначает роль администратора существующим пользователям
+// с email из конфигурации
+func (u *UserUsecase) EnsureAdmins(ctx context.Context) error {
+	for email := range u.admins {
+		user, err := u.userRepo.GetUserByEmail(ctx, email)
+		if err != nil {
+			// Пользователь еще не зарегистрирован: роль будет назначена при регистрации
+			continue
+		}
+		if user.Role == models.RoleAdmin {
+			continue
+		}
+		if err := u.assignRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			return fmt.Errorf("failed to grant admin role to %s: %w", email, err)
+		}
+		log.Printf("INFO: Granted admin role to %s from configuration", email)
+	}
+	return nil
+}
+
+// Назначение существующей роли и отзыв access токенов со старой ролью
+func (u *UserUsecase) assignRole(ctx context.Context, userID uint, role string) error {
+	if _, err := u.roles.Role(ctx, role); err != nil {
+		return err
+	}
+	if err := u.userRepo.SetUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+	if err := u.userRepo.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	return nil
+}
+
+// Роль нового пользователя по системным настройкам
+func (u *UserUsecase) registrationRole(ctx context.Context, email string) (string, error) {
+	if _, ok := u.admins[email]; ok {
+		return models.RoleAdmin, nil
+	}
+
+	settings, err := u.settings.Settings(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get settings: %w", err)
+	}
+	if !settings.RegistrationEnabled {
+		return "", ErrRegistrationClosed
+	}
+
+	// Роль по умолчанию могли удалить после того, как она была выбрана в настройках
+	if _, err := u.roles.Role(ctx, settings.DefaultRole); err != nil {
+		log.Printf("WARN: Default role %s is unavailable (%v), using %s", settings.DefaultRole, err, models.RoleMember)
+		return models.RoleMember, nil
+	}
+	return settings.DefaultRole, nil
+}
@@ -2,9 +2,12 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"tages/internal/auth"
@@ -17,20 +20,66 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserByID(ctx context.Context, id uint) (*models.User, error)
-	StoreRefreshToken(ctx context.Context, userID uint, token string, expiresAt time.Time) error
-	DeleteRefreshToken(ctx context.Context, token string) error
-	GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error)
+	GetUsers(ctx context.Context, limit, offset int) (*models.UserPage, error)
+	SetUserRole(ctx context.Context, userID uint, role string) error
+	SetPassword(ctx context.Context, userID uint, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID uint) error
+	MarkVerificationSent(ctx context.Context, userID uint, sentAt, resendAfter time.Time) (bool, error)
+	StoreRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, oldTokenHash string, token *models.RefreshToken) error
+	DeleteRefreshToken(ctx context.Context, tokenHash string) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	GetRotatedRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	GetUserSessions(ctx context.Context, userID uint, now time.Time) ([]*models.RefreshToken, error)
+	DeleteUserSession(ctx context.Context, userID, sessionID uint) error
+	DeleteUserSessions(ctx context.Context, userID, exceptID uint) (int64, error)
+	DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error)
+	RevokeUserTokens(ctx context.Context, userID uint, revokedAt time.Time) error
+	SetTOTPSecret(ctx context.Context, userID uint, secret string) error
+	EnableMFA(ctx context.Context, userID uint, step int64, recoveryCodes []string) error
+	DisableMFA(ctx context.Context, userID uint) error
+	SetRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	CountPasswordResetTokens(ctx context.Context, userID uint, since time.Time) (int, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	DeletePasswordResetTokens(ctx context.Context, userID uint) error
+	DeleteExpiredPasswordResetTokens(ctx context.Context, before time.Time) (int64, error)
+	RecordLoginFailure(ctx context.Context, subject string, now, resetBefore time.Time) (int, error)
+	LockLoginAttempts(ctx context.Context, subject string, until time.Time) error
+	LoginLockedUntil(ctx context.Context, subjects []string, now time.Time) (time.Time, error)
+	DeleteLoginAttempts(ctx context.Context, subject string) error
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, before time.Time) (int64, error)
 }
 
 type AuthManager interface {
 	GenerateTokenPair(user *models.User) (*auth.TokenPair, error)
-	ParseAccessToken(tokenString string) (*auth.TokenClaims, error)
+	ParseAccessToken(ctx context.Context, tokenString string) (*auth.TokenClaims, error)
 	ParseRefreshToken(tokenString string) (*auth.TokenClaims, error)
+	GenerateMFAToken(user *models.User) (string, time.Time, error)
+	ParseMFAToken(tokenString string) (*auth.TokenClaims, error)
+	GenerateVerificationToken(user *models.User, lifetime time.Duration) (string, error)
+	ParseVerificationToken(tokenString string) (*auth.TokenClaims, error)
 }
 
 type UserUsecase struct {
 	userRepo    UserRepository
 	authManager AuthManager
+	auditor     Auditor
+	webhooks    WebhookNotifier
+	roles       RoleProvider
+	settings    SettingsProvider
+	admins      map[string]struct{}
+	mfa         MFAConfig
+	mfaFailures *mfaFailures
+	mailer      Mailer
+	password    PasswordConfig
+	verify      EmailVerificationConfig
+	throttle    LoginThrottleConfig
 }
 
 // Ошибки авторизации
@@ -38,22 +87,115 @@ var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrUserAlreadyExists  = errors.New("user with this email already exists")
 	ErrInvalidToken       = errors.New("invalid refresh token")
+	ErrRegistrationClosed = errors.New("registration is disabled")
 )
 
 func NewUserUsecase(userRepo UserRepository, authManager AuthManager) *UserUsecase {
 	return &UserUsecase{
 		userRepo:    userRepo,
 		authManager: authManager,
+		auditor:     nopAuditor{},
+		webhooks:    nopWebhookNotifier{},
+		roles:       builtinRoles{},
+		settings:    defaultSettings{},
+		mfa:         MFAConfig{Issuer: "Tages", RecoveryCodes: 10},
+		mfaFailures: newMFAFailures(),
+		mailer:      nopMailer{},
+		password:    PasswordConfig{ResetTokenLifetime: time.Hour},
+		verify: EmailVerificationConfig{
+			TokenLifetime:    48 * time.Hour,
+			ResendInterval:   time.Minute,
+			UnverifiedAccess: UnverifiedAccessFull,
+		},
+		throttle: LoginThrottleConfig{
+			AccountMaxFailures: 5,
+			IPMaxFailures:      20,
+			BaseLockout:        time.Minute,
+			MaxLockout:         time.Hour,
+			ResetAfter:         30 * time.Minute,
+		},
 	}
 }
 
+// SetAuditor подключает журнал аудита входов и операций с токенами
+func (u *UserUsecase) SetAuditor(auditor Auditor) {
+	u.auditor = auditor
+}
+
+// SetWebhookNotifier подключает отправку событий пользователей на вебхуки
+func (u *UserUsecase) SetWebhookNotifier(webhooks WebhookNotifier) {
+	u.webhooks = webhooks
+}
+
+// SetRoles подключает роли, хранящиеся в базе
+func (u *UserUsecase) SetRoles(roles RoleProvider) {
+	u.roles = roles
+}
+
+// SetSettings подключает системные настройки, хранящиеся в базе
+func (u *UserUsecase) SetSettings(settings SettingsProvider) {
+	u.settings = settings
+}
+
+// SetAdmins задает email пользователей, которые всегда получают роль администратора.
+// Так назначается первый администратор, остальным роли выдаются через API
+func (u *UserUsecase) SetAdmins(emails []string) {
+	u.admins = make(map[string]struct{}, len(emails))
+	for _, email := range emails {
+		u.admins[email] = struct{}{}
+	}
+}
+
+// Проверка прав администратора
+func (u *UserUsecase) IsAdmin(ctx context.Context, userID uint) (bool, error) {
+	return u.HasPermission(ctx, userID, models.PermissionAdmin)
+}
+
+// Запись события авторизации в журнал аудита
+func (u *UserUsecase) recordAuthEvent(ctx context.Context, action string, userID uint, email string, err error) {
+	event := &models.AuditEvent{
+		Action:     action,
+		Outcome:    models.AuditOutcomeSuccess,
+		ActorID:    userID,
+		ActorEmail: email,
+		Target:     email,
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Details = map[string]string{"error": err.Error()}
+	}
+	u.auditor.Record(ctx, event)
+}
+
 // Регистрация нового пользователя
-func (u *UserUsecase) Register(ctx context.Context, email, password string) (*models.User, *auth.TokenPair, error) {
+func (u *UserUsecase) Register(ctx context.Context, email, password, deviceName string) (user *models.User, _ *auth.TokenPair, err error) {
 	log.Printf("INFO: Registering new user with email: %s", email)
+	defer func() {
+		var userID uint
+		if user != nil {
+			userID = user.ID
+		}
+		u.recordAuthEvent(ctx, models.AuditActionRegister, userID, email, err)
+	}()
+
+	// Перебор email через регистрацию ограничивается так же, как подбор паролей:
+	// попытки зарегистрировать существующий email считаются неудачными для IP
+	keys := attemptKeys(ctx, "")
+	if err := u.checkLockout(ctx, keys); err != nil {
+		return nil, nil, err
+	}
+
+	// Роль нового пользователя; администраторы из конфигурации регистрируются,
+	// даже если регистрация закрыта
+	role, err := u.registrationRole(ctx, email)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Проверяем, существует ли пользователь с таким email
-	_, err := u.userRepo.GetUserByEmail(ctx, email)
+	_, err = u.userRepo.GetUserByEmail(ctx, email)
 	if err == nil {
+		u.recordFailedAttempt(ctx, keys)
 		return nil, nil, ErrUserAlreadyExists
 	}
 
@@ -63,86 +205,129 @@ func (u *UserUsecase) Register(ctx context.Context, email, password string) (*mo
 		return nil, nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Создаем нового пользователя
-	user := &models.User{
-		Email:    email,
-		Password: string(hashedPassword),
+	// Создаем нового пользователя. Email администраторов из конфигурации
+	// считается подтвержденным, остальным отправляется письмо со ссылкой
+	_, isAdmin := u.admins[email]
+	user = &models.User{
+		Email:         email,
+		Password:      string(hashedPassword),
+		Role:          role,
+		EmailVerified: isAdmin,
 	}
 
 	if err := u.userRepo.CreateUser(ctx, user); err != nil {
 		return nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Генерируем токены
-	tokens, err := u.authManager.GenerateTokenPair(user)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+	if !user.EmailVerified {
+		// Пользователь уже создан: письмо можно запросить повторно
+		if _, err := u.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("ERROR: Failed to send verification email to user %d: %v", user.ID, err)
+		}
 	}
 
-	// Сохраняем refresh token в базу
-	claims, err := u.authManager.ParseRefreshToken(tokens.RefreshToken)
+	// Открываем сессию и выдаем токены
+	tokens, err := u.startSession(ctx, user, deviceName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse refresh token: %w", err)
+		return nil, nil, err
 	}
 
-	expiresAt := time.Unix(claims.ExpiresAt.Unix(), 0)
-	if err := u.userRepo.StoreRefreshToken(ctx, user.ID, tokens.RefreshToken, expiresAt); err != nil {
-		return nil, nil, fmt.Errorf("failed to store refresh token: %w", err)
-	}
+	u.webhooks.Enqueue(ctx, models.WebhookEventUserRegistered, map[string]any{
+		"user_id": user.ID,
+		"email":   user.Email,
+	})
 
 	log.Printf("INFO: Successfully registered user with ID: %d", user.ID)
 	return user, tokens, nil
 }
 
+// LoginResult результат проверки пароля. Если у пользователя включена
+// двухфакторная аутентификация, вместо токенов выдается MFAToken для второго шага
+type LoginResult struct {
+	User         *models.User
+	Tokens       *auth.TokenPair
+	MFAToken     string
+	MFAExpiresAt time.Time
+}
+
 // Авторизация пользователя
-func (u *UserUsecase) Login(ctx context.Context, email, password string) (*models.User, *auth.TokenPair, error) {
+func (u *UserUsecase) Login(ctx context.Context, email, password, deviceName string) (*LoginResult, error) {
 	log.Printf("INFO: Login attempt for user: %s", email)
 
+	// Учетная запись или IP заблокированы после неудачных попыток. Счетчик
+	// ведется и для незарегистрированных email, чтобы блокировка не выдавала их
+	keys := attemptKeys(ctx, email)
+	if err := u.checkLockout(ctx, keys); err != nil {
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, 0, email, err)
+		return nil, err
+	}
+
 	// Получаем пользователя по email
 	user, err := u.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, nil, ErrInvalidCredentials
+		u.recordFailedAttempt(ctx, keys)
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, 0, email, ErrInvalidCredentials)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Проверяем пароль
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, nil, ErrInvalidCredentials
+		u.recordFailedAttempt(ctx, keys)
+		u.recordAuthEvent(ctx, models.AuditActionLoginFailed, user.ID, email, ErrInvalidCredentials)
+		return nil, ErrInvalidCredentials
 	}
 
-	// Генерируем токены
-	tokens, err := u.authManager.GenerateTokenPair(user)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+	// Пароль верный, но для входа нужен еще код подтверждения. Счетчик
+	// обнулится только после верного кода
+	if user.MFAEnabled {
+		mfaToken, expiresAt, err := u.authManager.GenerateMFAToken(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+		log.Printf("INFO: User %s passed password check, waiting for mfa code", email)
+		return &LoginResult{User: user, MFAToken: mfaToken, MFAExpiresAt: expiresAt}, nil
 	}
 
-	// Сохраняем refresh token в базу
-	claims, err := u.authManager.ParseRefreshToken(tokens.RefreshToken)
+	// Открываем сессию и выдаем токены
+	tokens, err := u.startSession(ctx, user, deviceName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse refresh token: %w", err)
-	}
-
-	expiresAt := time.Unix(claims.ExpiresAt.Unix(), 0)
-	if err := u.userRepo.StoreRefreshToken(ctx, user.ID, tokens.RefreshToken, expiresAt); err != nil {
-		return nil, nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
 	}
 
+	u.resetFailedAttempts(ctx, email)
+	u.recordAuthEvent(ctx, models.AuditActionLogin, user.ID, email, nil)
 	log.Printf("INFO: User %s logged in successfully", email)
-	return user, tokens, nil
+	return &LoginResult{User: user, Tokens: tokens}, nil
 }
 
 // Обновление токена
-func (u *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+func (u *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (_ *auth.TokenPair, err error) {
 	log.Printf("INFO: Refreshing token")
 
+	// Неверные refresh токены считаются неудачными попытками для IP
+	keys := attemptKeys(ctx, "")
+	if err := u.checkLockout(ctx, keys); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errors.Is(err, ErrInvalidToken) {
+			u.recordFailedAttempt(ctx, keys)
+		}
+	}()
+
 	// Проверяем refresh token
 	claims, err := u.authManager.ParseRefreshToken(refreshToken)
 	if err != nil {
+		u.recordAuthEvent(ctx, models.AuditActionTokenRefresh, 0, "", ErrInvalidToken)
 		return nil, ErrInvalidToken
 	}
 
-	// Получаем сохраненный токен из БД
-	storedToken, err := u.userRepo.GetRefreshToken(ctx, refreshToken)
-	if err != nil {
+	// Получаем сохраненный токен из БД по хешу
+	tokenHash := hashRefreshToken(refreshToken)
+	storedToken, err := u.userRepo.GetRefreshToken(ctx, tokenHash)
+	if err != nil || storedToken.UserID != claims.UserID {
+		u.revokeReusedFamily(ctx, tokenHash, claims.UserID)
+		u.recordAuthEvent(ctx, models.AuditActionTokenRefresh, claims.UserID, "", ErrInvalidToken)
 		return nil, ErrInvalidToken
 	}
 
@@ -158,41 +343,63 @@ func (u *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (*a
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
-	// Удаляем старый refresh token
-	if err := u.userRepo.DeleteRefreshToken(ctx, storedToken.Token); err != nil {
-		log.Printf("WARN: Failed to delete old refresh token: %v", err)
-	}
-
-	// Сохраняем новый refresh token
-	claims, err = u.authManager.ParseRefreshToken(tokens.RefreshToken)
+	// Заменяем refresh token в той же сессии
+	session, err := u.newSessionToken(ctx, tokens.RefreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse refresh token: %w", err)
+		return nil, err
 	}
-
-	expiresAt := time.Unix(claims.ExpiresAt.Unix(), 0)
-	if err := u.userRepo.StoreRefreshToken(ctx, user.ID, tokens.RefreshToken, expiresAt); err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	if err := u.userRepo.RotateRefreshToken(ctx, tokenHash, session); err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			// Токен уже заменен другим запросом: это тоже повторное использование
+			u.revokeReusedFamily(ctx, tokenHash, user.ID)
+			u.recordAuthEvent(ctx, models.AuditActionTokenRefresh, user.ID, user.Email, ErrInvalidToken)
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
 
+	u.recordAuthEvent(ctx, models.AuditActionTokenRefresh, user.ID, user.Email, nil)
 	log.Printf("INFO: Successfully refreshed token for user ID: %d", user.ID)
 	return tokens, nil
 }
 
-// Выход из системы
-func (u *UserUsecase) Logout(ctx context.Context, refreshToken string) error {
+// Выход из системы: завершение сессии refresh token и отзыв access token.
+// Любой из токенов может быть пустым
+func (u *UserUsecase) Logout(ctx context.Context, refreshToken, accessToken string) error {
 	log.Printf("INFO: Processing logout")
 
-	if err := u.userRepo.DeleteRefreshToken(ctx, refreshToken); err != nil {
-		return fmt.Errorf("failed to delete refresh token: %w", err)
+	var userID uint
+	if claims, err := u.authManager.ParseRefreshToken(refreshToken); err == nil {
+		userID = claims.UserID
+	}
+
+	if accessToken != "" {
+		if claims, err := u.authManager.ParseAccessToken(ctx, accessToken); err == nil {
+			if userID == 0 {
+				userID = claims.UserID
+			}
+			if err := u.revokeAccessToken(ctx, claims); err != nil {
+				u.recordAuthEvent(ctx, models.AuditActionLogout, userID, "", err)
+				return err
+			}
+		}
 	}
 
+	if refreshToken != "" {
+		if err := u.userRepo.DeleteRefreshToken(ctx, hashRefreshToken(refreshToken)); err != nil {
+			u.recordAuthEvent(ctx, models.AuditActionLogout, userID, "", err)
+			return fmt.Errorf("failed to delete refresh token: %w", err)
+		}
+	}
+
+	u.recordAuthEvent(ctx, models.AuditActionLogout, userID, "", nil)
 	log.Printf("INFO: User successfully logged out")
 	return nil
 }
 
 // Получение пользователя по access token
 func (u *UserUsecase) GetUserByToken(ctx context.Context, accessToken string) (*models.User, error) {
-	claims, err := u.authManager.ParseAccessToken(accessToken)
+	claims, err := u.authManager.ParseAccessToken(ctx, accessToken)
 	if err != nil {
 		return nil, err
 	}
@@ -204,3 +411,96 @@ func (u *UserUsecase) GetUserByToken(ctx context.Context, accessToken string) (*
 
 	return user, nil
 }
+
+// Максимальная длина User-Agent, сохраняемого в сессии
+const maxSessionUserAgentLength = 512
+
+// Создание сессии пользователя с новой парой токенов
+func (u *UserUsecase) startSession(ctx context.Context, user *models.User, deviceName string) (*auth.TokenPair, error) {
+	tokens, err := u.authManager.GenerateTokenPair(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	session, err := u.newSessionToken(ctx, tokens.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	session.UserID = user.ID
+	session.DeviceName = deviceName
+	session.CreatedAt = session.LastUsedAt
+
+	if err := u.userRepo.StoreRefreshToken(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return tokens, nil
+}
+
+// Данные сессии для сохранения refresh token: срок действия и сведения о клиенте
+func (u *UserUsecase) newSessionToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	claims, err := u.authManager.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse refresh token: %w", err)
+	}
+
+	meta := RequestMetaFromContext(ctx)
+	userAgent := meta.UserAgent
+	if len(userAgent) > maxSessionUserAgentLength {
+		userAgent = userAgent[:maxSessionUserAgentLength]
+	}
+
+	return &models.RefreshToken{
+		TokenHash:  hashRefreshToken(refreshToken),
+		ExpiresAt:  time.Unix(claims.ExpiresAt.Unix(), 0),
+		IP:         meta.IP,
+		UserAgent:  userAgent,
+		LastUsedAt: time.Now(),
+	}, nil
+}
+
+// Хеш refresh token для хранения в базе. Токен — подписанный JWT со случайным
+// ID, поэтому соль не нужна
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Если токен с этим хешем уже был заменен, его предъявил кто-то кроме
+// владельца сессии: отзываем все семейство
+func (u *UserUsecase) revokeReusedFamily(ctx context.Context, tokenHash string, userID uint) {
+	session, err := u.userRepo.GetRotatedRefreshToken(ctx, tokenHash)
+	if err != nil {
+		if !errors.Is(err, models.ErrSessionNotFound) {
+			log.Printf("ERROR: Failed to check refresh token reuse: %v", err)
+		}
+		return
+	}
+	if session.UserID != userID {
+		return
+	}
+
+	log.Printf("WARN: Reuse of rotated refresh token detected for user %d, revoking session %d", userID, session.ID)
+	err = u.userRepo.DeleteUserSession(ctx, userID, session.ID)
+	if errors.Is(err, models.ErrSessionNotFound) {
+		err = nil
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to revoke session %d after token reuse: %v", session.ID, err)
+	}
+
+	event := &models.AuditEvent{
+		Action:  models.AuditActionTokenReuse,
+		Outcome: models.AuditOutcomeSuccess,
+		ActorID: userID,
+		Target:  strconv.FormatUint(uint64(session.ID), 10),
+		Details: map[string]string{
+			"session_ip":         session.IP,
+			"session_user_agent": session.UserAgent,
+		},
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Details["error"] = err.Error()
+	}
+	u.auditor.Record(ctx, event)
+}
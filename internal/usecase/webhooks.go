@@ -0,0 +1,453 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"tages/internal/models"
+)
+
+var (
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrWebhookForbidden = errors.New("webhook belongs to another user")
+)
+
+// Заголовки запроса доставки вебхука
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// События, доступные только системным вебхукам
+var systemWebhookEvents = map[string]bool{
+	models.WebhookEventUserRegistered: true,
+}
+
+var knownWebhookEvents = map[string]bool{
+	models.WebhookEventFileUploaded:   true,
+	models.WebhookEventFileUpdated:    true,
+	models.WebhookEventFileDeleted:    true,
+	models.WebhookEventUserRegistered: true,
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhooks(ctx context.Context, userID *uint) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, id uint) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+	EnqueueWebhookDeliveries(ctx context.Context, eventType string, payload []byte, systemOnly bool, now time.Time) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, now, leaseUntil time.Time) ([]*models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, webhookID uint, limit, offset int) ([]*models.WebhookDelivery, error)
+}
+
+// WebhookNotifier ставит событие в очередь доставки вебхукам
+type WebhookNotifier interface {
+	Enqueue(ctx context.Context, eventType string, data any)
+}
+
+// WebhookConfig параметры доставки вебхуков
+type WebhookConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	PollInterval   time.Duration
+	BatchSize      int
+	// Разрешить доставку на адреса внутренней сети
+	AllowPrivateNetworks bool
+}
+
+// webhookPayload тело запроса доставки
+type webhookPayload struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type WebhookUsecase struct {
+	r      WebhookRepository
+	cfg    WebhookConfig
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewWebhookUsecase(r WebhookRepository, cfg WebhookConfig) *WebhookUsecase {
+	return &WebhookUsecase{
+		r:      r,
+		cfg:    cfg,
+		client: newWebhookClient(cfg),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Создание вебхука. Секрет для проверки подписи генерируется и возвращается вызывающему
+func (u *WebhookUsecase) Create(ctx context.Context, userID uint, system bool, rawURL string, events []string) (*models.Webhook, error) {
+	if err := u.validateURL(ctx, rawURL); err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if !knownWebhookEvents[event] {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		if systemWebhookEvents[event] && !system {
+			return nil, fmt.Errorf("%w: event %q is available only to admin webhooks", ErrInvalidWebhook, event)
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook := &models.Webhook{
+		UserID:    userID,
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		System:    system,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}
+	if err := u.r.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	log.Printf("INFO: Created webhook %d for user %d (%s)", webhook.ID, userID, rawURL)
+	return webhook, nil
+}
+
+// Список вебхуков пользователя; для администратора (all) — всех пользователей
+func (u *WebhookUsecase) List(ctx context.Context, userID uint, all bool) ([]*models.Webhook, error) {
+	var filter *uint
+	if !all {
+		filter = &userID
+	}
+
+	webhooks, err := u.r.GetWebhooks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Удаление вебхука. Чужой вебхук может удалить только администратор
+func (u *WebhookUsecase) Delete(ctx context.Context, userID uint, admin bool, id uint) error {
+	if _, err := u.get(ctx, userID, admin, id); err != nil {
+		return err
+	}
+
+	if err := u.r.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: Deleted webhook %d", id)
+	return nil
+}
+
+// История доставок вебхука
+func (u *WebhookUsecase) Deliveries(ctx context.Context, userID uint, admin bool, id uint, limit, offset int) ([]*models.WebhookDelivery, error) {
+	if _, err := u.get(ctx, userID, admin, id); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := u.r.GetWebhookDeliveries(ctx, id, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (u *WebhookUsecase) get(ctx context.Context, userID uint, admin bool, id uint) (*models.Webhook, error) {
+	webhook, err := u.r.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !admin && webhook.UserID != userID {
+		return nil, ErrWebhookForbidden
+	}
+	return webhook, nil
+}
+
+// Publish преобразует событие изменения файла в событие вебхука
+func (u *WebhookUsecase) Publish(ctx context.Context, event *models.FileEvent) {
+	eventType := models.WebhookEventFileUpdated
+	switch event.Type {
+	case models.FileEventCreated:
+		eventType = models.WebhookEventFileUploaded
+	case models.FileEventDeleted:
+		eventType = models.WebhookEventFileDeleted
+	}
+
+	u.Enqueue(ctx, eventType, event)
+}
+
+// Enqueue ставит событие в очередь доставки всем подписанным вебхукам.
+// Ошибки не прерывают основную операцию и только логируются
+func (u *WebhookUsecase) Enqueue(ctx context.Context, eventType string, data any) {
+	id, err := generateSecret()
+	if err != nil {
+		log.Printf("ERROR: Failed to generate webhook event id: %v", err)
+		return
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(webhookPayload{
+		ID:         id[:32],
+		Type:       eventType,
+		OccurredAt: now,
+		Data:       data,
+	})
+	if err != nil {
+		log.Printf("ERROR: Failed to encode webhook event %s: %v", eventType, err)
+		return
+	}
+
+	count, err := u.r.EnqueueWebhookDeliveries(ctx, eventType, payload, systemWebhookEvents[eventType], now)
+	if err != nil {
+		log.Printf("ERROR: Failed to enqueue webhook event %s: %v", eventType, err)
+		return
+	}
+	if count > 0 {
+		select {
+		case u.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run отправляет доставки из очереди до отмены контекста
+func (u *WebhookUsecase) Run(ctx context.Context) {
+	log.Printf("INFO: Webhook dispatcher started")
+	ticker := time.NewTicker(u.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		u.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Printf("INFO: Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-u.wake:
+		}
+	}
+}
+
+func (u *WebhookUsecase) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		// Доставки пачки отправляются по очереди, поэтому аренда рассчитана на
+		// таймаут каждой из них с запасом, чтобы пачку не взял другой экземпляр
+		lease := time.Duration(u.cfg.BatchSize+1) * u.cfg.Timeout
+		deliveries, err := u.r.ClaimWebhookDeliveries(ctx, u.cfg.BatchSize, now, now.Add(lease))
+		if err != nil {
+			log.Printf("ERROR: Failed to claim webhook deliveries: %v", err)
+			return
+		}
+
+		for _, delivery := range deliveries {
+			u.deliver(ctx, delivery)
+		}
+
+		if len(deliveries) < u.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (u *WebhookUsecase) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	status, err := u.send(ctx, delivery, now)
+	if ctx.Err() != nil {
+		// Сервис останавливается: доставка вернется в очередь после истечения аренды
+		return
+	}
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+	case delivery.Attempts >= u.cfg.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		log.Printf("WARN: Webhook delivery %d failed permanently after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(u.backoff(delivery.Attempts))
+		log.Printf("WARN: Webhook delivery %d failed (attempt %d), retry at %s: %v",
+			delivery.ID, delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339), err)
+	}
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = now
+	}
+
+	if err := u.r.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		log.Printf("ERROR: Failed to save webhook delivery %d result: %v", delivery.ID, err)
+	}
+}
+
+func (u *WebhookUsecase) send(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tages-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Экспоненциальная задержка перед повтором: InitialBackoff * 2^(attempt-1), не больше MaxBackoff
+func (u *WebhookUsecase) backoff(attempt int) time.Duration {
+	delay := float64(u.cfg.InitialBackoff) * math.Pow(2, float64(attempt-1))
+	if delay > float64(u.cfg.MaxBackoff) {
+		return u.cfg.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// SignWebhookPayload вычисляет подпись HMAC-SHA256 от "timestamp.body".
+// Получатель сверяет ее с заголовком X-Webhook-Signature (после префикса "sha256=")
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Проверка адреса вебхука. Если доставка во внутреннюю сеть запрещена,
+// все адреса хоста должны быть внешними. DNS может измениться после создания,
+// поэтому адрес проверяется еще раз при подключении
+func (u *WebhookUsecase) validateURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: url scheme must be http or https", ErrInvalidWebhook)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("%w: url host is empty", ErrInvalidWebhook)
+	}
+	if u.cfg.AllowPrivateNetworks {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: failed to resolve host: %v", ErrInvalidWebhook, err)
+	}
+	for _, addr := range addrs {
+		if isInternalAddr(addr) {
+			return fmt.Errorf("%w: host resolves to internal address %s", ErrInvalidWebhook, addr)
+		}
+	}
+	return nil
+}
+
+// HTTP клиент доставки. Перенаправления не выполняются: ответ 3xx считается
+// неудачной доставкой. Без AllowPrivateNetworks подключение к внутренним
+// адресам отклоняется после разрешения имени, поэтому его нельзя обойти через DNS
+func newWebhookClient(cfg WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isInternalAddr(addrPort.Addr()) {
+				return fmt.Errorf("connection to internal address %s is not allowed", addrPort.Addr())
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Прокси из окружения подключался бы к адресу, который здесь не проверяется
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Адреса внутренней сети: петлевые, частные (RFC 1918, ULA), link-local,
+// групповые и неуказанные
+func isInternalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified()
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Заглушка, пока вебхуки не подключены
+type nopWebhookNotifier struct{}
+
+func (nopWebhookNotifier) Enqueue(context.Context, string, any) {}
@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tages/internal/models"
+)
+
+// Очередь доставок в памяти
+type memoryWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   []*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func (r *memoryWebhookRepository) CreateWebhook(_ context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook.ID = uint(len(r.webhooks) + 1)
+	r.webhooks = append(r.webhooks, webhook)
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetWebhooks(_ context.Context, userID *uint) ([]*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*models.Webhook
+	for _, webhook := range r.webhooks {
+		if userID == nil || webhook.UserID == *userID {
+			result = append(result, webhook)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryWebhookRepository) GetWebhook(_ context.Context, id uint) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, webhook := range r.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return nil, models.ErrWebhookNotFound
+}
+
+func (r *memoryWebhookRepository) DeleteWebhook(_ context.Context, id uint) error {
+	return errors.New("not implemented")
+}
+
+func (r *memoryWebhookRepository) EnqueueWebhookDeliveries(_ context.Context, eventType string, payload []byte, systemOnly bool, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, webhook := range r.webhooks {
+		if !webhook.Active || (systemOnly && !webhook.System) {
+			continue
+		}
+		subscribed := false
+		for _, event := range webhook.Events {
+			subscribed = subscribed || event == eventType
+		}
+		if !subscribed {
+			continue
+		}
+		r.deliveries = append(r.deliveries, &models.WebhookDelivery{
+			ID:            int64(len(r.deliveries) + 1),
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		count++
+	}
+	return count, nil
+}
+
+func (r *memoryWebhookRepository) ClaimWebhookDeliveries(_ context.Context, limit int, now, leaseUntil time.Time) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.NextAttemptAt = leaseUntil
+		clone := *delivery
+		for _, webhook := range r.webhooks {
+			if webhook.ID == delivery.WebhookID {
+				clone.URL, clone.Secret = webhook.URL, webhook.Secret
+			}
+		}
+		claimed = append(claimed, &clone)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepository) UpdateWebhookDelivery(_ context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.deliveries {
+		if stored.ID == delivery.ID {
+			clone := *delivery
+			r.deliveries[i] = &clone
+			return nil
+		}
+	}
+	return errors.New("delivery not found")
+}
+
+func (r *memoryWebhookRepository) GetWebhookDeliveries(_ context.Context, webhookID uint, limit, offset int) ([]*models.WebhookDelivery, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *memoryWebhookRepository) delivery(t *testing.T) models.WebhookDelivery {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(r.deliveries))
+	}
+	return *r.deliveries[0]
+}
+
+// Получатель вебхуков: проверяет подпись и отвечает статусами из statuses,
+// последний повторяется
+type webhookReceiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	requests int
+}
+
+func (rv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rv.t.Errorf("failed to read body: %v", err)
+	}
+
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	want := "sha256=" + SignWebhookPayload(rv.secret, timestamp, body)
+	if got := r.Header.Get(WebhookSignatureHeader); got != want {
+		rv.t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := r.Header.Get(WebhookEventHeader); got != models.WebhookEventFileUploaded {
+		rv.t.Errorf("event header = %q", got)
+	}
+
+	status := rv.statuses[min(rv.requests, len(rv.statuses)-1)]
+	rv.requests++
+	w.WriteHeader(status)
+}
+
+func (rv *webhookReceiver) count() int {
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	return rv.requests
+}
+
+func newTestWebhook(t *testing.T, statuses ...int) (*WebhookUsecase, *memoryWebhookRepository, *webhookReceiver) {
+	t.Helper()
+	repo := &memoryWebhookRepository{}
+	u := NewWebhookUsecase(repo, WebhookConfig{
+		MaxAttempts:          3,
+		InitialBackoff:       20 * time.Millisecond,
+		MaxBackoff:           time.Second,
+		Timeout:              5 * time.Second,
+		PollInterval:         time.Second,
+		BatchSize:            10,
+		AllowPrivateNetworks: true,
+	})
+
+	receiver := &webhookReceiver{t: t, statuses: statuses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	webhook, err := u.Create(context.Background(), 1, false, server.URL, []string{models.WebhookEventFileUploaded})
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	receiver.secret = webhook.Secret
+
+	u.Enqueue(context.Background(), models.WebhookEventFileUploaded, map[string]string{"name": "a.txt"})
+	return u, repo, receiver
+}
+
+// Ждет, пока доставка снова станет доступной, и отправляет ее
+func dispatchAfter(u *WebhookUsecase, delivery models.WebhookDelivery) {
+	time.Sleep(time.Until(delivery.NextAttemptAt))
+	u.dispatchDue(context.Background())
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	u, repo, receiver := newTestWebhook(t, http.StatusOK)
+
+	u.dispatchDue(context.Background())
+
+	delivery := repo.delivery(t)
+	if delivery.Status != models.WebhookDeliverySucceeded {
+		t.Fatalf("status = %s, want %s (error %q)", delivery.Status, models.WebhookDeliverySucceeded, delivery.LastError)
+	}
+	if delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK {
+		t.Errorf("attempts = %d, response status = %d", delivery.Attempts, delivery.ResponseStatus)
+	}
+	if receiver.count() != 1 {
+		t.Errorf("receiver got %d requests, want 1", receiver.count())
+	}
+}
+
+func TestWebhookDeliveryRetriesAfterServerError(t *testing.T) {
+	u, repo, receiver := newTestWebhook(t, http.StatusInternalServerError, http.StatusOK)
+
+	before := time.Now()
+	u.dispatchDue(context.Background())
+
+	delivery := repo.delivery(t)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("after failure: status = %s, attempts = %d", delivery.Status, delivery.Attempts)
+	}
+	if delivery.ResponseStatus != http.StatusInternalServerError || delivery.LastError == "" {
+		t.Errorf("after failure: response status = %d, error = %q", delivery.ResponseStatus, delivery.LastError)
+	}
+	if delay := delivery.NextAttemptAt.Sub(before); delay < 20*time.Millisecond {
+		t.Errorf("retry scheduled after %s, want at least the initial backoff", delay)
+	}
+
+	// До истечения задержки доставка не повторяется
+	u.dispatchDue(context.Background())
+	if receiver.count() != 1 {
+		t.Fatalf("delivery retried before backoff: %d requests", receiver.count())
+	}
+
+	dispatchAfter(u, delivery)
+	delivery = repo.delivery(t)
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 2 {
+		t.Fatalf("after retry: status = %s, attempts = %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	u, repo, receiver := newTestWebhook(t, http.StatusBadGateway)
+
+	u.dispatchDue(context.Background())
+	first := repo.delivery(t)
+	dispatchAfter(u, first)
+	second := repo.delivery(t)
+	if delay, prev := second.NextAttemptAt.Sub(*second.LastAttemptAt), first.NextAttemptAt.Sub(*first.LastAttemptAt); delay < 2*prev-time.Millisecond {
+		t.Errorf("backoff did not double: %s after %s", delay, prev)
+	}
+	dispatchAfter(u, second)
+
+	delivery := repo.delivery(t)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.Attempts != 3 {
+		t.Fatalf("status = %s, attempts = %d, want failed after 3", delivery.Status, delivery.Attempts)
+	}
+
+	// Неудачная доставка больше не отправляется
+	time.Sleep(50 * time.Millisecond)
+	u.dispatchDue(context.Background())
+	if receiver.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", receiver.count())
+	}
+}
+
+func TestWebhookRejectsInternalAddresses(t *testing.T) {
+	repo := &memoryWebhookRepository{}
+	u := NewWebhookUsecase(repo, WebhookConfig{MaxAttempts: 1, Timeout: time.Second, BatchSize: 10})
+
+	for _, rawURL := range []string{
+		"http://localhost/hook",
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.1/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://0.0.0.0/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		_, err := u.Create(context.Background(), 1, false, rawURL, []string{models.WebhookEventFileUploaded})
+		if !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("Create(%s) error = %v, want ErrInvalidWebhook", rawURL, err)
+		}
+	}
+
+	// Адрес мог смениться после создания: подключение тоже проверяется
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	repo.webhooks = append(repo.webhooks, &models.Webhook{
+		ID:     1,
+		URL:    server.URL,
+		Events: []string{models.WebhookEventFileUploaded},
+		Active: true,
+	})
+	u.Enqueue(context.Background(), models.WebhookEventFileUploaded, nil)
+	u.dispatchDue(context.Background())
+
+	delivery := repo.delivery(t)
+	if requests != 0 || delivery.Status != models.WebhookDeliveryFailed {
+		t.Fatalf("requests = %d, status = %s", requests, delivery.Status)
+	}
+	if !strings.Contains(delivery.LastError, "internal address") {
+		t.Errorf("error = %q", delivery.LastError)
+	}
+}
+
+func TestWebhookDoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	u, repo, _ := newTestWebhook(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	repo.webhooks[0].URL = redirect.URL
+
+	u.dispatchDue(context.Background())
+
+	delivery := repo.delivery(t)
+	if redirected {
+		t.Error("redirect was followed")
+	}
+	if delivery.Status == models.WebhookDeliverySucceeded || delivery.ResponseStatus != http.StatusFound {
+		t.Errorf("status = %s, response status = %d", delivery.Status, delivery.ResponseStatus)
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_file_meta_attributes;
+DROP INDEX IF EXISTS idx_file_meta_tags;
+
+ALTER TABLE file_meta
+    DROP COLUMN IF EXISTS attributes,
+    DROP COLUMN IF EXISTS tags;
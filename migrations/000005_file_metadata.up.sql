@@ -0,0 +1,6 @@
+ALTER TABLE file_meta
+    ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_file_meta_tags ON file_meta USING GIN (tags jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_file_meta_attributes ON file_meta USING GIN (attributes jsonb_path_ops);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_file_meta_content_indexed_at;
+DROP INDEX IF EXISTS idx_file_meta_search_vector;
+
+ALTER TABLE file_meta
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS content_indexed_at,
+    DROP COLUMN IF EXISTS content_text;
@@ -0,0 +1,11 @@
+ALTER TABLE file_meta
+    ADD COLUMN IF NOT EXISTS content_text TEXT,
+    ADD COLUMN IF NOT EXISTS content_indexed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', translate(name, '._-/', '    ')), 'A') ||
+        setweight(jsonb_to_tsvector('simple', tags, '["string"]'), 'B') ||
+        setweight(to_tsvector('simple', coalesce(content_text, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_file_meta_search_vector ON file_meta USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_file_meta_content_indexed_at ON file_meta (content_indexed_at);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_forbid_update();
+DROP TABLE IF EXISTS audit_events;
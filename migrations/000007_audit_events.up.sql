@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    action VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    actor_id INTEGER,
+    actor_email VARCHAR(255),
+    ip VARCHAR(64),
+    user_agent TEXT,
+    request_id VARCHAR(64),
+    target TEXT,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target);
+
+-- Журнал только дополняется: изменение записей запрещено,
+-- удаление разрешено только для очистки по сроку хранения
+CREATE OR REPLACE FUNCTION audit_events_forbid_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_forbid_update();
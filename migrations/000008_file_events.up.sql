@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS file_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    type VARCHAR(16) NOT NULL,
+    file_name TEXT NOT NULL,
+    actor_id INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_events_occurred_at ON file_events (occurred_at);
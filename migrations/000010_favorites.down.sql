@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS file_recents;
+DROP TABLE IF EXISTS file_stars;
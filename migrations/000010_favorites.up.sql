@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS file_stars (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    starred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, file_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_stars_user ON file_stars (user_id, starred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_file_stars_file ON file_stars (file_name);
+
+CREATE TABLE IF NOT EXISTS file_recents (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    action VARCHAR(16) NOT NULL,
+    accessed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, file_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_recents_user ON file_recents (user_id, accessed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_file_recents_file ON file_recents (file_name);
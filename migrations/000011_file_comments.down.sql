@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS file_comment_mentions;
+DROP TABLE IF EXISTS file_comments;
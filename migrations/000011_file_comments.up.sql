@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS file_comments (
+    id BIGSERIAL PRIMARY KEY,
+    file_name TEXT NOT NULL REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    parent_id BIGINT REFERENCES file_comments(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    resolved BOOLEAN NOT NULL DEFAULT FALSE,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_comments_file ON file_comments (file_name, created_at);
+CREATE INDEX IF NOT EXISTS idx_file_comments_parent ON file_comments (parent_id);
+
+CREATE TABLE IF NOT EXISTS file_comment_mentions (
+    comment_id BIGINT NOT NULL REFERENCES file_comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_comment_mentions_user ON file_comment_mentions (user_id, comment_id DESC);
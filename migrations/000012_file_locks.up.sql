@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS file_locks (
+    file_name TEXT PRIMARY KEY REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    token VARCHAR(64) NOT NULL,
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    acquired_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_locks_expires_at ON file_locks (expires_at);
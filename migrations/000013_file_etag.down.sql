@@ -0,0 +1 @@
+ALTER TABLE file_meta DROP COLUMN IF EXISTS etag;
@@ -0,0 +1,4 @@
+ALTER TABLE file_meta ADD COLUMN IF NOT EXISTS etag VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Существующим файлам выдаем версию, чтобы условные запросы работали сразу
+UPDATE file_meta SET etag = md5(name || updated_at::text) WHERE etag = '';
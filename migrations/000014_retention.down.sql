@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS legal_holds;
+DROP TABLE IF EXISTS folder_retention;
+DROP TABLE IF EXISTS file_retention;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS file_retention (
+    file_name TEXT PRIMARY KEY REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    retain_until TIMESTAMP NOT NULL,
+    mode VARCHAR(16) NOT NULL CHECK (mode IN ('governance', 'compliance')),
+    set_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    set_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Срок хранения для всех файлов, имя которых начинается с префикса
+CREATE TABLE IF NOT EXISTS folder_retention (
+    prefix TEXT PRIMARY KEY,
+    retain_until TIMESTAMP NOT NULL,
+    mode VARCHAR(16) NOT NULL CHECK (mode IN ('governance', 'compliance')),
+    set_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    set_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+    file_name TEXT PRIMARY KEY REFERENCES file_meta(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    set_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    set_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
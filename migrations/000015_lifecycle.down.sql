@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS file_trash;
+DROP TABLE IF EXISTS lifecycle_actions;
+DROP TABLE IF EXISTS lifecycle_rules;
+
+DROP INDEX IF EXISTS idx_file_meta_accessed_at;
+DROP INDEX IF EXISTS idx_file_meta_updated_at;
+ALTER TABLE file_meta DROP COLUMN IF EXISTS accessed_at;
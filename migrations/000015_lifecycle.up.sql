@@ -0,0 +1,56 @@
+-- Время последнего обращения к файлу для правил «не использовался N дней»
+ALTER TABLE file_meta ADD COLUMN IF NOT EXISTS accessed_at TIMESTAMP;
+UPDATE file_meta SET accessed_at = updated_at WHERE accessed_at IS NULL;
+ALTER TABLE file_meta
+    ALTER COLUMN accessed_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN accessed_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_file_meta_updated_at ON file_meta (updated_at);
+CREATE INDEX IF NOT EXISTS idx_file_meta_accessed_at ON file_meta (accessed_at);
+
+CREATE TABLE IF NOT EXISTS lifecycle_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    owner_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    prefix TEXT NOT NULL DEFAULT '',
+    tag TEXT NOT NULL DEFAULT '',
+    condition VARCHAR(16) NOT NULL CHECK (condition IN ('age', 'idle')),
+    after_days INTEGER NOT NULL CHECK (after_days > 0),
+    action VARCHAR(16) NOT NULL CHECK (action IN ('delete', 'trash')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_run_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lifecycle_rules_owner ON lifecycle_rules (owner_id);
+
+-- История применения правил
+CREATE TABLE IF NOT EXISTS lifecycle_actions (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL REFERENCES lifecycle_rules(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('applied', 'skipped', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lifecycle_actions_rule ON lifecycle_actions (rule_id, applied_at DESC);
+CREATE INDEX IF NOT EXISTS idx_lifecycle_actions_applied_at ON lifecycle_actions (applied_at);
+
+-- Корзина: метаданные удаленных файлов; содержимое хранится под служебным именем
+CREATE TABLE IF NOT EXISTS file_trash (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    tags JSONB NOT NULL DEFAULT '[]'::jsonb,
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    etag VARCHAR(64) NOT NULL DEFAULT '',
+    trashed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    trashed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    rule_id BIGINT REFERENCES lifecycle_rules(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_trash_trashed_at ON file_trash (trashed_at);
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS usage_snapshot_runs;
+DROP TABLE IF EXISTS usage_snapshots;
+
+ALTER TABLE file_trash
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS mime_type,
+    DROP COLUMN IF EXISTS size;
+
+DROP INDEX IF EXISTS idx_file_meta_unmeasured;
+DROP INDEX IF EXISTS idx_file_meta_owner;
+DROP INDEX IF EXISTS idx_file_meta_size;
+ALTER TABLE file_meta
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS mime_type,
+    DROP COLUMN IF EXISTS size;
@@ -0,0 +1,32 @@
+-- Размер, тип и владелец файла. Пустой mime_type — размер и тип еще не определены,
+-- их заполняет фоновое задание по содержимому в хранилище
+ALTER TABLE file_meta
+    ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mime_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_file_meta_size ON file_meta (size DESC);
+CREATE INDEX IF NOT EXISTS idx_file_meta_owner ON file_meta (owner_id);
+CREATE INDEX IF NOT EXISTS idx_file_meta_unmeasured ON file_meta (name) WHERE mime_type = '';
+
+ALTER TABLE file_trash
+    ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mime_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- Ежедневные снимки занятого места по владельцам и типам; owner_id = 0 — владелец неизвестен
+CREATE TABLE IF NOT EXISTS usage_snapshots (
+    snapshot_date DATE NOT NULL,
+    owner_id INTEGER NOT NULL DEFAULT 0,
+    mime_type TEXT NOT NULL,
+    bytes BIGINT NOT NULL,
+    files BIGINT NOT NULL,
+    PRIMARY KEY (snapshot_date, owner_id, mime_type)
+);
+
+-- Запуски снимков: строка занимает день, чтобы снимок делал один экземпляр
+CREATE TABLE IF NOT EXISTS usage_snapshot_runs (
+    snapshot_date DATE PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
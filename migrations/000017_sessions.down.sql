@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_expires;
+DROP INDEX IF EXISTS idx_refresh_tokens_user;
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+
+ALTER TABLE refresh_tokens ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS device_name;
+
+-- Оставляем по одному, самому свежему токену на пользователя
+DELETE FROM refresh_tokens r
+USING refresh_tokens newer
+WHERE newer.user_id = r.user_id AND newer.id > r.id;
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_user_id_key UNIQUE (user_id);
@@ -0,0 +1,15 @@
+-- Сессии: у пользователя может быть несколько refresh токенов, по одному на устройство
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_id_key;
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS device_name VARCHAR(128) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE refresh_tokens SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN created_at SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id, last_used_at DESC);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens(expires_at);
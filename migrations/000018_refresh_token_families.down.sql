@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rotated_refresh_tokens;
+
+-- Исходные токены по хешам не восстановить, поэтому все сессии завершаются
+DELETE FROM refresh_tokens;
+ALTER INDEX IF EXISTS idx_refresh_tokens_token_hash RENAME TO idx_refresh_tokens_token;
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(512);
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
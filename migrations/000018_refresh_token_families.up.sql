@@ -0,0 +1,15 @@
+-- В базе хранится только хеш refresh токена (SHA-256 в hex)
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+ALTER INDEX IF EXISTS idx_refresh_tokens_token RENAME TO idx_refresh_tokens_token_hash;
+
+-- Уже замененные токены семейства (сессии). Повторное предъявление такого
+-- токена означает его кражу: семейство отзывается целиком
+CREATE TABLE IF NOT EXISTS rotated_refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES refresh_tokens(id) ON DELETE CASCADE,
+    rotated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rotated_refresh_tokens_session ON rotated_refresh_tokens(session_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS settings;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,29 @@
+-- Роли пользователей: именованные наборы разрешений
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(256) NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Встроенные роли, совпадают с models.BuiltinRoles
+INSERT INTO roles(name, description, permissions, builtin) VALUES
+    ('admin', 'Полный доступ, управление пользователями и настройками', '{files:read,files:write,files:delete,admin}', TRUE),
+    ('member', 'Работа с файлами', '{files:read,files:write,files:delete}', TRUE),
+    ('readonly', 'Только чтение файлов', '{files:read}', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- Роль, назначенную пользователям, нельзя удалить
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(64) NOT NULL DEFAULT 'member' REFERENCES roles(name);
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+-- Системные настройки, изменяемые администраторами во время работы
+CREATE TABLE IF NOT EXISTS settings (
+    key VARCHAR(64) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
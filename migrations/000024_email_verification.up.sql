@@ -0,0 +1,6 @@
+-- Подтверждение email. Учетные записи, созданные раньше, считаются подтвержденными
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET email_verified = TRUE;
+
+-- Время последнего письма для подтверждения: ограничение повторной отправки
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP;
@@ -0,0 +1,10 @@
+-- Неудачные попытки входа. Счетчики общие для всех экземпляров сервиса.
+-- subject — account:<email> или ip:<адрес>
+CREATE TABLE IF NOT EXISTS login_attempts (
+    subject VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure ON login_attempts(last_failure_at);
@@ -0,0 +1,7 @@
+-- Номер поколения токенов пользователя: увеличивается при отзыве всех токенов,
+-- access token действителен, только пока номер в нем совпадает с текущим
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+
+-- Токены, выпущенные до появления номера, у отзывавшихся пользователей
+-- недействительны: клиенты получат новые через refresh token
+UPDATE users SET token_version = 1 WHERE tokens_valid_after IS NOT NULL;
@@ -0,0 +1,7 @@
+// Package migrations содержит SQL миграции схемы, встроенные в бинарный файл
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS